@@ -0,0 +1,114 @@
+// Команда auditverify проверяет цепочку хешей файлов аудита и сообщает о первой нарушенной записи.
+//
+// Использование:
+//
+//	auditverify [-key ключ | -key-file файл] [-prev-hash хеш] audit.log.20240101T000000.000.gz audit.log
+//
+// Файлы передаются от старых к новым, цепочка проверяется и между файлами.
+// Ключ по умолчанию берется из переменной окружения AUDIT_HMAC_KEY или из файла AUDIT_HMAC_KEY_FILE,
+// как и у сервиса.
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
+)
+
+const (
+	exitOK     = 0
+	exitBroken = 1
+	exitError  = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("auditverify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	key := flags.String("key", os.Getenv("AUDIT_HMAC_KEY"), "HMAC key of the audit log")
+	keyFile := flags.String("key-file", os.Getenv("AUDIT_HMAC_KEY_FILE"), "file with HMAC key of the audit log")
+	prevHash := flags.String("prev-hash", "", "hash of the last record before the first file")
+
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if *key != "" && *keyFile != "" {
+		fmt.Fprintln(stderr, "Укажите только один из ключей: -key (AUDIT_HMAC_KEY) или -key-file (AUDIT_HMAC_KEY_FILE)")
+		return exitError
+	}
+
+	if *keyFile != "" {
+		content, err := os.ReadFile(*keyFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "Не удалось прочитать ключ: %v\n", err)
+			return exitError
+		}
+		// Как и сервис, отбрасываем перевод строки в конце файла
+		*key = strings.TrimRight(string(content), "\r\n")
+	}
+
+	if *key == "" || flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "Использование: auditverify -key ключ файл...")
+		return exitError
+	}
+
+	head := *prevHash
+
+	for _, path := range flags.Args() {
+		summary, err := verifyFile(path, []byte(*key), head)
+
+		var broken *audit.ChainBrokenError
+		if errors.As(err, &broken) {
+			fmt.Fprintf(stdout, "%s: цепочка нарушена в строке %d (seq %d): %s\n", path, broken.Line, broken.Seq, broken.Reason)
+			return exitBroken
+		}
+
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			return exitError
+		}
+
+		fmt.Fprintf(stdout, "%s: OK, записей %d, контрольных точек %d, seq %d-%d\n",
+			path, summary.Records, summary.Checkpoints, summary.FirstSeq, summary.LastSeq)
+
+		// Пустой файл не продолжает цепочку
+		if summary.LastSeq != 0 {
+			head = summary.Head
+		}
+	}
+
+	return exitOK
+}
+
+func verifyFile(path string, key []byte, prevHash string) (*audit.ChainSummary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	}
+
+	return audit.VerifyChain(reader, key, prevHash)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "test-key"
+
+// writeSegments пишет записи одной цепочки в несколько файлов, как при ротации,
+// и возвращает пути файлов и хеш последней записи каждого из них
+func writeSegments(t *testing.T, records ...int) ([]string, []string) {
+	dir := t.TempDir()
+	chain := audit.NewHashChain([]byte(testKey), 0)
+
+	var paths, heads []string
+
+	for i, count := range records {
+		var data []byte
+		for range count {
+			line, err := chain.Seal(audit.AuditPayload{TS: int64(i), Action: audit.ShortURLActionCreate, URL: "https://example.com"})
+			require.NoError(t, err)
+			data = append(data, line...)
+		}
+
+		path := filepath.Join(dir, "audit.log."+string(rune('a'+i)))
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		summary, err := audit.VerifyChain(bytes.NewReader(data), []byte(testKey), "")
+		require.NoError(t, err)
+
+		paths = append(paths, path)
+		heads = append(heads, summary.Head)
+	}
+
+	return paths, heads
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("AUDIT_HMAC_KEY", "")
+	t.Setenv("AUDIT_HMAC_KEY_FILE", "")
+
+	paths, heads := writeSegments(t, 2, 3)
+
+	tampered := filepath.Join(t.TempDir(), "tampered.log")
+	data, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(tampered, bytes.Replace(data, []byte("example.com"), []byte("evil.example"), 1), 0o600))
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(testKey+"\n"), 0o600))
+
+	tests := []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "clean chain across files",
+			args:       []string{"-key", testKey, paths[0], paths[1]},
+			wantCode:   exitOK,
+			wantStdout: "OK, записей 3, контрольных точек 0, seq 3-5",
+		},
+		{
+			name:       "tampered line",
+			args:       []string{"-key", testKey, tampered},
+			wantCode:   exitBroken,
+			wantStdout: "цепочка нарушена в строке 1 (seq 0): hmac mismatch",
+		},
+		{
+			name:       "previous segment hash",
+			args:       []string{"-key", testKey, "-prev-hash", heads[0], paths[1]},
+			wantCode:   exitOK,
+			wantStdout: "OK, записей 3",
+		},
+		{
+			name:       "wrong previous segment hash",
+			args:       []string{"-key", testKey, "-prev-hash", heads[1], paths[1]},
+			wantCode:   exitBroken,
+			wantStdout: "first record is not linked to the previous segment",
+		},
+		{
+			name:       "key file from environment",
+			args:       []string{paths[0]},
+			env:        map[string]string{"AUDIT_HMAC_KEY_FILE": keyFile},
+			wantCode:   exitOK,
+			wantStdout: "OK, записей 2",
+		},
+		{
+			name:       "key and key file",
+			args:       []string{"-key", testKey, "-key-file", keyFile, paths[0]},
+			wantCode:   exitError,
+			wantStderr: "Укажите только один из ключей",
+		},
+		{
+			name:       "missing key",
+			args:       []string{paths[0]},
+			wantCode:   exitError,
+			wantStderr: "Использование",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			var stdout, stderr strings.Builder
+
+			code := run(tt.args, &stdout, &stderr)
+
+			assert.Equal(t, tt.wantCode, code, stderr.String())
+			assert.Contains(t, stdout.String(), tt.wantStdout)
+			assert.Contains(t, stderr.String(), tt.wantStderr)
+		})
+	}
+}
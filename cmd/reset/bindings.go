@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// configBinding содержит привязку поля конфигурации к флагам, переменной окружения и значению по умолчанию
+type configBinding struct {
+	FieldName string   // Путь к полю от builder, например b.config.Server.Address
+	Env       string   // Имя переменной окружения
+	Flags     []string // Имена флагов командной строки
+	Default   string   // Значение по умолчанию в виде строки
+	Usage     string   // Описание для справки
+	Loader    string   // Имя метода builder, загружающего значение
+}
+
+// Загрузчики значений по типам полей, ключ - тип поля
+var configLoaders = map[string]string{
+	"string":        "loadStringVariableFromEnv",
+	"int":           "loadIntVariableFromEnv",
+	"bool":          "loadBoolVariableFromEnv",
+	"float64":       "loadFloatVariableFromEnv",
+	"time.Duration": "loadDurationVariableFromEnv",
+}
+
+// Проверки значений по умолчанию для загрузчиков, которые преобразуют строку в другой тип
+var configDefaultParsers = map[string]func(string) error{
+	"loadIntVariableFromEnv": func(value string) error {
+		_, err := strconv.Atoi(value)
+		return err
+	},
+	"loadBoolVariableFromEnv": func(value string) error {
+		_, err := strconv.ParseBool(value)
+		return err
+	},
+	"loadFloatVariableFromEnv": func(value string) error {
+		_, err := strconv.ParseFloat(value, 64)
+		return err
+	},
+	"loadDurationVariableFromEnv": func(value string) error {
+		_, err := time.ParseDuration(value)
+		return err
+	},
+}
+
+// generateConfigBindings генерирует для структур с комментарием // generate:config функцию регистрации флагов,
+// метод загрузки полей и текст справки по тегам env, flag, default и usage.
+// Возвращает ошибку, если у поля нет обязательного тега, тип поля не поддерживается
+// или значение по умолчанию не соответствует типу
+func generateConfigBindings(structs []StructInfo) (string, error) {
+	var methods strings.Builder
+
+	for _, s := range structs {
+		bindings, err := collectConfigBindings(s.Fields, "b.config", "")
+		if err != nil {
+			return "", fmt.Errorf("%s.%w", s.Name, err)
+		}
+
+		writeRegisterFlags(&methods, s.Name, bindings)
+		writeLoadFields(&methods, s.Name, bindings)
+
+		if err := writeUsage(&methods, s.Name, bindings); err != nil {
+			return "", fmt.Errorf("%s: %w", s.Name, err)
+		}
+	}
+
+	var builder strings.Builder
+
+	// Заголовок файла
+	builder.WriteString("// Code generated by reset tool. DO NOT EDIT.\n")
+	builder.WriteString("//go:generate go run github.com/Alexey-zaliznuak/shortener/cmd/reset\n\n")
+	builder.WriteString(fmt.Sprintf("package %s\n\n", structs[0].Package))
+	builder.WriteString("import \"flag\"\n\n")
+	builder.WriteString(methods.String())
+
+	return builder.String(), nil
+}
+
+// collectConfigBindings собирает привязки полей в порядке объявления, заходя в анонимные структуры
+// и структуры, объявленные в том же пакете
+func collectConfigBindings(fields []FieldInfo, prefix string, namePrefix string) ([]configBinding, error) {
+	var bindings []configBinding
+
+	for _, field := range fields {
+		if field.Name == "" {
+			// Встроенное поле - пропускаем
+			continue
+		}
+
+		fieldName := prefix + "." + field.Name
+		name := namePrefix + field.Name
+
+		if field.IsAnonymousStruct && !field.IsPtr {
+			nested, err := collectConfigBindings(field.AnonymousFields, fieldName, name+".")
+			if err != nil {
+				return nil, err
+			}
+			bindings = append(bindings, nested...)
+			continue
+		}
+
+		if field.IsStruct && !field.IsPtr && field.NamedFields != nil {
+			nested, err := collectConfigBindings(field.NamedFields, fieldName, name+".")
+			if err != nil {
+				return nil, err
+			}
+			bindings = append(bindings, nested...)
+			continue
+		}
+
+		binding, err := newConfigBinding(field, fieldName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, nil
+}
+
+// newConfigBinding строит привязку поля по его тегам и выбирает загрузчик по типу поля: для именованных
+// типов пакета, например type Secret string, используется метод load<Тип>VariableFromEnv
+func newConfigBinding(field FieldInfo, fieldName string) (configBinding, error) {
+	binding := configBinding{
+		FieldName: fieldName,
+		Env:       field.Env,
+		Default:   field.Default,
+		Usage:     field.Usage,
+	}
+
+	if field.Flag != "" {
+		for _, flagName := range strings.Split(field.Flag, ",") {
+			binding.Flags = append(binding.Flags, strings.TrimSpace(flagName))
+		}
+	}
+
+	switch {
+	case field.IsPtr || field.IsSlice || field.IsMap || field.IsStruct:
+		return binding, fmt.Errorf("type %s is not supported", field.Type)
+	case configLoaders[field.Type] != "":
+		binding.Loader = configLoaders[field.Type]
+	case field.Underlying != field.Type && !strings.Contains(field.Type, "."):
+		binding.Loader = "load" + field.Type + "VariableFromEnv"
+	default:
+		return binding, fmt.Errorf("type %s is not supported", field.Type)
+	}
+
+	if binding.Env == "" {
+		return binding, fmt.Errorf("env tag is required")
+	}
+	if binding.Usage == "" {
+		return binding, fmt.Errorf("usage tag is required")
+	}
+
+	if parse, ok := configDefaultParsers[binding.Loader]; ok {
+		if !field.HasDefault {
+			return binding, fmt.Errorf("default tag is required for %s", field.Type)
+		}
+		if err := parse(binding.Default); err != nil {
+			return binding, fmt.Errorf("default '%s' is not a valid %s", binding.Default, field.Type)
+		}
+	}
+
+	return binding, nil
+}
+
+// writeRegisterFlags генерирует функцию register<Структура>Flags, которая регистрирует строковые флаги полей
+// и возвращает их значения по именам переменных окружения. Все имена флага поля указывают на одно значение
+func writeRegisterFlags(builder *strings.Builder, structName string, bindings []configBinding) {
+	builder.WriteString(fmt.Sprintf("func register%sFlags(flags *flag.FlagSet) map[string]*string {\n", structName))
+	builder.WriteString("\tvalues := make(map[string]*string)\n\n")
+
+	for _, binding := range bindings {
+		if len(binding.Flags) == 0 {
+			continue
+		}
+
+		usage := strconv.Quote(flagUsage(binding))
+
+		builder.WriteString(fmt.Sprintf("\tvalues[%q] = flags.String(%q, \"\", %s)\n", binding.Env, binding.Flags[0], usage))
+		for _, alias := range binding.Flags[1:] {
+			builder.WriteString(fmt.Sprintf("\tflags.StringVar(values[%q], %q, \"\", %s)\n", binding.Env, alias, usage))
+		}
+	}
+
+	builder.WriteString("\n\treturn values\n")
+	builder.WriteString("}\n\n")
+}
+
+// flagUsage возвращает описание флага с именем переменной окружения и значением по умолчанию
+func flagUsage(binding configBinding) string {
+	if binding.Default == "" {
+		return fmt.Sprintf("%s (%s)", binding.Usage, binding.Env)
+	}
+
+	return fmt.Sprintf("%s (%s, default %s)", binding.Usage, binding.Env, binding.Default)
+}
+
+// writeLoadFields генерирует метод load<Структура>Fields, который загружает все поля загрузчиками builder
+func writeLoadFields(builder *strings.Builder, structName string, bindings []configBinding) {
+	builder.WriteString(fmt.Sprintf("func (b *%sBuilder) load%sFields() {\n", structName, structName))
+
+	for _, binding := range bindings {
+		builder.WriteString(fmt.Sprintf("\t%s = b.%s(%q, %q)\n", binding.FieldName, binding.Loader, binding.Env, binding.Default))
+	}
+
+	builder.WriteString("}\n\n")
+}
+
+// writeUsage генерирует константу <структура>Usage со справкой по переменным окружения в виде таблицы
+func writeUsage(builder *strings.Builder, structName string, bindings []configBinding) error {
+	var table strings.Builder
+
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "  VARIABLE\tDEFAULT\tDESCRIPTION")
+
+	for _, binding := range bindings {
+		name := binding.Env
+		for _, flagName := range binding.Flags {
+			name += ", -" + flagName
+		}
+
+		fmt.Fprintf(writer, "  %s\t%s\t%s\n", name, binding.Default, binding.Usage)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	// Табличный вывод не должен оставлять пробелы в конце строк
+	lines := strings.Split(table.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	text := strings.Join(lines, "\n")
+
+	literal := "`" + text + "`"
+	if strings.Contains(text, "`") {
+		literal = strconv.Quote(text)
+	}
+
+	constName := strings.ToLower(structName[:1]) + structName[1:] + "Usage"
+	builder.WriteString(fmt.Sprintf("const %s = %s\n", constName, literal))
+
+	return nil
+}
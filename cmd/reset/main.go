@@ -1,3 +1,34 @@
+// Команда reset генерирует для структур, отмеченных комментарием // generate:reset, метод Reset() в файле
+// reset.gen.go, для структур, отмеченных комментарием // generate:validate, метод Validate() в файле
+// validate.gen.go, а для структур, отмеченных комментарием // generate:config, привязки полей к флагам
+// и переменным окружения в файле config.gen.go.
+//
+// Validate() проверяет поля по тегу validate, правила перечисляются через запятую:
+//
+//	required        - значение не пустое, для указателей - не nil
+//	min=N, max=N    - ограничение значения чисел и длины строк, слайсов и мап,
+//	                  для time.Duration допускаются длительности, например min=1ms
+//	url             - строка является абсолютным URL со схемой и хостом
+//	oneof=a b c     - значение совпадает с одним из перечисленных через пробел
+//
+// Пустые строки, слайсы, мапы и nil указатели проверяются только правилом required. Поля анонимных
+// структур проверяются напрямую, именованные структуры - своим методом Validate(), если он есть.
+//
+// Для структуры S с комментарием // generate:config генерируются по тегам полей:
+//
+//	registerSFlags(flags) - регистрирует строковые флаги из тега flag (имена через запятую)
+//	                        и возвращает их значения по именам переменных окружения
+//	(b *SBuilder) loadSFields() - загружает каждое поле методом b.load<Тип>VariableFromEnv(env, default)
+//	sUsage                - справка по переменным окружения из тегов env, default и usage
+//
+// Теги env и usage обязательны, тег default обязателен для чисел, bool и time.Duration. Поля анонимных
+// структур и структур того же пакета загружаются рекурсивно. Загрузчики принимают значение по умолчанию
+// строкой: loadStringVariableFromEnv, loadIntVariableFromEnv, loadBoolVariableFromEnv, loadFloatVariableFromEnv,
+// loadDurationVariableFromEnv, для именованных типов пакета - load<Тип>VariableFromEnv, например loadSecretVariableFromEnv.
+//
+// Использование:
+//
+//	reset [директория]
 package main
 
 import (
@@ -8,15 +39,22 @@ import (
 	"go/token"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-// StructInfo содержит информацию о структуре, для которой нужно сгенерировать Reset()
+// StructInfo содержит информацию о структуре, для которой нужно сгенерировать Reset() или Validate()
 type StructInfo struct {
-	Package string
-	Name    string
-	Fields  []FieldInfo
-	File    string
+	Package  string
+	Name     string
+	Fields   []FieldInfo
+	File     string
+	Imports  map[string]string // имя пакета -> путь импорта в исходном файле
+	Reset    bool              // Структура отмечена комментарием // generate:reset
+	Validate bool              // Структура отмечена комментарием // generate:validate
+	Config   bool              // Структура отмечена комментарием // generate:config
 }
 
 // FieldInfo содержит информацию о поле структуры
@@ -29,6 +67,14 @@ type FieldInfo struct {
 	IsStruct          bool
 	IsAnonymousStruct bool
 	AnonymousFields   []FieldInfo // Поля анонимной структуры
+	NamedFields       []FieldInfo // Поля структуры, объявленной в том же пакете
+	Underlying        string      // Базовый тип именованного типа пакета, не являющегося структурой
+	Env               string      // Значение тега env
+	ValidateTag       string      // Значение тега validate
+	Flag              string      // Значение тега flag
+	Default           string      // Значение тега default
+	HasDefault        bool        // Тег default указан, в том числе пустой
+	Usage             string      // Значение тега usage
 }
 
 func main() {
@@ -38,8 +84,62 @@ func main() {
 		rootDir = os.Args[1]
 	}
 
-	// Собираем все структуры с комментарием // generate:reset
+	structs, err := collectStructs(rootDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка при обходе директорий: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Генерируем файлы reset.gen.go, validate.gen.go и config.gen.go для каждого пакета
+	for packagePath, structsList := range structs {
+		files, err := generateFiles(structsList)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка в тегах пакета %s: %v\n", packagePath, err)
+			continue
+		}
+
+		for name, generatedCode := range files {
+			writeGeneratedFile(filepath.Join(packagePath, name), generatedCode)
+		}
+	}
+}
+
+// generateFiles генерирует код файлов пакета по отмеченным структурам и возвращает его по именам файлов
+func generateFiles(structs []StructInfo) (map[string]string, error) {
+	files := make(map[string]string)
+
+	if resetStructs := filterStructs(structs, func(s StructInfo) bool { return s.Reset }); len(resetStructs) > 0 {
+		files["reset.gen.go"] = generateResetMethods(resetStructs)
+	}
+
+	if validateStructs := filterStructs(structs, func(s StructInfo) bool { return s.Validate }); len(validateStructs) > 0 {
+		generatedCode, err := generateValidateMethods(validateStructs)
+		if err != nil {
+			return nil, fmt.Errorf("validate: %w", err)
+		}
+		files["validate.gen.go"] = generatedCode
+	}
+
+	if configStructs := filterStructs(structs, func(s StructInfo) bool { return s.Config }); len(configStructs) > 0 {
+		generatedCode, err := generateConfigBindings(configStructs)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		files["config.gen.go"] = generatedCode
+	}
+
+	return files, nil
+}
+
+// collectStructs находит в директории rootDir и ее поддиректориях структуры с комментариями
+// // generate:reset, // generate:validate и // generate:config и возвращает их по путям пакетов
+func collectStructs(rootDir string) (map[string][]StructInfo, error) {
+	// Собираем все структуры с комментарием // generate:reset, // generate:validate или // generate:config
 	structs := make(map[string][]StructInfo) // ключ - путь к пакету
+	// Именованные типы, не являющиеся структурами (например type Secret string), и их базовые типы
+	nonStructTypes := make(map[string]map[string]string) // ключ - путь к пакету
+	// Поля всех структур пакета по именам структур
+	structTypes := make(map[string]map[string][]FieldInfo) // ключ - путь к пакету
 
 	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -51,17 +151,17 @@ func main() {
 			if strings.HasPrefix(info.Name(), ".") && info.Name() != "." {
 				return filepath.SkipDir
 			}
-			if info.Name() == "vendor" || info.Name() == "node_modules" {
+			if info.Name() == "vendor" || info.Name() == "node_modules" || info.Name() == "testdata" {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		// Обрабатываем только .go файлы, исключая сгенерированные
+		// Обрабатываем только .go файлы, исключая сгенерированные и тесты
 		if !strings.HasSuffix(path, ".go") {
 			return nil
 		}
-		if strings.HasSuffix(path, ".gen.go") {
+		if strings.HasSuffix(path, ".gen.go") || strings.HasSuffix(path, "_test.go") {
 			return nil
 		}
 
@@ -73,30 +173,32 @@ func main() {
 			return nil
 		}
 
-		// Находим структуры с комментарием // generate:reset
+		// Находим структуры с комментариями // generate:reset и // generate:validate
 		ast.Inspect(node, func(n ast.Node) bool {
 			genDecl, ok := n.(*ast.GenDecl)
 			if !ok {
 				return true
 			}
 
+			// Обрабатываем только type declarations
+			if genDecl.Tok != token.TYPE {
+				return true
+			}
+
+			collectNonStructTypes(genDecl, filepath.Dir(path), nonStructTypes, fset)
+			collectStructTypes(genDecl, filepath.Dir(path), structTypes, fset)
+
 			// Проверяем комментарии
-			hasResetComment := false
+			hasResetComment, hasValidateComment, hasConfigComment := false, false, false
 			if genDecl.Doc != nil {
 				for _, comment := range genDecl.Doc.List {
-					if strings.Contains(comment.Text, "generate:reset") {
-						hasResetComment = true
-						break
-					}
+					hasResetComment = hasResetComment || strings.Contains(comment.Text, "generate:reset")
+					hasValidateComment = hasValidateComment || strings.Contains(comment.Text, "generate:validate")
+					hasConfigComment = hasConfigComment || strings.Contains(comment.Text, "generate:config")
 				}
 			}
 
-			if !hasResetComment {
-				return true
-			}
-
-			// Обрабатываем только type declarations
-			if genDecl.Tok != token.TYPE {
+			if !hasResetComment && !hasValidateComment && !hasConfigComment {
 				return true
 			}
 
@@ -120,10 +222,14 @@ func main() {
 				packageName := node.Name.Name
 
 				structInfo := StructInfo{
-					Package: packageName,
-					Name:    typeSpec.Name.Name,
-					Fields:  fields,
-					File:    path,
+					Package:  packageName,
+					Name:     typeSpec.Name.Name,
+					Fields:   fields,
+					File:     path,
+					Imports:  extractImports(node),
+					Reset:    hasResetComment,
+					Validate: hasValidateComment,
+					Config:   hasConfigComment,
 				}
 
 				structs[packagePath] = append(structs[packagePath], structInfo)
@@ -136,41 +242,159 @@ func main() {
 	})
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Ошибка при обходе директорий: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
-	// Генерируем файлы reset.gen.go для каждого пакета
 	for packagePath, structsList := range structs {
-		if len(structsList) == 0 {
+		for i := range structsList {
+			resolveNamedStructs(structsList[i].Fields, structTypes[packagePath])
+			markNonStructTypes(structsList[i].Fields, nonStructTypes[packagePath])
+		}
+	}
+
+	return structs, nil
+}
+
+// filterStructs возвращает структуры, для которых keep возвращает true
+func filterStructs(structs []StructInfo, keep func(StructInfo) bool) []StructInfo {
+	var filtered []StructInfo
+
+	for _, s := range structs {
+		if keep(s) {
+			filtered = append(filtered, s)
+		}
+	}
+
+	return filtered
+}
+
+// writeGeneratedFile форматирует сгенерированный код и записывает его в файл outputPath
+func writeGeneratedFile(outputPath string, generatedCode string) {
+	// Форматируем код
+	formatted, err := format.Source([]byte(generatedCode))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка при форматировании %s: %v\n", outputPath, err)
+		formatted = []byte(generatedCode)
+	}
+
+	err = os.WriteFile(outputPath, formatted, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка при записи файла %s: %v\n", outputPath, err)
+		return
+	}
+
+	fmt.Printf("Сгенерирован файл: %s\n", outputPath)
+}
+
+// collectNonStructTypes запоминает объявленные в пакете именованные типы, которые не являются структурами,
+// вместе с их базовыми типами
+func collectNonStructTypes(genDecl *ast.GenDecl, packagePath string, types map[string]map[string]string, fset *token.FileSet) {
+	for _, spec := range genDecl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok {
 			continue
 		}
 
-		// Генерируем код
-		generatedCode := generateResetMethods(structsList)
+		if _, ok := typeSpec.Type.(*ast.StructType); ok {
+			continue
+		}
 
-		// Записываем в файл reset.gen.go
-		outputPath := filepath.Join(packagePath, "reset.gen.go")
-		err := os.WriteFile(outputPath, []byte(generatedCode), 0644)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Ошибка при записи файла %s: %v\n", outputPath, err)
+		if types[packagePath] == nil {
+			types[packagePath] = make(map[string]string)
+		}
+		types[packagePath][typeSpec.Name.Name] = getTypeString(typeSpec.Type, fset)
+	}
+}
+
+// collectStructTypes запоминает поля объявленных в пакете структур
+func collectStructTypes(genDecl *ast.GenDecl, packagePath string, types map[string]map[string][]FieldInfo, fset *token.FileSet) {
+	for _, spec := range genDecl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok {
 			continue
 		}
 
-		// Форматируем код
-		formatted, err := format.Source([]byte(generatedCode))
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Ошибка при форматировании %s: %v\n", outputPath, err)
+		structType, ok := typeSpec.Type.(*ast.StructType)
+		if !ok {
 			continue
 		}
 
-		err = os.WriteFile(outputPath, formatted, 0644)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Ошибка при записи отформатированного файла %s: %v\n", outputPath, err)
+		if types[packagePath] == nil {
+			types[packagePath] = make(map[string][]FieldInfo)
+		}
+		types[packagePath][typeSpec.Name.Name] = extractFields(structType, fset)
+	}
+}
+
+// resolveNamedStructs заполняет поля значений структур, объявленных в том же пакете, копией их полей.
+// Указатели не раскрываются, поэтому рекурсивные типы не приводят к бесконечной рекурсии
+func resolveNamedStructs(fields []FieldInfo, types map[string][]FieldInfo) {
+	for i := range fields {
+		if fields[i].IsAnonymousStruct {
+			resolveNamedStructs(fields[i].AnonymousFields, types)
+			continue
+		}
+
+		if namedFields, ok := types[fields[i].Type]; ok && fields[i].IsStruct && !fields[i].IsPtr {
+			fields[i].NamedFields = append([]FieldInfo(nil), namedFields...)
+			resolveNamedStructs(fields[i].NamedFields, types)
+		}
+	}
+}
+
+// markNonStructTypes снимает признак структуры с полей, тип которых объявлен в пакете не как структура,
+// такие поля сбрасываются к нулевому значению как примитивные и проверяются по базовому типу
+func markNonStructTypes(fields []FieldInfo, types map[string]string) {
+	for i := range fields {
+		if fields[i].IsAnonymousStruct {
+			markNonStructTypes(fields[i].AnonymousFields, types)
+			continue
+		}
+
+		if fields[i].NamedFields != nil {
+			markNonStructTypes(fields[i].NamedFields, types)
+			continue
+		}
+
+		if underlying, ok := types[strings.TrimPrefix(fields[i].Type, "*")]; ok && fields[i].IsStruct {
+			fields[i].IsStruct = false
+			fields[i].Underlying = underlying
+		}
+	}
+}
+
+// extractImports возвращает импорты файла в виде имя пакета -> путь
+func extractImports(file *ast.File) map[string]string {
+	imports := make(map[string]string)
+
+	for _, spec := range file.Imports {
+		importPath := strings.Trim(spec.Path.Value, `"`)
+		name := filepath.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+
+	return imports
+}
+
+// usedPackages собирает пакеты, на типы которых ссылается сгенерированный код:
+// это поля-значения из других пакетов, слайсы и мапы сбрасываются без упоминания типа
+func usedPackages(fields []FieldInfo, used map[string]bool) {
+	for _, field := range fields {
+		if field.IsAnonymousStruct {
+			usedPackages(field.AnonymousFields, used)
 			continue
 		}
 
-		fmt.Printf("Сгенерирован файл: %s\n", outputPath)
+		if field.IsSlice || field.IsMap {
+			continue
+		}
+
+		if pkg, _, ok := strings.Cut(strings.TrimPrefix(field.Type, "*"), "."); ok {
+			used[pkg] = true
+		}
 	}
 }
 
@@ -192,8 +416,22 @@ func extractFields(structType *ast.StructType, fset *token.FileSet) []FieldInfo
 			anonymousFields = extractFields(structTypeField, fset)
 		}
 
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			value, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(value)
+		}
+		defaultValue, hasDefault := tag.Lookup("default")
+
 		fieldInfo := FieldInfo{
 			Type:              fieldType,
+			Underlying:        fieldType,
+			Env:               tag.Get("env"),
+			ValidateTag:       tag.Get("validate"),
+			Flag:              tag.Get("flag"),
+			Default:           defaultValue,
+			HasDefault:        hasDefault,
+			Usage:             tag.Get("usage"),
 			IsPtr:             isPointerType(field.Type),
 			IsSlice:           isSliceType(baseType),
 			IsMap:             isMapType(baseType),
@@ -305,6 +543,35 @@ func generateResetMethods(structs []StructInfo) string {
 	builder.WriteString("//go:generate go run github.com/Alexey-zaliznuak/shortener/cmd/reset\n\n")
 	builder.WriteString(fmt.Sprintf("package %s\n\n", packageName))
 
+	// Импорты пакетов, типы которых используются при сбросе полей
+	used := make(map[string]bool)
+	imports := make(map[string]string)
+	for _, s := range structs {
+		usedPackages(s.Fields, used)
+		for name, importPath := range s.Imports {
+			imports[name] = importPath
+		}
+	}
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		if _, ok := imports[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if filepath.Base(imports[name]) == name {
+			builder.WriteString(fmt.Sprintf("import %q\n", imports[name]))
+		} else {
+			builder.WriteString(fmt.Sprintf("import %s %q\n", name, imports[name]))
+		}
+	}
+	if len(names) > 0 {
+		builder.WriteString("\n")
+	}
+
 	// Генерируем метод Reset() для каждой структуры
 	for _, s := range structs {
 		builder.WriteString(generateResetMethod(s))
@@ -348,7 +615,7 @@ func generateResetMethod(s StructInfo) string {
 				builder.WriteString(fmt.Sprintf("\tif %s != nil {\n", fieldName))
 				if field.IsAnonymousStruct {
 					// Анонимная структура - сбрасываем поля напрямую
-					generateResetForFields(&builder, field.AnonymousFields, "*"+fieldName, "\t\t")
+					generateResetForFields(&builder, field.AnonymousFields, "(*"+fieldName+")", "\t\t")
 				} else {
 					// Именованная структура - проверяем наличие метода Reset() (как в примере)
 					builder.WriteString(fmt.Sprintf("\t\tif resetter, ok := interface{}(%s).(interface{ Reset() }); ok {\n", fieldName))
@@ -423,7 +690,7 @@ func generateResetForFields(builder *strings.Builder, fields []FieldInfo, prefix
 				builder.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, fieldName))
 				if field.IsAnonymousStruct {
 					// Анонимная структура
-					generateResetForFields(builder, field.AnonymousFields, "*"+fieldName, indent+"\t")
+					generateResetForFields(builder, field.AnonymousFields, "(*"+fieldName+")", indent+"\t")
 				} else {
 					// Именованная структура
 					builder.WriteString(fmt.Sprintf("%s\tif resetter, ok := interface{}(%s).(interface{ Reset() }); ok {\n", indent, fieldName))
@@ -490,7 +757,7 @@ func getZeroValue(typeStr string) string {
 	case "rune":
 		return "0"
 	default:
-		// Для пользовательских типов возвращаем нулевое значение через {}
-		return typeStr + "{}"
+		// Именованные типы могут быть не структурами (например time.Duration или type Secret string)
+		return "*new(" + typeStr + ")"
 	}
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func TestGenerate_Golden(t *testing.T) {
+	tests := []struct {
+		name        string
+		structs     int
+		wantedFiles []string
+	}{
+		{name: "config", structs: 3, wantedFiles: []string{"reset.gen.go", "validate.gen.go"}},
+		{name: "bindings", structs: 1, wantedFiles: []string{"config.gen.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packagePath := filepath.Join("testdata", tt.name)
+
+			structs, err := collectStructs(packagePath)
+			require.NoError(t, err)
+			require.Len(t, structs[packagePath], tt.structs)
+
+			generated, err := generateFiles(structs[packagePath])
+			require.NoError(t, err)
+			require.Len(t, generated, len(tt.wantedFiles))
+
+			fset := token.NewFileSet()
+			source, err := parser.ParseFile(fset, filepath.Join(packagePath, "config.go"), nil, 0)
+			require.NoError(t, err)
+			files := []*ast.File{source}
+
+			for _, name := range tt.wantedFiles {
+				require.Contains(t, generated, name)
+
+				formatted, err := format.Source([]byte(generated[name]))
+				require.NoError(t, err, name)
+
+				golden := filepath.Join(packagePath, name+".golden")
+				if *update {
+					require.NoError(t, os.WriteFile(golden, formatted, 0o644))
+				}
+
+				want, err := os.ReadFile(golden)
+				require.NoError(t, err)
+				assert.Equal(t, string(want), string(formatted), "%s differs from %s, run go test -update", name, golden)
+
+				file, err := parser.ParseFile(fset, name, formatted, 0)
+				require.NoError(t, err, name)
+				files = append(files, file)
+			}
+
+			// The generated code compiles together with the source package
+			checker := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+			_, err = checker.Check("config", fset, files, nil)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestGenerateValidateMethods_InvalidTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   FieldInfo
+		wantErr string
+	}{
+		{
+			name:    "unknown rule",
+			field:   FieldInfo{Name: "Address", Type: "string", Underlying: "string", ValidateTag: "hostname"},
+			wantErr: "Config.Address: unknown rule 'hostname'",
+		},
+		{
+			name:    "min without number",
+			field:   FieldInfo{Name: "Length", Type: "int", Underlying: "int", ValidateTag: "min=four"},
+			wantErr: "Config.Length: rule min requires a number, got 'four'",
+		},
+		{
+			name:    "duration on int",
+			field:   FieldInfo{Name: "Length", Type: "int", Underlying: "int", ValidateTag: "min=1s"},
+			wantErr: "Config.Length: rule min requires a number, got '1s'",
+		},
+		{
+			name:    "invalid duration",
+			field:   FieldInfo{Name: "Timeout", Type: "time.Duration", Underlying: "time.Duration", ValidateTag: "max=soon"},
+			wantErr: "Config.Timeout: rule max requires a duration, got 'soon'",
+		},
+		{
+			name:    "url on number",
+			field:   FieldInfo{Name: "Length", Type: "int", Underlying: "int", ValidateTag: "url"},
+			wantErr: "Config.Length: rule url requires a string",
+		},
+		{
+			name:    "rules on bool",
+			field:   FieldInfo{Name: "Enabled", Type: "bool", Underlying: "bool", ValidateTag: "required"},
+			wantErr: "Config.Enabled: rules are not supported for bool",
+		},
+		{
+			name: "rules on anonymous struct field",
+			field: FieldInfo{Name: "Server", IsStruct: true, IsAnonymousStruct: true, AnonymousFields: []FieldInfo{
+				{Name: "Workers", Type: "int", Underlying: "int", ValidateTag: "oneof=one two"},
+			}},
+			wantErr: "Config.Server.Workers: rule oneof requires numbers, got 'one'",
+		},
+		{
+			name:    "min on struct",
+			field:   FieldInfo{Name: "DB", Type: "DBConfig", IsStruct: true, ValidateTag: "min=1"},
+			wantErr: "Config.DB: only required is supported for struct pointers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generateValidateMethods([]StructInfo{{Package: "config", Name: "Config", Fields: []FieldInfo{tt.field}}})
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGenerateConfigBindings_InvalidTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   FieldInfo
+		wantErr string
+	}{
+		{
+			name:    "missing env",
+			field:   FieldInfo{Name: "Address", Type: "string", Underlying: "string", Usage: "startup address"},
+			wantErr: "Config.Address: env tag is required",
+		},
+		{
+			name:    "missing usage",
+			field:   FieldInfo{Name: "Address", Type: "string", Underlying: "string", Env: "SERVER_ADDRESS"},
+			wantErr: "Config.Address: usage tag is required",
+		},
+		{
+			name:    "missing default for int",
+			field:   FieldInfo{Name: "Workers", Type: "int", Underlying: "int", Env: "WORKERS", Usage: "workers"},
+			wantErr: "Config.Workers: default tag is required for int",
+		},
+		{
+			name: "invalid duration default in anonymous struct",
+			field: FieldInfo{Name: "Server", IsStruct: true, IsAnonymousStruct: true, AnonymousFields: []FieldInfo{
+				{Name: "Timeout", Type: "time.Duration", Underlying: "time.Duration", Env: "TIMEOUT", Usage: "timeout", Default: "30", HasDefault: true},
+			}},
+			wantErr: "Config.Server.Timeout: default '30' is not a valid time.Duration",
+		},
+		{
+			name:    "unsupported type",
+			field:   FieldInfo{Name: "Hosts", Type: "[]string", Underlying: "[]string", IsSlice: true, Env: "HOSTS", Usage: "hosts"},
+			wantErr: "Config.Hosts: type []string is not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generateConfigBindings([]StructInfo{{Package: "config", Name: "Config", Fields: []FieldInfo{tt.field}}})
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import "time"
+
+// Secret содержит секретное значение.
+type Secret string
+
+// DBConfig содержит именованную вложенную структуру того же пакета.
+type DBConfig struct {
+	DSN         Secret `env:"DATABASE_DSN" flag:"d" usage:"database DSN"`
+	StoragePath string `env:"STORAGE_PATH" flag:"f" default:"storage.json" usage:"storage file path"`
+}
+
+// AppConfig содержит поля всех поддерживаемых типов.
+// generate:config
+type AppConfig struct {
+	LoggingLevel string        `env:"LOGGING_LEVEL" flag:"l,level" default:"info" usage:"logging level"`
+	Timeout      time.Duration `env:"TIMEOUT" default:"30s" usage:"request timeout"`
+	DB           DBConfig
+
+	Server struct {
+		Address string  `env:"SERVER_ADDRESS" flag:"a" default:"localhost:8080" usage:"startup address"`
+		Ratio   float64 `env:"SAMPLE_RATIO" default:"0.5" usage:"sampled share of requests"`
+
+		TLS struct {
+			Enabled bool `env:"TLS_ENABLED" default:"false" usage:"serve HTTPS"`
+		}
+	}
+
+	Audit struct {
+		File    string `env:"AUDIT_FILE" flag:"audit-file" usage:"audit log file, empty disables it"`
+		Retries int    `env:"AUDIT_RETRIES" default:"3" usage:"delivery attempts"`
+	}
+}
+
+// AppConfigBuilder содержит загрузчики, которые вызывает сгенерированный код.
+type AppConfigBuilder struct {
+	config *AppConfig
+}
+
+func (b *AppConfigBuilder) loadStringVariableFromEnv(envName string, Default string) string {
+	return Default
+}
+
+func (b *AppConfigBuilder) loadIntVariableFromEnv(envName string, Default string) int {
+	return 0
+}
+
+func (b *AppConfigBuilder) loadBoolVariableFromEnv(envName string, Default string) bool {
+	return false
+}
+
+func (b *AppConfigBuilder) loadFloatVariableFromEnv(envName string, Default string) float64 {
+	return 0
+}
+
+func (b *AppConfigBuilder) loadDurationVariableFromEnv(envName string, Default string) time.Duration {
+	return 0
+}
+
+func (b *AppConfigBuilder) loadSecretVariableFromEnv(envName string, Default string) Secret {
+	return Secret(Default)
+}
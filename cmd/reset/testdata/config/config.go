@@ -0,0 +1,47 @@
+package config
+
+import "time"
+
+// Secret содержит секретное значение.
+type Secret string
+
+// Level содержит числовой уровень.
+type Level int
+
+// DBConfig содержит именованную вложенную структуру со своим методом Validate().
+// generate:reset
+// generate:validate
+type DBConfig struct {
+	DSN   Secret `env:"DSN" validate:"required,min=8"`
+	Scope string `env:"SCOPE" validate:"oneof=global user none"`
+}
+
+// Limits содержит структуру только с методом Validate().
+// generate:validate
+type Limits struct {
+	MaxItems int `validate:"min=1"`
+}
+
+// AppConfig содержит поля всех поддерживаемых видов.
+// generate:reset
+// generate:validate
+type AppConfig struct {
+	LoggingLevel string        `env:"LOGGING_LEVEL" validate:"oneof=debug info warn error"`
+	Level        Level         `validate:"oneof=1 2 3"`
+	Timeout      time.Duration `env:"TIMEOUT" validate:"min=0"`
+	Interval     time.Duration `env:"INTERVAL" validate:"min=1ms,max=1h"`
+	Tags         []string      `validate:"max=10"`
+	Labels       map[string]string
+	Ratio        *float64 `validate:"required,min=0,max=1"`
+	DB           DBConfig
+	Limits       *Limits `validate:"required"`
+	Optional     *Limits
+
+	Server struct {
+		BaseURL          string `env:"BASE_URL" validate:"required,url"`
+		ShortLinksLength int    `env:"SHORT_LINKS_LENGTH" validate:"min=4,max=32"`
+		TLS              *struct {
+			CertFile string `validate:"max=255"`
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validateRules содержит правила проверки поля из тега validate
+type validateRules struct {
+	Required bool
+	Min      string // Пустое значение - правило не задано
+	Max      string // Пустое значение - правило не задано
+	MinValue string // Значение min для сравнения в коде, длительности переводятся в наносекунды
+	MaxValue string // Значение max для сравнения в коде, длительности переводятся в наносекунды
+	URL      bool
+	OneOf    []string
+}
+
+// Виды значений полей, от которых зависит смысл правил min и max
+const (
+	kindNumber     = "number"     // Числа, в том числе типы других пакетов, например time.Duration
+	kindString     = "string"     // Строки, min и max ограничивают длину
+	kindCollection = "collection" // Слайсы и мапы, min и max ограничивают количество элементов
+	kindBool       = "bool"
+)
+
+// parseValidateTag разбирает тег вида "required,min=4,max=32,url,oneof=debug info"
+func parseValidateTag(tag string) (validateRules, error) {
+	var rules validateRules
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "required", "url":
+			if hasValue {
+				return rules, fmt.Errorf("rule %s does not take a value", name)
+			}
+			rules.Required = rules.Required || name == "required"
+			rules.URL = rules.URL || name == "url"
+		case "min", "max":
+			if value == "" {
+				return rules, fmt.Errorf("rule %s requires a value", name)
+			}
+			if name == "min" {
+				rules.Min = value
+			} else {
+				rules.Max = value
+			}
+		case "oneof":
+			rules.OneOf = strings.Fields(value)
+			if len(rules.OneOf) == 0 {
+				return rules, fmt.Errorf("rule oneof requires values separated by spaces")
+			}
+		default:
+			return rules, fmt.Errorf("unknown rule '%s'", rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// boundValue возвращает значение правила min или max для сравнения в коде. Для полей time.Duration
+// допускаются длительности вида 500ms или 1m30s, они переводятся в наносекунды
+func boundValue(field FieldInfo, rule string, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value, nil
+	}
+
+	if strings.TrimPrefix(field.Type, "*") == "time.Duration" {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return "", fmt.Errorf("rule %s requires a duration, got '%s'", rule, value)
+		}
+		return strconv.FormatInt(int64(duration), 10), nil
+	}
+
+	return "", fmt.Errorf("rule %s requires a number, got '%s'", rule, value)
+}
+
+// fieldKind возвращает вид значения поля по его базовому типу
+func fieldKind(field FieldInfo) string {
+	baseType := strings.TrimPrefix(field.Underlying, "*")
+
+	switch {
+	case field.IsSlice || field.IsMap || strings.HasPrefix(baseType, "[]") || strings.HasPrefix(baseType, "map["):
+		return kindCollection
+	case baseType == "string":
+		return kindString
+	case baseType == "bool":
+		return kindBool
+	default:
+		return kindNumber
+	}
+}
+
+// validateGenerator накапливает код методов Validate() и импорты, которые им нужны
+type validateGenerator struct {
+	builder strings.Builder
+	usesFmt bool
+	usesURL bool
+}
+
+// generateValidateMethods генерирует код методов Validate() для всех структур по тегам validate их полей.
+// Возвращает ошибку, если тег содержит неизвестное правило или правило не подходит к типу поля
+func generateValidateMethods(structs []StructInfo) (string, error) {
+	var methods validateGenerator
+
+	for _, s := range structs {
+		if err := methods.writeMethod(s); err != nil {
+			return "", fmt.Errorf("%s.%w", s.Name, err)
+		}
+	}
+
+	var builder strings.Builder
+
+	// Заголовок файла
+	builder.WriteString("// Code generated by reset tool. DO NOT EDIT.\n")
+	builder.WriteString("//go:generate go run github.com/Alexey-zaliznuak/shortener/cmd/reset\n\n")
+	builder.WriteString(fmt.Sprintf("package %s\n\n", structs[0].Package))
+
+	builder.WriteString("import \"errors\"\n")
+	if methods.usesFmt {
+		builder.WriteString("import \"fmt\"\n")
+	}
+	if methods.usesURL {
+		builder.WriteString("import \"net/url\"\n")
+	}
+	builder.WriteString("\n")
+
+	builder.WriteString(methods.builder.String())
+
+	return builder.String(), nil
+}
+
+// writeMethod генерирует код метода Validate() для одной структуры
+func (g *validateGenerator) writeMethod(s StructInfo) error {
+	g.builder.WriteString(fmt.Sprintf("func (rs *%s) Validate() error {\n", s.Name))
+	g.builder.WriteString("\tif rs == nil {\n")
+	g.builder.WriteString("\t\treturn nil\n")
+	g.builder.WriteString("\t}\n\n")
+	g.builder.WriteString("\tvar errs []error\n\n")
+
+	if err := g.writeFields(s.Fields, "rs", "", "\t"); err != nil {
+		return err
+	}
+
+	g.builder.WriteString("\n\treturn errors.Join(errs...)\n")
+	g.builder.WriteString("}\n\n")
+
+	return nil
+}
+
+// writeFields генерирует проверки полей. В сообщениях об ошибках поле называется по тегу env,
+// если он указан, иначе путем к полю от проверяемой структуры, например Server.BaseURL
+func (g *validateGenerator) writeFields(fields []FieldInfo, prefix string, namePrefix string, indent string) error {
+	for _, field := range fields {
+		if field.Name == "" {
+			// Встроенное поле - пропускаем
+			continue
+		}
+
+		fieldName := prefix + "." + field.Name
+		name := field.Env
+		if name == "" {
+			name = namePrefix + field.Name
+		}
+
+		var rules validateRules
+		if field.ValidateTag != "" {
+			parsed, err := parseValidateTag(field.ValidateTag)
+			if err != nil {
+				return fmt.Errorf("%s: %w", namePrefix+field.Name, err)
+			}
+			rules = parsed
+		}
+
+		if field.IsStruct {
+			if rules.Min != "" || rules.Max != "" || rules.URL || rules.OneOf != nil || (rules.Required && !field.IsPtr) {
+				return fmt.Errorf("%s: only required is supported for struct pointers", namePrefix+field.Name)
+			}
+
+			if err := g.writeStruct(field, fieldName, name, namePrefix, rules, indent); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field.ValidateTag == "" {
+			continue
+		}
+
+		if err := g.writeRules(field, fieldName, name, rules, indent); err != nil {
+			return fmt.Errorf("%s: %w", namePrefix+field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// writeStruct генерирует проверку вложенной структуры: поля анонимной структуры проверяются напрямую,
+// именованная структура проверяется своим методом Validate(), если он есть
+func (g *validateGenerator) writeStruct(field FieldInfo, fieldName string, name string, namePrefix string, rules validateRules, indent string) error {
+	inner := indent
+
+	if field.IsPtr {
+		if rules.Required {
+			g.builder.WriteString(fmt.Sprintf("%sif %s == nil {\n", indent, fieldName))
+			g.builder.WriteString(fmt.Sprintf("%s\terrs = append(errs, errors.New(%s))\n", indent, strconv.Quote(name+" is required")))
+			g.builder.WriteString(fmt.Sprintf("%s} else {\n", indent))
+		} else {
+			g.builder.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, fieldName))
+		}
+		inner = indent + "\t"
+	}
+
+	if field.IsAnonymousStruct {
+		if err := g.writeFields(field.AnonymousFields, fieldName, namePrefix+field.Name+".", inner); err != nil {
+			return err
+		}
+	} else {
+		target := "&" + fieldName
+		if field.IsPtr {
+			target = fieldName
+		}
+
+		g.builder.WriteString(fmt.Sprintf("%sif validator, ok := interface{}(%s).(interface{ Validate() error }); ok {\n", inner, target))
+		g.builder.WriteString(fmt.Sprintf("%s\tif err := validator.Validate(); err != nil {\n", inner))
+		g.builder.WriteString(fmt.Sprintf("%s\t\terrs = append(errs, err)\n", inner))
+		g.builder.WriteString(fmt.Sprintf("%s\t}\n", inner))
+		g.builder.WriteString(fmt.Sprintf("%s}\n", inner))
+	}
+
+	if field.IsPtr {
+		g.builder.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+
+	return nil
+}
+
+// writeRules генерирует проверки поля по правилам. Пустые строки, слайсы, мапы и nil указатели
+// проверяются только правилом required, остальные правила к ним не применяются
+func (g *validateGenerator) writeRules(field FieldInfo, fieldName string, name string, rules validateRules, indent string) error {
+	kind := fieldKind(field)
+
+	if kind == kindBool {
+		return fmt.Errorf("rules are not supported for bool")
+	}
+	if rules.URL && kind != kindString {
+		return fmt.Errorf("rule url requires a string")
+	}
+	if rules.OneOf != nil && kind == kindCollection {
+		return fmt.Errorf("rule oneof requires a string or a number")
+	}
+	if rules.OneOf != nil && kind == kindNumber {
+		for _, value := range rules.OneOf {
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return fmt.Errorf("rule oneof requires numbers, got '%s'", value)
+			}
+		}
+	}
+
+	var err error
+	if rules.MinValue, err = boundValue(field, "min", rules.Min); err != nil {
+		return err
+	}
+	if rules.MaxValue, err = boundValue(field, "max", rules.Max); err != nil {
+		return err
+	}
+
+	hasOtherRules := rules.Min != "" || rules.Max != "" || rules.URL || rules.OneOf != nil
+	required := fmt.Sprintf("errs = append(errs, errors.New(%s))", strconv.Quote(name+" is required"))
+
+	value := fieldName
+	inner := indent
+	closeBlock := false
+
+	switch {
+	case field.IsPtr:
+		value = "*" + fieldName
+		if rules.Required {
+			g.builder.WriteString(fmt.Sprintf("%sif %s == nil {\n%s\t%s\n%s}", indent, fieldName, indent, required, indent))
+			if !hasOtherRules {
+				g.builder.WriteString("\n")
+				return nil
+			}
+			g.builder.WriteString(" else {\n")
+		} else {
+			g.builder.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, fieldName))
+		}
+		inner, closeBlock = indent+"\t", true
+	case kind == kindNumber:
+		if rules.Required {
+			g.builder.WriteString(fmt.Sprintf("%sif %s == 0 {\n%s\t%s\n%s}\n", indent, fieldName, indent, required, indent))
+		}
+	default:
+		if rules.Required {
+			g.builder.WriteString(fmt.Sprintf("%sif len(%s) == 0 {\n%s\t%s\n%s}", indent, fieldName, indent, required, indent))
+			if !hasOtherRules {
+				g.builder.WriteString("\n")
+				return nil
+			}
+			g.builder.WriteString(" else {\n")
+		} else if hasOtherRules {
+			g.builder.WriteString(fmt.Sprintf("%sif len(%s) > 0 {\n", indent, fieldName))
+		}
+		inner, closeBlock = indent+"\t", hasOtherRules
+	}
+
+	g.writeBounds(kind, value, name, rules, inner)
+
+	if rules.URL {
+		g.usesURL, g.usesFmt = true, true
+
+		stringValue := value
+		if strings.TrimPrefix(field.Type, "*") != "string" {
+			stringValue = "string(" + value + ")"
+		}
+
+		g.builder.WriteString(fmt.Sprintf("%sif u, err := url.Parse(%s); err != nil || u.Scheme == \"\" || u.Host == \"\" {\n", inner, stringValue))
+		g.builder.WriteString(fmt.Sprintf("%s\terrs = append(errs, fmt.Errorf(%s, %s))\n", inner, errorFormat(name, " must be an absolute URL, got '%v'"), value))
+		g.builder.WriteString(fmt.Sprintf("%s}\n", inner))
+	}
+
+	if rules.OneOf != nil {
+		g.usesFmt = true
+
+		cases := make([]string, len(rules.OneOf))
+		for i, item := range rules.OneOf {
+			cases[i] = item
+			if kind == kindString {
+				cases[i] = strconv.Quote(item)
+			}
+		}
+
+		message := strconv.Quote("unknown " + escapePercent(name) + " '%v', expected one of: " + escapePercent(strings.Join(rules.OneOf, ", ")))
+
+		g.builder.WriteString(fmt.Sprintf("%sswitch %s {\n", inner, value))
+		g.builder.WriteString(fmt.Sprintf("%scase %s:\n", inner, strings.Join(cases, ", ")))
+		g.builder.WriteString(fmt.Sprintf("%sdefault:\n", inner))
+		g.builder.WriteString(fmt.Sprintf("%s\terrs = append(errs, fmt.Errorf(%s, %s))\n", inner, message, value))
+		g.builder.WriteString(fmt.Sprintf("%s}\n", inner))
+	}
+
+	if closeBlock {
+		g.builder.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+
+	return nil
+}
+
+// writeBounds генерирует проверку правил min и max: для чисел ограничивается значение,
+// для строк, слайсов и мап - длина
+func (g *validateGenerator) writeBounds(kind string, value string, name string, rules validateRules, indent string) {
+	if rules.Min == "" && rules.Max == "" {
+		return
+	}
+
+	g.usesFmt = true
+
+	checked, subject, verb := value, "", "%v"
+	if kind != kindNumber {
+		checked, subject, verb = "len("+value+")", " length", "%d"
+	}
+
+	var condition, message string
+
+	switch {
+	case rules.Min != "" && rules.Max != "":
+		condition = fmt.Sprintf("%s < %s || %s > %s", checked, rules.MinValue, checked, rules.MaxValue)
+		message = fmt.Sprintf("%s must be between %s and %s, got %s", subject, rules.Min, rules.Max, verb)
+	case rules.Min != "":
+		condition = fmt.Sprintf("%s < %s", checked, rules.MinValue)
+		message = fmt.Sprintf("%s must be at least %s, got %s", subject, rules.Min, verb)
+	default:
+		condition = fmt.Sprintf("%s > %s", checked, rules.MaxValue)
+		message = fmt.Sprintf("%s must be at most %s, got %s", subject, rules.Max, verb)
+	}
+
+	g.builder.WriteString(fmt.Sprintf("%sif %s {\n", indent, condition))
+	g.builder.WriteString(fmt.Sprintf("%s\terrs = append(errs, fmt.Errorf(%s, %s))\n", indent, errorFormat(name, message), checked))
+	g.builder.WriteString(fmt.Sprintf("%s}\n", indent))
+}
+
+// errorFormat возвращает литерал строки формата сообщения об ошибке поля name
+func errorFormat(name string, message string) string {
+	return strconv.Quote(escapePercent(name) + message)
+}
+
+// escapePercent экранирует знаки процента для строки формата fmt
+func escapePercent(text string) string {
+	return strings.ReplaceAll(text, "%", "%%")
+}
@@ -5,19 +5,33 @@ import (
 	"database/sql"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
 	"github.com/Alexey-zaliznuak/shortener/internal/config"
 	"github.com/Alexey-zaliznuak/shortener/internal/handler"
-	"github.com/Alexey-zaliznuak/shortener/internal/handler/audit"
 	"github.com/Alexey-zaliznuak/shortener/internal/logger"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/ratelimit"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/webhook"
+	"github.com/Alexey-zaliznuak/shortener/internal/server"
 	"github.com/Alexey-zaliznuak/shortener/internal/service"
+	"github.com/Alexey-zaliznuak/shortener/internal/service/urlcheck"
 	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/zap"
 
 	_ "net/http/pprof"
@@ -28,19 +42,61 @@ func main() {
 
 	// Init config
 	flagsConfig := config.CreateFLagsInitialConfig()
-	flag.Parse()
+	flag.Usage = usage
+
+	// "shortener config check [flags]" validates the configuration and prints it without starting the service
+	args := os.Args[1:]
+	checkConfig := len(args) >= 2 && args[0] == "config" && args[1] == "check"
+	if checkConfig {
+		args = args[2:]
+	}
+
+	// The command line flag set exits on invalid flags
+	_ = flag.CommandLine.Parse(args)
 
 	cfg, err := config.GetConfig(flagsConfig)
 
+	if checkConfig {
+		os.Exit(runConfigCheck(cfg, err, os.Stdout, os.Stderr))
+	}
+
 	if err != nil {
-		logger.Log.Error(err.Error())
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	logger.Initialize(cfg.LoggingLevel)
 	defer logger.Log.Sync()
 
+	// Secrets are redacted in the log
 	logger.Log.Info("Configuration", zap.Any("config", cfg))
 
+	if err := config.CheckSecrets(cfg); err != nil {
+		logger.Log.Fatal(err.Error())
+	}
+
+	// Init tracing
+	exporter, err := newSpanExporter(context.Background(), cfg)
+	if err != nil {
+		logger.Log.Fatal(err.Error())
+	}
+
+	if exporter != nil {
+		// Root spans are sampled with the ratio, children follow the decision of their parent
+		tracerProvider := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+			sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.Tracing.ServiceName))),
+		)
+		otel.SetTracerProvider(tracerProvider)
+
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			utils.LogErrorWrapper(tracerProvider.Shutdown(ctx))
+		}()
+	}
+
 	// Init dependencies
 	if cfg.DB.DatabaseDSN != "" {
 		db, err = database.NewDatabaseConnectionPool(cfg)
@@ -59,31 +115,237 @@ func main() {
 		logger.Log.Fatal(err.Error())
 	}
 
+	urlPolicy := service.NewURLPolicy(cfg)
+	if err := urlPolicy.LoadDomainLists(cfg.Policy.DomainAllowListFile, cfg.Policy.DomainDenyListFile); err != nil {
+		logger.Log.Fatal(err.Error())
+	}
+
 	linksService := service.NewLinksService(linksRepository, cfg)
+	linksService.UseURLPolicy(urlPolicy)
+
+	// Server process
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The blocklist is created without files as well, they may be configured on reload
+	blocklist, err := urlcheck.NewHashPrefixBlocklist(blocklistFiles(cfg))
+	if err != nil {
+		logger.Log.Fatal(err.Error())
+	}
+
+	if cfg.URLCheck.ReloadInterval > 0 {
+		go blocklist.Watch(ctx, cfg.URLCheck.ReloadInterval)
+	}
+	linksService.UseURLChecker(blocklist)
+
+	// Handlers of SIGHUP
+	var onHangup []func()
 
 	auditor := audit.NewAuditorShortURLOperationManager()
 
 	if cfg.Audit.AuditFile != "" {
-		auditor.UseAuditor(&audit.AuditShortURLOperationFile{FilePath: cfg.Audit.AuditFile})
+		auditFile := &audit.AuditShortURLOperationFile{
+			FilePath:       cfg.Audit.AuditFile,
+			MaxSize:        int64(cfg.Audit.FileMaxSizeMB) << 20,
+			RotateInterval: cfg.Audit.FileRotateInterval,
+			MaxBackups:     cfg.Audit.FileMaxBackups,
+			Compress:       cfg.Audit.FileCompress,
+		}
+
+		if cfg.Audit.HMACKey != "" {
+			auditFile.Chain = audit.NewHashChain([]byte(cfg.Audit.HMACKey.Value()), cfg.Audit.CheckpointInterval)
+		}
+		defer func() { utils.LogErrorWrapper(auditFile.Close()) }()
+
+		go auditFile.Run(ctx, cfg.Audit.FileFlushInterval)
+
+		// Reopen the file moved by logrotate
+		onHangup = append(onHangup, func() {
+			if err := auditFile.Reopen(); err != nil {
+				logger.Log.Error("Audit file reopen failed", zap.Error(err))
+			}
+		})
+
+		auditor.UseAuditor(auditFile)
 	}
 
+	if cfg.Audit.SyslogURL != "" {
+		auditSyslog, err := audit.NewAuditShortURLOperationSyslog(cfg.Audit.SyslogURL, cfg.Audit.SyslogCAFile, cfg.Audit.SyslogAppName)
+		if err != nil {
+			logger.Log.Fatal(err.Error())
+		}
+		defer func() { utils.LogErrorWrapper(auditSyslog.Close()) }()
+
+		auditor.UseAuditor(auditSyslog)
+	}
+
+	if cfg.Audit.PubSubURL != "" {
+		publisher, err := audit.NewNATSPublisher(cfg.Audit.PubSubURL, "shortener")
+		if err != nil {
+			logger.Log.Fatal(err.Error())
+		}
+		defer func() { utils.LogErrorWrapper(publisher.Close()) }()
+
+		auditor.UseAuditor(&audit.AuditShortURLOperationPubSub{Publisher: publisher, Subject: cfg.Audit.PubSubSubject})
+	}
+
+	var auditDelivery *audit.AuditShortURLOperationDelivery
+	// auditDeliveryDone is closed when auditDelivery.Run returns
+	auditDeliveryDone := make(chan struct{})
+
 	if cfg.Audit.AuditURL != "" {
-		auditor.UseAuditor(&audit.AuditShortURLOperationHTTP{URL: cfg.Audit.AuditURL})
+		outbox, err := audit.NewFileOutbox(cfg.Audit.OutboxPath)
+		if err != nil {
+			logger.Log.Fatal(err.Error())
+		}
+		defer func() { utils.LogErrorWrapper(outbox.Close()) }()
+
+		auditDelivery = audit.NewAuditShortURLOperationDelivery(
+			outbox,
+			&audit.AuditShortURLOperationHTTP{URL: cfg.Audit.AuditURL, Client: &http.Client{Timeout: cfg.Audit.RequestTimeout}},
+			audit.DeliveryConfig{
+				DeadLetterPath: cfg.Audit.DeadLetterPath,
+				BatchSize:      cfg.Audit.BatchSize,
+				FlushInterval:  cfg.Audit.FlushInterval,
+				RequestTimeout: cfg.Audit.RequestTimeout,
+				MaxAttempts:    cfg.Audit.MaxAttempts,
+				RetryBaseDelay: cfg.Audit.RetryBaseDelay,
+				RetryMaxDelay:  cfg.Audit.RetryMaxDelay,
+			},
+		)
+
+		go func() {
+			defer close(auditDeliveryDone)
+			auditDelivery.Run(ctx)
+		}()
+		auditor.UseAuditor(auditDelivery)
+	}
+
+	webhooksRepository, err := webhook.NewWebhooksRepository(context.Background(), cfg, db)
+	if err != nil {
+		logger.Log.Fatal(err.Error())
+	}
+
+	webhooksService := service.NewWebhooksService(webhooksRepository, cfg)
+	webhooksService.UseURLPolicy(urlPolicy)
+
+	webhookDispatcher := service.NewWebhookDispatcher(webhooksRepository, linksRepository, cfg)
+	go webhookDispatcher.Run(ctx)
+	auditor.UseAuditor(webhookDispatcher)
+
+	linksService.UseAuditor(auditor)
+
+	healthService := service.NewHealthService(linksRepository)
+	if db != nil {
+		healthService.AddCheck("migrations", func(ctx context.Context) error { return database.CheckMigrations(ctx, db) })
+	}
+	healthService.AddCheck("audit", auditor.CheckHealth)
+
+	rateLimitRepository, err := ratelimit.NewRateLimitRepository(cfg, db)
+	if err != nil {
+		logger.Log.Fatal(err.Error())
 	}
+	go rateLimitRepository.Run(ctx)
 
-	router := handler.NewRouter()
 	authService := service.NewAuthService(cfg)
+	authService.UseAuditor(auditor)
+
+	router := handler.NewRouter(cfg)
+	rateLimitRules := handler.RegisterRateLimits(router, cfg, rateLimitRepository, authService)
 	handler.RegisterLinksRoutes(router, linksService, authService, auditor, db)
-	handler.RegisterAppHandlerRoutes(router, db)
+	handler.RegisterWebhooksRoutes(router, webhooksService)
+	handler.RegisterAppHandlerRoutes(router, db, healthService)
 
-	// Server process
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
+	// Apply reloadable settings without restart
+	configWatcher := config.NewWatcher(flagsConfig, cfg)
+
+	configWatcher.OnReload(func(reloaded *config.AppConfig, changed []string) {
+		if err := logger.SetLevel(reloaded.LoggingLevel); err != nil {
+			logger.Log.Error("Logging level is not changed", zap.Error(err))
+		}
+
+		if slices.Contains(changed, "URL_BLOCKLIST_FILES") {
+			if err := blocklist.SetFiles(blocklistFiles(reloaded)); err != nil {
+				logger.Log.Error("Blocklist files are not changed", zap.Error(err))
+			}
+		}
+
+		rateLimitRules.Store(handler.RateLimitRules(reloaded))
+		linksService.ApplyConfig(reloaded)
 
-	srv := &http.Server{Addr: cfg.Server.Address, Handler: router}
+		auditor.AuditNotify(audit.AuditPayload{Action: audit.ConfigActionReloaded, Changes: changed})
+	})
+
+	go configWatcher.Watch(ctx, cfg.ReloadInterval)
+
+	onHangup = append(onHangup, func() {
+		changed, err := configWatcher.Reload()
+		if err != nil {
+			logger.Log.Error("Configuration reload failed", zap.Error(err))
+			return
+		}
+		logger.Log.Info("Configuration reloaded", zap.Strings("changed", changed))
+	})
+
+	srv := server.NewServer(cfg, router)
+
+	var redirectSrv *http.Server
+
+	if cfg.TLS.CertFile != "" {
+		reloader, err := server.NewCertificateReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			logger.Log.Fatal(err.Error())
+		}
+
+		srv.TLSConfig, err = server.NewTLSConfig(cfg, reloader)
+		if err != nil {
+			logger.Log.Fatal(err.Error())
+		}
+
+		if cfg.TLS.ReloadInterval > 0 {
+			go reloader.Watch(ctx, cfg.TLS.ReloadInterval)
+		}
+
+		onHangup = append(onHangup, func() {
+			if err := reloader.Reload(); err != nil {
+				logger.Log.Error("Certificate reload failed", zap.Error(err))
+				return
+			}
+			logger.Log.Info("Certificate reloaded", zap.String("file", cfg.TLS.CertFile))
+		})
+
+		if cfg.TLS.RedirectAddress != "" {
+			redirectSrv = server.NewRedirectServer(cfg)
+
+			go func() {
+				if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Log.Fatal(fmt.Errorf("listen redirect: %w", err).Error())
+				}
+			}()
+		}
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
 
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		for range hangup {
+			for _, handle := range onHangup {
+				handle()
+			}
+		}
+	}()
+
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+
+		if err != nil && err != http.ErrServerClosed {
 			logger.Log.Fatal(fmt.Errorf("listen: %w", err).Error())
 		}
 	}()
@@ -98,16 +360,94 @@ func main() {
 
 	stop()
 
+	// Readiness fails from now on, so load balancers stop sending new requests
+	healthService.StartShutdown()
+
 	logger.Log.Info("shutting down gracefully, press Ctrl+C again to force")
 
+	// Servers keep accepting requests until load balancers notice failed readiness
+	time.Sleep(cfg.Server.ShutdownDrainDelay)
+
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if redirectSrv != nil {
+		utils.LogErrorWrapper(redirectSrv.Shutdown(ctx))
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Log.Fatal(fmt.Errorf("server forced to shutdown: %w", err).Error())
 	}
 
+	// Not delivered events stay in the outbox until the next start.
+	// Run is awaited first, so the batch it is delivering is not sent twice
+	if auditDelivery != nil {
+		<-auditDeliveryDone
+
+		if err := auditDelivery.Flush(ctx); err != nil {
+			logger.Log.Warn("Audit events were not flushed", zap.Error(err))
+		}
+	}
+
 	logger.Log.Info("Server exited")
 }
+
+// blocklistFiles returns the configured blocklist files, nil if URLs are not checked.
+func blocklistFiles(cfg *config.AppConfig) []string {
+	if cfg.URLCheck.BlocklistFiles == "" {
+		return nil
+	}
+	return strings.Split(cfg.URLCheck.BlocklistFiles, ",")
+}
+
+// newSpanExporter returns the exporter selected by the configuration, nil if spans are not recorded.
+func newSpanExporter(ctx context.Context, cfg *config.AppConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Tracing.Exporter {
+	case config.TracingExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case config.TracingExporterOTLP:
+		// The exporter posts to the endpoint path as is, so the traces path is appended like the SDK does by default
+		endpoint := strings.TrimSuffix(cfg.Tracing.OTLPEndpoint, "/")
+		if !strings.HasSuffix(endpoint, "/v1/traces") {
+			endpoint += "/v1/traces"
+		}
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	return nil, nil
+}
+
+// usage prints the command line help.
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  %[1]s [flags]\n  %[1]s config check [flags]\n\nFlags:\n", os.Args[0])
+	flag.PrintDefaults()
+	fmt.Fprintf(flag.CommandLine.Output(), "\nEnvironment variables:\n%s", config.Usage())
+}
+
+// runConfigCheck prints the effective configuration to stdout and the configuration errors to stderr.
+// It returns the process exit code.
+func runConfigCheck(cfg *config.AppConfig, configErr error, stdout io.Writer, stderr io.Writer) int {
+	dump, err := config.Dump(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if _, err := stdout.Write(dump); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if configErr == nil {
+		configErr = config.CheckSecrets(cfg)
+	}
+
+	if configErr != nil {
+		fmt.Fprintln(stderr, configErr)
+		return 1
+	}
+
+	fmt.Fprintln(stderr, "Configuration is valid")
+	return 0
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// возможно это стоит вынести в слой service?
+
+type ShortURLAction = string
+
+var (
+	ShortURLActionGet         ShortURLAction = "follow"
+	ShortURLActionCreate      ShortURLAction = "shorten"
+	ShortURLActionBatchCreate ShortURLAction = "shorten_batch"
+	ShortURLActionDelete      ShortURLAction = "delete"
+	ShortURLActionUpdate      ShortURLAction = "update"
+
+	AuthActionTokenIssued   ShortURLAction = "auth_token_issued"
+	AuthActionTokenRejected ShortURLAction = "auth_token_rejected"
+
+	ConfigActionReloaded ShortURLAction = "config_reloaded"
+)
+
+type AuditorShortURLOperation interface {
+	Audit(payload AuditPayload) error
+}
+
+// HealthChecker is implemented by auditors depending on external systems.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+type AuditorShortURLOperationManager struct {
+	auditors []AuditorShortURLOperation
+}
+
+// AuditNotify sends the event to every auditor, TS is set to the current time if empty.
+func (m *AuditorShortURLOperationManager) AuditNotify(payload AuditPayload) {
+	if payload.TS == 0 {
+		payload.TS = time.Now().Unix()
+	}
+
+	for _, auditor := range m.auditors {
+		if err := auditor.Audit(payload); err != nil {
+			metrics.AuditFailures.WithLabelValues(auditorName(auditor)).Inc()
+			logger.Log.Error("Audit failed", zap.String("action", payload.Action), zap.Error(err))
+		}
+	}
+}
+
+// CheckHealth checks auditors implementing HealthChecker, errors are prefixed with auditor names.
+func (m *AuditorShortURLOperationManager) CheckHealth(ctx context.Context) error {
+	var errs []error
+
+	for _, auditor := range m.auditors {
+		if checker, ok := auditor.(HealthChecker); ok {
+			if err := checker.CheckHealth(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", auditorName(auditor), err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// auditorName returns short name of the auditor type, e.g. "file" for *AuditShortURLOperationFile.
+func auditorName(auditor AuditorShortURLOperation) string {
+	name := fmt.Sprintf("%T", auditor)
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	return strings.ToLower(strings.TrimPrefix(name, "AuditShortURLOperation"))
+}
+
+func (m *AuditorShortURLOperationManager) UseAuditor(newAuditor AuditorShortURLOperation) {
+	m.auditors = append(m.auditors, newAuditor)
+}
+
+func NewAuditorShortURLOperationManager() *AuditorShortURLOperationManager {
+	return &AuditorShortURLOperationManager{
+		auditors: make([]AuditorShortURLOperation, 0),
+	}
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/Alexey-zaliznuak/shortener/internal/audit"
+
+const defaultAuditRequestTimeout = 5 * time.Second
+
+type AuditShortURLOperationHTTP struct {
+	URL string
+	// Client is used for requests, http.Client with defaultAuditRequestTimeout if nil.
+	Client *http.Client
+}
+
+type AuditPayload struct {
+	TS     int64          `json:"ts"`
+	Action ShortURLAction `json:"action"`
+	UserID string         `json:"user_id"`
+	URL    string         `json:"url"`
+
+	RequestID string `json:"request_id,omitempty"`
+	ClientIP  string `json:"client_ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Shortcut  string `json:"shortcut,omitempty"`
+	// Status contains HTTP status of the operation result.
+	Status int `json:"status,omitempty"`
+	// Changes contains names of settings changed by a configuration reload.
+	Changes []string `json:"changes,omitempty"`
+}
+
+func (a *AuditShortURLOperationHTTP) Audit(payload AuditPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	return a.post(context.Background(), data)
+}
+
+// Send delivers the batch as a JSON array in a single request.
+func (a *AuditShortURLOperationHTTP) Send(ctx context.Context, batch []AuditPayload) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	return a.post(ctx, data)
+}
+
+func (a *AuditShortURLOperationHTTP) post(ctx context.Context, data []byte) (err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "POST audit",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("http.request.method", http.MethodPost)),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	span.SetAttributes(attribute.String("url.full", req.URL.Redacted()))
+
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultAuditRequestTimeout}
+	}
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ActionCheckpoint marks checkpoint records of the hash chain.
+const ActionCheckpoint ShortURLAction = "checkpoint"
+
+// hmacFieldPrefix starts the last field of a chained record. HMAC is calculated over
+// the record bytes without this field, so any change of the written bytes is detected.
+const hmacFieldPrefix = `,"hmac":"`
+
+// Checkpoint anchors the chain state, it can be copied to external storage
+// to prove the log was not rewritten up to the checkpoint.
+type Checkpoint struct {
+	// Seq contains sequence number of the last record before the checkpoint.
+	Seq uint64 `json:"seq"`
+	// Head contains hash of the last record before the checkpoint.
+	Head string `json:"head"`
+}
+
+type chainedRecord struct {
+	AuditPayload
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+	Seq        uint64      `json:"seq"`
+	PrevHash   string      `json:"prev_hash"`
+}
+
+// HashChain seals audit records into a tamper-evident chain: every record contains
+// its sequence number, SHA-256 of the previous record line and HMAC-SHA256 of itself.
+// Every CheckpointInterval records a checkpoint record is added.
+type HashChain struct {
+	key                []byte
+	checkpointInterval int
+
+	seq             uint64
+	head            string
+	sinceCheckpoint int
+}
+
+// Seal returns lines for the payload and, when it is time, the checkpoint.
+func (c *HashChain) Seal(payload AuditPayload) ([]byte, error) {
+	line, err := c.seal(chainedRecord{AuditPayload: payload})
+	if err != nil {
+		return nil, err
+	}
+
+	c.sinceCheckpoint++
+
+	if c.checkpointInterval <= 0 || c.sinceCheckpoint < c.checkpointInterval {
+		return line, nil
+	}
+
+	checkpoint, err := c.seal(chainedRecord{
+		AuditPayload: AuditPayload{TS: payload.TS, Action: ActionCheckpoint},
+		Checkpoint:   &Checkpoint{Seq: c.seq, Head: c.head},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.sinceCheckpoint = 0
+
+	return append(line, checkpoint...), nil
+}
+
+// Resume continues the chain after the last line written to the log.
+func (c *HashChain) Resume(lastLine []byte) error {
+	lastLine = bytes.TrimRight(lastLine, "\n")
+	if len(lastLine) == 0 {
+		return nil
+	}
+
+	record, err := c.open(lastLine)
+	if err != nil {
+		return err
+	}
+
+	c.seq = record.Seq
+	c.head = lineHash(lastLine)
+
+	return nil
+}
+
+func (c *HashChain) seal(record chainedRecord) ([]byte, error) {
+	record.Seq = c.seq + 1
+	record.PrevHash = c.head
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	line := make([]byte, 0, len(body)+len(hmacFieldPrefix)+sha256.Size*2+3)
+	line = append(line, body[:len(body)-1]...)
+	line = append(line, hmacFieldPrefix...)
+	line = append(line, c.sign(body)...)
+	line = append(line, '"', '}')
+
+	c.seq = record.Seq
+	c.head = lineHash(line)
+
+	return append(line, '\n'), nil
+}
+
+// open checks HMAC of the line and returns the record.
+func (c *HashChain) open(line []byte) (*chainedRecord, error) {
+	index := bytes.LastIndex(line, []byte(hmacFieldPrefix))
+	if index < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, errors.New("record has no hmac")
+	}
+
+	signature := line[index+len(hmacFieldPrefix) : len(line)-2]
+	body := append(line[:index:index], '}')
+
+	if !hmac.Equal(signature, []byte(c.sign(body))) {
+		return nil, errors.New("hmac mismatch")
+	}
+
+	record := &chainedRecord{}
+	if err := json.Unmarshal(body, record); err != nil {
+		return nil, fmt.Errorf("malformed record: %w", err)
+	}
+
+	return record, nil
+}
+
+func (c *HashChain) sign(body []byte) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func lineHash(line []byte) string {
+	hash := sha256.Sum256(line)
+	return hex.EncodeToString(hash[:])
+}
+
+// NewHashChain creates HashChain signing records with key.
+// checkpointInterval 0 disables checkpoints.
+func NewHashChain(key []byte, checkpointInterval int) *HashChain {
+	return &HashChain{key: key, checkpointInterval: checkpointInterval}
+}
+
+// ChainBrokenError describes the first record breaking the chain.
+type ChainBrokenError struct {
+	// Line contains 1-based line number in the log.
+	Line int
+	// Seq contains sequence number of the record if it could be read.
+	Seq    uint64
+	Reason string
+}
+
+func (e *ChainBrokenError) Error() string {
+	return fmt.Sprintf("chain broken at line %d (seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// ChainSummary contains results of the successful verification.
+type ChainSummary struct {
+	Records     uint64
+	Checkpoints uint64
+	// FirstSeq and FirstPrevHash link the log to the previous rotated segment.
+	FirstSeq      uint64
+	FirstPrevHash string
+	// Head contains hash of the last record to verify the next segment.
+	Head    string
+	LastSeq uint64
+}
+
+// VerifyChain walks the log and returns ChainBrokenError for the first record which
+// was changed, removed or inserted. prevHash is the head of the previous segment,
+// empty value accepts any link of the first record.
+func VerifyChain(r io.Reader, key []byte, prevHash string) (*ChainSummary, error) {
+	chain := NewHashChain(key, 0)
+	summary := &ChainSummary{}
+
+	reader := bufio.NewReader(r)
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		line = bytes.TrimRight(line, "\n")
+
+		broken := func(seq uint64, reason string) error {
+			return &ChainBrokenError{Line: lineNumber, Seq: seq, Reason: reason}
+		}
+
+		record, openErr := chain.open(line)
+		if openErr != nil {
+			return nil, broken(0, openErr.Error())
+		}
+
+		if lineNumber == 1 {
+			summary.FirstSeq = record.Seq
+			summary.FirstPrevHash = record.PrevHash
+
+			if prevHash != "" && record.PrevHash != prevHash {
+				return nil, broken(record.Seq, "first record is not linked to the previous segment")
+			}
+		} else {
+			if record.Seq != chain.seq+1 {
+				return nil, broken(record.Seq, fmt.Sprintf("expected seq %d", chain.seq+1))
+			}
+
+			if record.PrevHash != chain.head {
+				return nil, broken(record.Seq, "previous record hash mismatch")
+			}
+		}
+
+		if record.Checkpoint != nil {
+			if record.Action != ActionCheckpoint || record.Checkpoint.Head != record.PrevHash || record.Checkpoint.Seq+1 != record.Seq {
+				return nil, broken(record.Seq, "checkpoint does not match the chain")
+			}
+
+			summary.Checkpoints++
+		} else {
+			summary.Records++
+		}
+
+		chain.seq = record.Seq
+		chain.head = lineHash(line)
+	}
+
+	summary.Head = chain.head
+	summary.LastSeq = chain.seq
+
+	return summary, nil
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testChainKey = []byte("test-key")
+
+func writeChainedLog(t *testing.T, path string, records int) {
+	sink := &AuditShortURLOperationFile{FilePath: path, Chain: NewHashChain(testChainKey, 3)}
+
+	for i := range records {
+		require.NoError(t, sink.Audit(AuditPayload{TS: int64(i), Action: ShortURLActionCreate, URL: "https://example.com"}))
+	}
+
+	require.NoError(t, sink.Close())
+}
+
+func TestVerifyChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writeChainedLog(t, path, 7)
+
+	// New process continues the chain of the existing log
+	writeChainedLog(t, path, 2)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	summary, err := VerifyChain(bytes.NewReader(data), testChainKey, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), summary.Records)
+	assert.Equal(t, uint64(2), summary.Checkpoints)
+	assert.Equal(t, uint64(11), summary.LastSeq)
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+
+	tests := []struct {
+		name   string
+		log    []byte
+		key    []byte
+		line   int
+		reason string
+	}{
+		{
+			name:   "edited record",
+			log:    bytes.Replace(data, []byte("https://example.com"), []byte("https://evil.example"), 1),
+			key:    testChainKey,
+			line:   1,
+			reason: "hmac mismatch",
+		},
+		{
+			name:   "removed record",
+			log:    bytes.Join(append(append([][]byte{}, lines[:2]...), lines[3:]...), nil),
+			key:    testChainKey,
+			line:   3,
+			reason: "expected seq 3",
+		},
+		{
+			name:   "swapped records",
+			log:    bytes.Join(append([][]byte{lines[1], lines[0]}, lines[2:]...), nil),
+			key:    testChainKey,
+			line:   2,
+			reason: "expected seq 3",
+		},
+		{
+			name:   "wrong key",
+			log:    data,
+			key:    []byte("other-key"),
+			line:   1,
+			reason: "hmac mismatch",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := VerifyChain(bytes.NewReader(test.log), test.key, "")
+
+			var broken *ChainBrokenError
+			require.ErrorAs(t, err, &broken)
+			assert.Equal(t, test.line, broken.Line)
+			assert.Equal(t, test.reason, broken.Reason)
+		})
+	}
+}
+
+func TestVerifyChain_RotatedSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink := &AuditShortURLOperationFile{FilePath: path, MaxSize: 600, Chain: NewHashChain(testChainKey, 0)}
+	for i := range 10 {
+		require.NoError(t, sink.Audit(AuditPayload{TS: int64(i), Action: ShortURLActionGet}))
+	}
+	require.NoError(t, sink.Close())
+
+	segments, err := sink.rotatedSegments()
+	require.NoError(t, err)
+	require.NotEmpty(t, segments)
+
+	head := ""
+	for _, segment := range append(segments, path) {
+		data, err := os.ReadFile(segment)
+		require.NoError(t, err)
+
+		summary, err := VerifyChain(bytes.NewReader(data), testChainKey, head)
+		require.NoError(t, err, segment)
+
+		head = summary.Head
+	}
+}
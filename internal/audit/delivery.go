@@ -0,0 +1,237 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// BatchSender delivers a batch of audit events to a remote receiver.
+type BatchSender interface {
+	Send(ctx context.Context, batch []AuditPayload) error
+}
+
+// ErrAuditReceiverUnavailable is reported by health checks while the last delivery attempt failed.
+var ErrAuditReceiverUnavailable = errors.New("audit receiver is unavailable")
+
+// DeliveryConfig contains settings of AuditShortURLOperationDelivery.
+type DeliveryConfig struct {
+	// DeadLetterPath is a file for batches which were not delivered after MaxAttempts.
+	DeadLetterPath string
+	BatchSize      int
+	FlushInterval  time.Duration
+	RequestTimeout time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// DeadLetterRecord is a line of the dead letter file.
+type DeadLetterRecord struct {
+	FailedAt int64          `json:"failed_at"`
+	Attempts int            `json:"attempts"`
+	Error    string         `json:"error"`
+	Batch    []AuditPayload `json:"batch"`
+}
+
+// AuditShortURLOperationDelivery stores events in the outbox and delivers them
+// in background with batching and retries, so requests never wait for the receiver
+// and events survive receiver outages and restarts.
+type AuditShortURLOperationDelivery struct {
+	outbox Outbox
+	sender BatchSender
+	cfg    DeliveryConfig
+
+	notify chan struct{}
+	// failing is set while delivery attempts fail.
+	failing atomic.Bool
+
+	// flushMu serializes Flush, so a batch is never peeked and acknowledged twice.
+	flushMu sync.Mutex
+
+	deadLetterMu sync.Mutex
+}
+
+func (d *AuditShortURLOperationDelivery) Audit(payload AuditPayload) error {
+	if err := d.outbox.Append(payload); err != nil {
+		return err
+	}
+
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run delivers events from the outbox until ctx is done.
+func (d *AuditShortURLOperationDelivery) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Flush(ctx); err != nil && ctx.Err() == nil {
+			logger.Log.Error("Audit delivery failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.notify:
+		}
+	}
+}
+
+// Flush delivers all events stored in the outbox. Batch which is being retried
+// when ctx is done stays in the outbox. Concurrent calls wait for each other.
+func (d *AuditShortURLOperationDelivery) Flush(ctx context.Context) error {
+	d.flushMu.Lock()
+	defer d.flushMu.Unlock()
+
+	for {
+		batch, position, err := d.outbox.Peek(d.cfg.BatchSize)
+		if err != nil {
+			return fmt.Errorf("read outbox: %w", err)
+		}
+
+		if len(batch) > 0 {
+			if err := d.deliver(ctx, batch); err != nil {
+				return err
+			}
+		}
+
+		if err := d.outbox.Ack(position); err != nil {
+			return fmt.Errorf("ack outbox: %w", err)
+		}
+
+		if len(batch) < d.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver sends the batch with exponential backoff and moves it to the dead letter file
+// after MaxAttempts failures. Returns error only when ctx is done or the batch is lost.
+func (d *AuditShortURLOperationDelivery) deliver(ctx context.Context, batch []AuditPayload) error {
+	var err error
+
+	for attempt := 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		requestCtx, cancel := context.WithTimeout(ctx, d.cfg.RequestTimeout)
+		err = d.sender.Send(requestCtx, batch)
+		cancel()
+
+		if err == nil {
+			d.failing.Store(false)
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		d.failing.Store(true)
+
+		metrics.AuditDeliveryFailedAttempts.Inc()
+
+		logger.Log.Warn("Audit batch delivery attempt failed",
+			zap.Int("attempt", attempt),
+			zap.Int("size", len(batch)),
+			zap.Error(err),
+		)
+
+		if attempt == d.cfg.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.backoff(attempt)):
+		}
+	}
+
+	if deadLetterErr := d.writeDeadLetter(batch, err); deadLetterErr != nil {
+		return fmt.Errorf("write dead letter: %w", deadLetterErr)
+	}
+
+	metrics.AuditDeadLetterEvents.Add(uint64(len(batch)))
+
+	logger.Log.Error("Audit batch moved to dead letter file",
+		zap.String("path", d.cfg.DeadLetterPath),
+		zap.Int("size", len(batch)),
+		zap.Error(err),
+	)
+
+	return nil
+}
+
+// CheckHealth reports whether the receiver accepted the last batch, events are kept in the outbox meanwhile.
+func (d *AuditShortURLOperationDelivery) CheckHealth(_ context.Context) error {
+	if d.failing.Load() {
+		return ErrAuditReceiverUnavailable
+	}
+	return nil
+}
+
+// backoff returns exponential delay limited by RetryMaxDelay with jitter in [delay/2, delay].
+func (d *AuditShortURLOperationDelivery) backoff(attempt int) time.Duration {
+	delay := d.cfg.RetryMaxDelay
+
+	if shift := attempt - 1; shift < 32 {
+		delay = min(d.cfg.RetryBaseDelay<<shift, d.cfg.RetryMaxDelay)
+	}
+
+	if delay <= 1 {
+		return delay
+	}
+
+	return delay/2 + rand.N(delay/2)
+}
+
+func (d *AuditShortURLOperationDelivery) writeDeadLetter(batch []AuditPayload, cause error) error {
+	data, err := json.Marshal(DeadLetterRecord{
+		FailedAt: time.Now().Unix(),
+		Attempts: d.cfg.MaxAttempts,
+		Error:    cause.Error(),
+		Batch:    batch,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.deadLetterMu.Lock()
+	defer d.deadLetterMu.Unlock()
+
+	file, err := os.OpenFile(d.cfg.DeadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { utils.LogErrorWrapper(file.Close()) }()
+
+	_, err = file.Write(append(data, '\n'))
+
+	return err
+}
+
+// NewAuditShortURLOperationDelivery creates AuditShortURLOperationDelivery. Call Run to start delivery.
+func NewAuditShortURLOperationDelivery(outbox Outbox, sender BatchSender, cfg DeliveryConfig) *AuditShortURLOperationDelivery {
+	return &AuditShortURLOperationDelivery{
+		outbox: outbox,
+		sender: sender,
+		cfg:    cfg,
+		notify: make(chan struct{}, 1),
+	}
+}
@@ -0,0 +1,258 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDeliveryConfig(dir string) DeliveryConfig {
+	return DeliveryConfig{
+		DeadLetterPath: filepath.Join(dir, "dead_letter.jsonl"),
+		BatchSize:      2,
+		FlushInterval:  10 * time.Millisecond,
+		RequestTimeout: time.Second,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Millisecond,
+		RetryMaxDelay:  5 * time.Millisecond,
+	}
+}
+
+func TestFileOutbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	outbox, err := NewFileOutbox(path)
+	require.NoError(t, err)
+
+	for i := range 3 {
+		require.NoError(t, outbox.Append(AuditPayload{TS: int64(i), Action: ShortURLActionCreate}))
+	}
+
+	batch, position, err := outbox.Peek(2)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, int64(0), batch[0].TS)
+
+	require.NoError(t, outbox.Ack(position))
+	require.NoError(t, outbox.Close())
+
+	// Acknowledged position survives restart
+	outbox, err = NewFileOutbox(path)
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	batch, position, err = outbox.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, int64(2), batch[0].TS)
+
+	// Spool is truncated when everything is delivered
+	require.NoError(t, outbox.Ack(position))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+func TestFileOutbox_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	outbox, err := NewFileOutbox(path)
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	outbox.compactThreshold = 64
+
+	for i := range 5 {
+		require.NoError(t, outbox.Append(AuditPayload{TS: int64(i), Action: ShortURLActionCreate}))
+	}
+
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// Reader never catches up, but the delivered prefix is dropped
+	_, position, err := outbox.Peek(3)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Ack(position))
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, before.Size()-position, after.Size())
+
+	offset, err := os.ReadFile(path + ".offset")
+	require.NoError(t, err)
+	assert.Equal(t, "0", string(offset))
+
+	// New events are appended to the compacted spool
+	require.NoError(t, outbox.Append(AuditPayload{TS: 5, Action: ShortURLActionCreate}))
+
+	batch, _, err := outbox.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, []int64{3, 4, 5}, []int64{batch[0].TS, batch[1].TS, batch[2].TS})
+}
+
+func TestAuditShortURLOperationDelivery_RetriesAndBatches(t *testing.T) {
+	var mu sync.Mutex
+	failures := 2
+	batches := make([][]AuditPayload, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		batch := make([]AuditPayload, 0)
+		require.NoError(t, json.Unmarshal(body, &batch))
+
+		batches = append(batches, batch)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	outbox, err := NewFileOutbox(filepath.Join(dir, "outbox.jsonl"))
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	delivery := NewAuditShortURLOperationDelivery(outbox, &AuditShortURLOperationHTTP{URL: server.URL}, testDeliveryConfig(dir))
+
+	for i := range 3 {
+		require.NoError(t, delivery.Audit(AuditPayload{TS: int64(i), Action: ShortURLActionGet, UserID: "user", URL: "https://example.com"}))
+	}
+
+	require.NoError(t, delivery.Flush(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], 2)
+	assert.Len(t, batches[1], 1)
+
+	_, err = os.Stat(filepath.Join(dir, "dead_letter.jsonl"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestAuditShortURLOperationDelivery_ConcurrentFlush(t *testing.T) {
+	var mu sync.Mutex
+	delivered := make(map[int64]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		batch := make([]AuditPayload, 0)
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &batch))
+
+		// Slow receiver keeps both flushes busy at the same time
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, payload := range batch {
+			delivered[payload.TS]++
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	outbox, err := NewFileOutbox(filepath.Join(dir, "outbox.jsonl"))
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	delivery := NewAuditShortURLOperationDelivery(outbox, &AuditShortURLOperationHTTP{URL: server.URL}, testDeliveryConfig(dir))
+
+	for i := range 5 {
+		require.NoError(t, delivery.Audit(AuditPayload{TS: int64(i), Action: ShortURLActionGet}))
+	}
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, delivery.Flush(context.Background()))
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, map[int64]int{0: 1, 1: 1, 2: 1, 3: 1, 4: 1}, delivered)
+}
+
+func TestAuditShortURLOperationDelivery_DeadLetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := testDeliveryConfig(dir)
+
+	outbox, err := NewFileOutbox(filepath.Join(dir, "outbox.jsonl"))
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	delivery := NewAuditShortURLOperationDelivery(outbox, &AuditShortURLOperationHTTP{URL: server.URL}, cfg)
+	require.NoError(t, delivery.Audit(AuditPayload{TS: 1, Action: ShortURLActionCreate, UserID: "user", URL: "https://example.com"}))
+
+	require.NoError(t, delivery.Flush(context.Background()))
+
+	data, err := os.ReadFile(cfg.DeadLetterPath)
+	require.NoError(t, err)
+
+	record := DeadLetterRecord{}
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, cfg.MaxAttempts, record.Attempts)
+	assert.Len(t, record.Batch, 1)
+
+	batch, _, err := outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+}
+
+func TestAuditShortURLOperationDelivery_KeepsEventsOnShutdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := testDeliveryConfig(dir)
+	cfg.MaxAttempts = 1000
+	cfg.RetryBaseDelay = time.Second
+
+	outbox, err := NewFileOutbox(filepath.Join(dir, "outbox.jsonl"))
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	delivery := NewAuditShortURLOperationDelivery(outbox, &AuditShortURLOperationHTTP{URL: server.URL}, cfg)
+	require.NoError(t, delivery.Audit(AuditPayload{TS: 1, Action: ShortURLActionCreate, UserID: "user", URL: "https://example.com"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, delivery.Flush(ctx), context.DeadlineExceeded)
+
+	batch, _, err := outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Len(t, batch, 1)
+}
@@ -0,0 +1,364 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	fileBufferSize       = 64 * 1024
+	rotatedSuffixLayout  = "20060102T150405.000"
+	compressedFileSuffix = ".gz"
+)
+
+// AuditShortURLOperationFile writes events as JSON lines to a long-lived buffered file.
+// The file is rotated when it grows over MaxSize or gets older than RotateInterval,
+// rotated segments are named "<FilePath>.<timestamp>" and optionally gzipped.
+// Buffered events are written on Flush, so Run should be started to flush them periodically.
+type AuditShortURLOperationFile struct {
+	FilePath string
+	// MaxSize contains maximum file size in bytes, 0 disables size based rotation.
+	MaxSize int64
+	// RotateInterval contains maximum file age, 0 disables time based rotation.
+	RotateInterval time.Duration
+	// MaxBackups contains number of kept rotated segments, 0 keeps all of them.
+	MaxBackups int
+	// Compress enables gzip of rotated segments.
+	Compress bool
+	// Chain makes the log tamper-evident if set. The chain continues across rotated segments.
+	Chain *HashChain
+
+	mu       sync.Mutex
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+	openedAt time.Time
+	// rotatedAt keeps rotated segment names unique when rotations happen within a millisecond.
+	rotatedAt time.Time
+
+	background sync.WaitGroup
+}
+
+func (a *AuditShortURLOperationFile) Audit(payload AuditPayload) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+
+	data, err := a.encode(payload)
+	if err != nil {
+		return err
+	}
+
+	if a.needsRotation(int64(len(data))) {
+		if err := a.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate file: %w", err)
+		}
+	}
+
+	n, err := a.writer.Write(data)
+	a.size += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+
+	return nil
+}
+
+func (a *AuditShortURLOperationFile) encode(payload AuditPayload) ([]byte, error) {
+	if a.Chain != nil {
+		return a.Chain.Seal(payload)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// Flush writes buffered events to the file.
+func (a *AuditShortURLOperationFile) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.writer == nil {
+		return nil
+	}
+
+	return a.writer.Flush()
+}
+
+// Run flushes buffered events every interval until ctx is done.
+func (a *AuditShortURLOperationFile) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Flush(); err != nil {
+				logger.Log.Error("Audit file flush failed", zap.String("path", a.FilePath), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Reopen flushes and reopens the file, e.g. on SIGHUP after it was moved by logrotate.
+func (a *AuditShortURLOperationFile) Reopen() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.close(); err != nil {
+		return err
+	}
+
+	return a.open()
+}
+
+// Close flushes buffered events, closes the file and waits for segments compression.
+func (a *AuditShortURLOperationFile) Close() error {
+	a.mu.Lock()
+	err := a.close()
+	a.mu.Unlock()
+
+	a.background.Wait()
+
+	return err
+}
+
+func (a *AuditShortURLOperationFile) open() error {
+	file, err := os.OpenFile(a.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		utils.LogErrorWrapper(file.Close())
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	a.file = file
+	a.writer = bufio.NewWriterSize(file, fileBufferSize)
+	a.size = info.Size()
+	a.openedAt = time.Now()
+
+	// Chain of the new process continues the existing log, broken link is reported by the verifier
+	if a.Chain != nil && a.size > 0 {
+		lastLine, err := readLastLine(a.FilePath, a.size)
+		if err == nil {
+			err = a.Chain.Resume(lastLine)
+		}
+		if err != nil {
+			logger.Log.Error("Audit hash chain resume failed", zap.String("path", a.FilePath), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// readLastLine returns the last line of the file, lines are expected to be shorter than fileBufferSize.
+func readLastLine(path string, size int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { utils.LogErrorWrapper(file.Close()) }()
+
+	offset := max(0, size-fileBufferSize)
+	data := make([]byte, size-offset)
+
+	if _, err := file.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	data = bytes.TrimRight(data, "\n")
+
+	return data[bytes.LastIndexByte(data, '\n')+1:], nil
+}
+
+func (a *AuditShortURLOperationFile) close() error {
+	if a.file == nil {
+		return nil
+	}
+
+	flushErr := a.writer.Flush()
+	closeErr := a.file.Close()
+
+	a.file = nil
+	a.writer = nil
+
+	if flushErr != nil {
+		return flushErr
+	}
+
+	return closeErr
+}
+
+func (a *AuditShortURLOperationFile) needsRotation(next int64) bool {
+	if a.size == 0 {
+		return false
+	}
+
+	if a.MaxSize > 0 && a.size+next > a.MaxSize {
+		return true
+	}
+
+	return a.RotateInterval > 0 && time.Since(a.openedAt) >= a.RotateInterval
+}
+
+func (a *AuditShortURLOperationFile) rotate() error {
+	if err := a.close(); err != nil {
+		return err
+	}
+
+	rotatedAt := time.Now().Truncate(time.Millisecond)
+	if !rotatedAt.After(a.rotatedAt) {
+		rotatedAt = a.rotatedAt.Add(time.Millisecond)
+	}
+	a.rotatedAt = rotatedAt
+
+	rotated := a.FilePath + "." + rotatedAt.Format(rotatedSuffixLayout)
+
+	if err := os.Rename(a.FilePath, rotated); err != nil {
+		return err
+	}
+
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	a.background.Add(1)
+
+	go func() {
+		defer a.background.Done()
+
+		if a.Compress {
+			if err := compressFile(rotated); err != nil {
+				logger.Log.Error("Audit file compression failed", zap.String("path", rotated), zap.Error(err))
+			}
+		}
+
+		a.prune()
+	}()
+
+	return nil
+}
+
+// prune removes the oldest rotated segments over MaxBackups.
+func (a *AuditShortURLOperationFile) prune() {
+	if a.MaxBackups <= 0 {
+		return
+	}
+
+	segments, err := a.rotatedSegments()
+	if err != nil {
+		logger.Log.Error("Audit file segments listing failed", zap.String("path", a.FilePath), zap.Error(err))
+		return
+	}
+
+	for len(segments) > a.MaxBackups {
+		if err := os.Remove(segments[0]); err != nil && !os.IsNotExist(err) {
+			logger.Log.Error("Audit file segment removal failed", zap.String("path", segments[0]), zap.Error(err))
+		}
+		segments = segments[1:]
+	}
+}
+
+// rotatedSegments returns rotated segments from the oldest to the newest.
+func (a *AuditShortURLOperationFile) rotatedSegments() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(a.FilePath))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(a.FilePath) + "."
+	segments := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		// Segment which is being compressed exists in both forms
+		suffix := strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressedFileSuffix)
+		if _, err := time.Parse(rotatedSuffixLayout, suffix); err != nil || seen[suffix] {
+			continue
+		}
+
+		seen[suffix] = true
+		segments = append(segments, suffix)
+	}
+
+	sort.Strings(segments)
+
+	for i, suffix := range segments {
+		path := filepath.Join(filepath.Dir(a.FilePath), prefix+suffix)
+		if a.Compress {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				path += compressedFileSuffix
+			}
+		}
+		segments[i] = path
+	}
+
+	return segments, nil
+}
+
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { utils.LogErrorWrapper(source.Close()) }()
+
+	target, err := os.OpenFile(path+compressedFileSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(target)
+
+	if _, err := io.Copy(writer, source); err != nil {
+		utils.LogErrorWrapper(target.Close())
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		utils.LogErrorWrapper(target.Close())
+		return err
+	}
+
+	if err := target.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
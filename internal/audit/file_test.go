@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPayload = AuditPayload{TS: 1, Action: ShortURLActionCreate, UserID: "user", URL: "https://example.com/some/path"}
+
+func countLines(t *testing.T, path string) int {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	if strings.HasSuffix(path, compressedFileSuffix) {
+		reader, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		scanner = bufio.NewScanner(reader)
+	}
+
+	lines := 0
+	for scanner.Scan() {
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &AuditPayload{}))
+		lines++
+	}
+	require.NoError(t, scanner.Err())
+
+	return lines
+}
+
+func TestAuditShortURLOperationFile_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	line, err := json.Marshal(testPayload)
+	require.NoError(t, err)
+
+	sink := &AuditShortURLOperationFile{
+		FilePath:   path,
+		MaxSize:    int64(len(line)+1) * 2,
+		MaxBackups: 2,
+		Compress:   true,
+	}
+
+	for range 10 {
+		require.NoError(t, sink.Audit(testPayload))
+		// Segment compression is waited for to make retention deterministic
+		sink.background.Wait()
+	}
+	require.NoError(t, sink.Close())
+
+	segments, err := sink.rotatedSegments()
+	require.NoError(t, err)
+	require.Len(t, segments, 2)
+
+	for _, segment := range segments {
+		assert.True(t, strings.HasSuffix(segment, compressedFileSuffix), segment)
+		assert.Equal(t, 2, countLines(t, segment))
+	}
+
+	assert.Equal(t, 2, countLines(t, path))
+}
+
+func TestAuditShortURLOperationFile_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	moved := filepath.Join(dir, "audit.log.1")
+
+	sink := &AuditShortURLOperationFile{FilePath: path}
+	defer sink.Close()
+
+	require.NoError(t, sink.Audit(testPayload))
+	require.NoError(t, os.Rename(path, moved))
+
+	// Buffered event is written to the moved file before reopening
+	require.NoError(t, sink.Reopen())
+	require.NoError(t, sink.Audit(testPayload))
+	require.NoError(t, sink.Flush())
+
+	assert.Equal(t, 1, countLines(t, moved))
+	assert.Equal(t, 1, countLines(t, path))
+}
+
+// legacyAuditFile is the previous implementation opening the file for every event.
+type legacyAuditFile struct {
+	FilePath string
+	mu       sync.Mutex
+}
+
+func (a *legacyAuditFile) Audit(payload AuditPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.OpenFile(a.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+func benchmarkAuditor(b *testing.B, auditor AuditorShortURLOperation) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := auditor.Audit(testPayload); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkAuditFile(b *testing.B) {
+	b.Run("legacy", func(b *testing.B) {
+		benchmarkAuditor(b, &legacyAuditFile{FilePath: filepath.Join(b.TempDir(), "audit.log")})
+	})
+
+	b.Run("buffered", func(b *testing.B) {
+		sink := &AuditShortURLOperationFile{FilePath: filepath.Join(b.TempDir(), "audit.log")}
+		defer sink.Close()
+
+		benchmarkAuditor(b, sink)
+	})
+
+	b.Run("buffered_rotating", func(b *testing.B) {
+		sink := &AuditShortURLOperationFile{
+			FilePath:   filepath.Join(b.TempDir(), "audit.log"),
+			MaxSize:    1 << 20,
+			MaxBackups: 3,
+			Compress:   true,
+		}
+		defer sink.Close()
+
+		benchmarkAuditor(b, sink)
+	})
+}
@@ -0,0 +1,270 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Outbox durably stores audit events until they are delivered.
+type Outbox interface {
+	// Append stores the event.
+	Append(payload AuditPayload) error
+	// Peek returns up to limit oldest not acknowledged events and the position to acknowledge them.
+	Peek(limit int) ([]AuditPayload, int64, error)
+	// Ack removes events before the position returned by Peek.
+	Ack(position int64) error
+}
+
+// outboxCompactThreshold is the delivered prefix size after which the spool is rewritten without it.
+const outboxCompactThreshold = 1 << 20
+
+// FileOutbox is an Outbox in a local spool file with JSON lines. Position of the first
+// not delivered event is kept in a sibling ".offset" file, the spool is truncated
+// once every event in it is delivered and compacted once the delivered prefix grows
+// past the threshold. Every change is synced to disk before returning,
+// so stored events survive a crash of the process or the host.
+type FileOutbox struct {
+	path       string
+	offsetPath string
+
+	compactThreshold int64
+
+	mu     sync.Mutex
+	file   *os.File
+	offset int64
+}
+
+func (o *FileOutbox) Append(payload AuditPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, err := o.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to outbox: %w", err)
+	}
+
+	if err := o.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox: %w", err)
+	}
+
+	return nil
+}
+
+func (o *FileOutbox) Peek(limit int) ([]AuditPayload, int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	reader, err := os.Open(o.path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { utils.LogErrorWrapper(reader.Close()) }()
+
+	if _, err := reader.Seek(o.offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]AuditPayload, 0, limit)
+	position := o.offset
+	buffered := bufio.NewReader(reader)
+
+	for len(result) < limit {
+		line, err := buffered.ReadBytes('\n')
+
+		// Неполная строка - событие еще дописывается
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		position += int64(len(line))
+
+		payload := AuditPayload{}
+		if err := json.Unmarshal(line, &payload); err != nil {
+			logger.Log.Error("Skipping broken audit outbox record", zap.Int64("position", position), zap.Error(err))
+			continue
+		}
+
+		result = append(result, payload)
+	}
+
+	return result, position, nil
+}
+
+func (o *FileOutbox) Ack(position int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	info, err := o.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if position == o.offset && (position == 0 || position < info.Size()) {
+		return nil
+	}
+
+	if position >= info.Size() {
+		if err := o.file.Truncate(0); err != nil {
+			return err
+		}
+		if err := o.file.Sync(); err != nil {
+			return err
+		}
+		position = 0
+	} else if position >= o.compactThreshold {
+		if err := o.compact(position); err != nil {
+			return fmt.Errorf("failed to compact outbox: %w", err)
+		}
+		position = 0
+	}
+
+	if err := writeFileAtomic(o.offsetPath, []byte(strconv.FormatInt(position, 10))); err != nil {
+		return fmt.Errorf("failed to save outbox offset: %w", err)
+	}
+
+	o.offset = position
+
+	return nil
+}
+
+// compact rewrites the spool with events after the position only. The offset is reset
+// before the new spool replaces the old one, so a crash in between delivers
+// the old events again instead of skipping new ones.
+func (o *FileOutbox) compact(position int64) error {
+	reader, err := os.Open(o.path)
+	if err != nil {
+		return err
+	}
+	defer func() { utils.LogErrorWrapper(reader.Close()) }()
+
+	if _, err := reader.Seek(position, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmp := o.path + ".tmp"
+
+	compacted, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(compacted, reader); err != nil {
+		utils.LogErrorWrapper(compacted.Close())
+		return err
+	}
+
+	if err := compacted.Sync(); err != nil {
+		utils.LogErrorWrapper(compacted.Close())
+		return err
+	}
+
+	if err := compacted.Close(); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(o.offsetPath, []byte("0")); err != nil {
+		return err
+	}
+	o.offset = 0
+
+	if err := os.Rename(tmp, o.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(o.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	utils.LogErrorWrapper(o.file.Close())
+	o.file = file
+
+	return nil
+}
+
+// Close closes the spool file.
+func (o *FileOutbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.file.Close()
+}
+
+// writeFileAtomic replaces the file with data synced to disk, so a crash leaves either the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		utils.LogErrorWrapper(file.Close())
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		utils.LogErrorWrapper(file.Close())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// NewFileOutbox opens or creates the spool file and restores the delivered position.
+func NewFileOutbox(path string) (*FileOutbox, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+
+	outbox := &FileOutbox{
+		path:             path,
+		offsetPath:       path + ".offset",
+		compactThreshold: outboxCompactThreshold,
+		file:             file,
+	}
+
+	data, err := os.ReadFile(outbox.offsetPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		utils.LogErrorWrapper(file.Close())
+		return nil, fmt.Errorf("failed to read outbox offset: %w", err)
+	}
+
+	if len(data) > 0 {
+		outbox.offset, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			utils.LogErrorWrapper(file.Close())
+			return nil, fmt.Errorf("failed to parse outbox offset: %w", err)
+		}
+	}
+
+	if info, err := file.Stat(); err == nil && outbox.offset > info.Size() {
+		outbox.offset = 0
+	}
+
+	return outbox, nil
+}
@@ -0,0 +1,252 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+const defaultPublishTimeout = 5 * time.Second
+
+// Publisher publishes messages to subjects of a message broker.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// AuditShortURLOperationPubSub publishes events to "<Subject>.<action>",
+// so consumers can subscribe to all events with "<Subject>.>" or to a single action.
+type AuditShortURLOperationPubSub struct {
+	Publisher Publisher
+	Subject   string
+}
+
+func (a *AuditShortURLOperationPubSub) Audit(payload AuditPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultPublishTimeout)
+	defer cancel()
+
+	if err := a.Publisher.Publish(ctx, a.Subject+"."+syslogField(payload.Action), data); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return nil
+}
+
+// CheckHealth checks the publisher if it implements HealthChecker.
+func (a *AuditShortURLOperationPubSub) CheckHealth(ctx context.Context) error {
+	if checker, ok := a.Publisher.(HealthChecker); ok {
+		return checker.CheckHealth(ctx)
+	}
+	return nil
+}
+
+// NATSPublisher is a Publisher speaking the NATS client protocol. Connection is established
+// on the first publish and reestablished after errors.
+type NATSPublisher struct {
+	Address string
+	// Name is sent to the server to identify the connection.
+	Name string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	writer *bufio.Writer
+	// broken is closed by the reader when the connection fails.
+	broken chan struct{}
+}
+
+type natsConnectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name,omitempty"`
+	Lang     string `json:"lang"`
+	Protocol int    `json:"protocol"`
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, subject string, data []byte) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("invalid subject '%s'", subject)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		select {
+		case <-p.broken:
+			p.disconnect()
+		default:
+		}
+	}
+
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := p.conn.SetWriteDeadline(deadline); err != nil {
+			p.disconnect()
+			return err
+		}
+	}
+
+	fmt.Fprintf(p.writer, "PUB %s %d\r\n", subject, len(data))
+	p.writer.Write(data)
+	p.writer.WriteString("\r\n")
+
+	if err := p.writer.Flush(); err != nil {
+		p.disconnect()
+		return err
+	}
+
+	// Deadline of the publish must not fail replies to server pings
+	utils.LogErrorWrapper(p.conn.SetWriteDeadline(time.Time{}))
+
+	return nil
+}
+
+// CheckHealth reconnects if the connection is missing or broken.
+func (p *NATSPublisher) CheckHealth(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil {
+		select {
+		case <-p.broken:
+			p.disconnect()
+		default:
+			return nil
+		}
+	}
+
+	return p.connect(ctx)
+}
+
+// Close closes the connection.
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.disconnect()
+
+	return nil
+}
+
+// NewNATSPublisher creates NATSPublisher for URL like "nats://host:4222".
+func NewNATSPublisher(rawURL string, name string) (*NATSPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "nats" || u.Host == "" {
+		return nil, fmt.Errorf("unsupported pub/sub URL '%s', expected nats://host:port", rawURL)
+	}
+
+	return &NATSPublisher{Address: u.Host, Name: name}, nil
+}
+
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	dialer := &net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "tcp", p.Address)
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		utils.LogErrorWrapper(conn.SetDeadline(deadline))
+	}
+
+	reader := bufio.NewReader(conn)
+
+	// Server starts with INFO {...}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		utils.LogErrorWrapper(conn.Close())
+		return fmt.Errorf("failed to read server info: %w", err)
+	}
+
+	if !strings.HasPrefix(line, "INFO ") {
+		utils.LogErrorWrapper(conn.Close())
+		return fmt.Errorf("unexpected server greeting '%s'", strings.TrimSpace(line))
+	}
+
+	options, err := json.Marshal(natsConnectOptions{Name: p.Name, Lang: "go", Protocol: 1})
+	if err != nil {
+		utils.LogErrorWrapper(conn.Close())
+		return err
+	}
+
+	writer := bufio.NewWriter(conn)
+	fmt.Fprintf(writer, "CONNECT %s\r\n", options)
+
+	if err := writer.Flush(); err != nil {
+		utils.LogErrorWrapper(conn.Close())
+		return err
+	}
+
+	utils.LogErrorWrapper(conn.SetDeadline(time.Time{}))
+
+	p.conn = conn
+	p.writer = writer
+	p.broken = make(chan struct{})
+
+	go p.read(conn, reader, p.broken)
+
+	return nil
+}
+
+// read answers server pings and reports protocol errors until the connection is closed.
+func (p *NATSPublisher) read(conn net.Conn, reader *bufio.Reader, broken chan struct{}) {
+	defer close(broken)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Log.Warn("NATS connection closed", zap.String("address", p.Address), zap.Error(err))
+			}
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			p.mu.Lock()
+			if p.conn == conn {
+				p.writer.WriteString("PONG\r\n")
+				utils.LogErrorWrapper(p.writer.Flush())
+			}
+			p.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			logger.Log.Error("NATS server error", zap.String("address", p.Address), zap.String("error", strings.TrimSpace(line)))
+		}
+	}
+}
+
+func (p *NATSPublisher) disconnect() {
+	if p.conn == nil {
+		return
+	}
+
+	utils.LogErrorWrapper(p.conn.Close())
+
+	p.conn = nil
+	p.writer = nil
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type publishedMessage struct {
+	subject string
+	data    []byte
+}
+
+// serveNATS accepts connections speaking a subset of the NATS server protocol and reports published messages.
+func serveNATS(listener net.Listener) <-chan publishedMessage {
+	messages := make(chan publishedMessage, 10)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+				// Pings are answered by the publisher in background
+				fmt.Fprint(conn, "PING\r\n")
+
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					fields := strings.Fields(line)
+					if len(fields) != 3 || fields[0] != "PUB" {
+						continue
+					}
+
+					size, err := strconv.Atoi(fields[2])
+					if err != nil {
+						return
+					}
+
+					data := make([]byte, size+2)
+					if _, err := io.ReadFull(reader, data); err != nil {
+						return
+					}
+
+					messages <- publishedMessage{subject: fields[1], data: data[:size]}
+				}
+			}()
+		}
+	}()
+
+	return messages
+}
+
+func TestAuditShortURLOperationPubSub_NATS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	messages := serveNATS(listener)
+
+	publisher, err := NewNATSPublisher("nats://"+listener.Addr().String(), "test")
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	sink := &AuditShortURLOperationPubSub{Publisher: publisher, Subject: "audit.shortener"}
+
+	require.NoError(t, sink.Audit(testPayload))
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, "audit.shortener."+testPayload.Action, message.subject)
+
+		payload := AuditPayload{}
+		require.NoError(t, json.Unmarshal(message.data, &payload))
+		assert.Equal(t, testPayload, payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not published")
+	}
+}
+
+func TestNewNATSPublisher_InvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"http://localhost:4222", "nats://"} {
+		_, err := NewNATSPublisher(rawURL, "test")
+		assert.Error(t, err, rawURL)
+	}
+}
@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader contains identifier of the request.
+const RequestIDHeader = "X-Request-ID"
+
+// NewRequestPayload creates AuditPayload filled with metadata of the request.
+func NewRequestPayload(c *gin.Context, action ShortURLAction, userID string) AuditPayload {
+	requestID := c.GetHeader(RequestIDHeader)
+	if requestID == "" {
+		requestID = c.Writer.Header().Get(RequestIDHeader)
+	}
+
+	return AuditPayload{
+		Action:    action,
+		UserID:    userID,
+		RequestID: requestID,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
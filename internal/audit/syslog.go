@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+)
+
+const (
+	syslogVersion = 1
+	// syslogPriority is facility local0 (16) with severity informational (6).
+	syslogPriority = 16*8 + 6
+	syslogNilValue = "-"
+
+	defaultSyslogTimeout = 5 * time.Second
+)
+
+// Syslog networks.
+const (
+	SyslogNetworkUDP = "udp"
+	SyslogNetworkTCP = "tcp"
+	SyslogNetworkTLS = "tls"
+)
+
+// AuditShortURLOperationSyslog sends events as RFC 5424 messages with the JSON payload as MSG
+// and the action as MSGID. Stream transports (tcp, tls) use octet counting framing (RFC 6587, RFC 5425),
+// udp sends one message per datagram.
+type AuditShortURLOperationSyslog struct {
+	// Network is one of SyslogNetworkUDP, SyslogNetworkTCP or SyslogNetworkTLS.
+	Network string
+	Address string
+	AppName string
+	// TLSConfig is used for SyslogNetworkTLS, nil uses system roots.
+	TLSConfig *tls.Config
+	// Timeout limits connection and write, defaultSyslogTimeout if 0.
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	conn     net.Conn
+	hostname string
+}
+
+// NewAuditShortURLOperationSyslog creates AuditShortURLOperationSyslog for URL like "udp://host:514",
+// "tcp://host:601" or "tls://host:6514". caFile with PEM certificates replaces system roots for tls.
+func NewAuditShortURLOperationSyslog(rawURL string, caFile string, appName string) (*AuditShortURLOperationSyslog, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("syslog URL '%s' has no host", rawURL)
+	}
+
+	sink := &AuditShortURLOperationSyslog{Network: u.Scheme, Address: u.Host, AppName: appName}
+
+	switch u.Scheme {
+	case SyslogNetworkUDP, SyslogNetworkTCP:
+	case SyslogNetworkTLS:
+		sink.TLSConfig = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read syslog CA file: %w", err)
+			}
+
+			sink.TLSConfig.RootCAs = x509.NewCertPool()
+			if !sink.TLSConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in syslog CA file '%s'", caFile)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported syslog URL scheme '%s', expected udp, tcp or tls", u.Scheme)
+	}
+
+	return sink, nil
+}
+
+func (a *AuditShortURLOperationSyslog) Audit(payload AuditPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	message := a.format(payload, data)
+
+	// Stream connection may be closed by the server, it is reconnected once
+	for attempt := 0; ; attempt++ {
+		if err = a.write(message); err == nil || attempt > 0 {
+			break
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to send syslog message: %w", err)
+	}
+
+	return nil
+}
+
+// CheckHealth connects to the server if there is no connection.
+func (a *AuditShortURLOperationSyslog) CheckHealth(_ context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.conn != nil {
+		return nil
+	}
+
+	return a.connect()
+}
+
+// Close closes the connection.
+func (a *AuditShortURLOperationSyslog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.disconnect()
+}
+
+func (a *AuditShortURLOperationSyslog) format(payload AuditPayload, data []byte) []byte {
+	if a.hostname == "" {
+		a.hostname = syslogNilValue
+		if hostname, err := os.Hostname(); err == nil && hostname != "" {
+			a.hostname = hostname
+		}
+	}
+
+	appName := a.AppName
+	if appName == "" {
+		appName = syslogNilValue
+	}
+
+	timestamp := time.Unix(payload.TS, 0).UTC().Format(time.RFC3339)
+
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	header := fmt.Sprintf("<%d>%d %s %s %s %d %s %s ",
+		syslogPriority, syslogVersion, timestamp, a.hostname, appName, os.Getpid(), syslogField(payload.Action), syslogNilValue)
+
+	message := append([]byte(header), data...)
+
+	if a.Network == SyslogNetworkUDP {
+		return message
+	}
+
+	return append([]byte(strconv.Itoa(len(message))+" "), message...)
+}
+
+func (a *AuditShortURLOperationSyslog) write(message []byte) error {
+	if a.conn == nil {
+		if err := a.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := a.conn.SetWriteDeadline(time.Now().Add(a.timeout())); err != nil {
+		utils.LogErrorWrapper(a.disconnect())
+		return err
+	}
+
+	if _, err := a.conn.Write(message); err != nil {
+		utils.LogErrorWrapper(a.disconnect())
+		return err
+	}
+
+	return nil
+}
+
+func (a *AuditShortURLOperationSyslog) connect() error {
+	dialer := &net.Dialer{Timeout: a.timeout()}
+
+	var err error
+
+	switch a.Network {
+	case SyslogNetworkUDP, SyslogNetworkTCP:
+		a.conn, err = dialer.Dial(a.Network, a.Address)
+	case SyslogNetworkTLS:
+		a.conn, err = tls.DialWithDialer(dialer, "tcp", a.Address, a.TLSConfig)
+	default:
+		err = fmt.Errorf("unsupported syslog network '%s'", a.Network)
+	}
+
+	return err
+}
+
+func (a *AuditShortURLOperationSyslog) disconnect() error {
+	if a.conn == nil {
+		return nil
+	}
+
+	err := a.conn.Close()
+	a.conn = nil
+
+	return err
+}
+
+func (a *AuditShortURLOperationSyslog) timeout() time.Duration {
+	if a.Timeout > 0 {
+		return a.Timeout
+	}
+	return defaultSyslogTimeout
+}
+
+// syslogField returns value allowed in header fields: printable US-ASCII without spaces, up to 32 chars.
+func syslogField(value string) string {
+	result := make([]byte, 0, min(len(value), 32))
+
+	for i := 0; i < len(value) && len(result) < 32; i++ {
+		if value[i] > ' ' && value[i] < 127 {
+			result = append(result, value[i])
+		}
+	}
+
+	if len(result) == 0 {
+		return syslogNilValue
+	}
+
+	return string(result)
+}
@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var syslogMessagePattern = regexp.MustCompile(`^<134>1 \S+ \S+ shortener \d+ shorten - (\{.*\})$`)
+
+func assertSyslogMessage(t *testing.T, message string) {
+	matches := syslogMessagePattern.FindStringSubmatch(message)
+	require.Len(t, matches, 2, message)
+
+	payload := AuditPayload{}
+	require.NoError(t, json.Unmarshal([]byte(matches[1]), &payload))
+	assert.Equal(t, testPayload, payload)
+}
+
+// readOctetCounted reads a message framed as "LEN SP MSG".
+func readOctetCounted(reader *bufio.Reader) (string, error) {
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+
+	size, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+
+	message := make([]byte, size)
+	if _, err := io.ReadFull(reader, message); err != nil {
+		return "", err
+	}
+
+	return string(message), nil
+}
+
+func acceptMessages(listener net.Listener, count int) <-chan string {
+	messages := make(chan string, count)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for range count {
+			message, err := readOctetCounted(reader)
+			if err != nil {
+				return
+			}
+			messages <- message
+		}
+	}()
+
+	return messages
+}
+
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestAuditShortURLOperationSyslog_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewAuditShortURLOperationSyslog("udp://"+conn.LocalAddr().String(), "", "shortener")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Audit(testPayload))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	buffer := make([]byte, 64*1024)
+	n, _, err := conn.ReadFrom(buffer)
+	require.NoError(t, err)
+
+	assertSyslogMessage(t, string(buffer[:n]))
+}
+
+func TestAuditShortURLOperationSyslog_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	messages := acceptMessages(listener, 2)
+
+	sink, err := NewAuditShortURLOperationSyslog("tcp://"+listener.Addr().String(), "", "shortener")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Audit(testPayload))
+	require.NoError(t, sink.Audit(testPayload))
+
+	for range 2 {
+		select {
+		case message := <-messages:
+			assertSyslogMessage(t, message)
+		case <-time.After(5 * time.Second):
+			t.Fatal("syslog message was not received")
+		}
+	}
+}
+
+func TestAuditShortURLOperationSyslog_TLS(t *testing.T) {
+	certificate, pool := selfSignedCertificate(t)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	messages := acceptMessages(listener, 1)
+
+	sink := &AuditShortURLOperationSyslog{
+		Network:   SyslogNetworkTLS,
+		Address:   listener.Addr().String(),
+		AppName:   "shortener",
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "localhost"},
+	}
+	defer sink.Close()
+
+	require.NoError(t, sink.Audit(testPayload))
+
+	select {
+	case message := <-messages:
+		assertSyslogMessage(t, message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("syslog message was not received")
+	}
+}
+
+func TestNewAuditShortURLOperationSyslog_InvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"http://localhost:514", "udp://", "ftp://localhost:21"} {
+		_, err := NewAuditShortURLOperationSyslog(rawURL, "", "shortener")
+		assert.Error(t, err, rawURL)
+	}
+}
@@ -0,0 +1,199 @@
+// Code generated by reset tool. DO NOT EDIT.
+//go:generate go run github.com/Alexey-zaliznuak/shortener/cmd/reset
+
+package config
+
+import "flag"
+
+func registerAppConfigFlags(flags *flag.FlagSet) map[string]*string {
+	values := make(map[string]*string)
+
+	values["DATABASE_CONN_STRING"] = flags.String("d", "", "database DSN (DATABASE_CONN_STRING)")
+	values["FILE_STORAGE_PATH"] = flags.String("f", "", "storage path to save dump and load all data (FILE_STORAGE_PATH, default storage.json)")
+	values["LINKS_DEDUPLICATION_SCOPE"] = flags.String("dedup-scope", "", "links deduplication scope: global, user or none (LINKS_DEDUPLICATION_SCOPE, default global)")
+	values["AUDIT_URL"] = flags.String("audit-url", "", "audit HTTP endpoint URL (AUDIT_URL)")
+	values["AUDIT_FILE"] = flags.String("audit-file", "", "audit log file path (AUDIT_FILE)")
+	values["AUDIT_SYSLOG_URL"] = flags.String("audit-syslog", "", "audit syslog server: udp://host:514, tcp://host:601 or tls://host:6514 (AUDIT_SYSLOG_URL)")
+	values["AUDIT_PUBSUB_URL"] = flags.String("audit-pubsub", "", "audit pub/sub broker: nats://host:4222 (AUDIT_PUBSUB_URL)")
+	values["AUDIT_OUTBOX_PATH"] = flags.String("audit-outbox", "", "file to spool audit events until they are delivered to audit URL (AUDIT_OUTBOX_PATH, default audit_outbox.jsonl)")
+	values["BASE_URL"] = flags.String("b", "", "short links url prefix (BASE_URL)")
+	values["SERVER_ADDRESS"] = flags.String("a", "", "startup address (SERVER_ADDRESS, default localhost:8080)")
+	values["TLS_CERT_FILE"] = flags.String("tls-cert", "", "TLS certificate PEM file, enables HTTPS (TLS_CERT_FILE)")
+	values["TLS_KEY_FILE"] = flags.String("tls-key", "", "TLS private key PEM file (TLS_KEY_FILE)")
+	values["URL_DOMAIN_ALLOW_LIST_FILE"] = flags.String("domain-allow-list", "", "file with allowed destination domains (URL_DOMAIN_ALLOW_LIST_FILE)")
+	values["URL_DOMAIN_DENY_LIST_FILE"] = flags.String("domain-deny-list", "", "file with denied destination domains (URL_DOMAIN_DENY_LIST_FILE)")
+	values["URL_BLOCKLIST_FILES"] = flags.String("blocklist", "", "comma separated files with malicious URL hash prefixes (URL_BLOCKLIST_FILES)")
+	values["TRACING_EXPORTER"] = flags.String("tracing", "", "spans exporter: none, stdout or otlp (TRACING_EXPORTER, default none)")
+
+	return values
+}
+
+func (b *AppConfigBuilder) loadAppConfigFields() {
+	b.config.LoggingLevel = b.loadStringVariableFromEnv("LOGGING_LEVEL", "info")
+	b.config.ReloadInterval = b.loadDurationVariableFromEnv("CONFIG_RELOAD_INTERVAL", "30s")
+	b.config.Environment = b.loadStringVariableFromEnv("APP_ENV", "development")
+	b.config.DB.DatabaseDSN = b.loadSecretVariableFromEnv("DATABASE_CONN_STRING", "")
+	b.config.DB.StoragePath = b.loadStringVariableFromEnv("FILE_STORAGE_PATH", "storage.json")
+	b.config.DB.DeduplicationScope = b.loadStringVariableFromEnv("LINKS_DEDUPLICATION_SCOPE", "global")
+	b.config.Auth.TokenLifeTimeHours = b.loadIntVariableFromEnv("AUTH_TOKEN_LIFE_TIME_HOURS", "24")
+	b.config.Auth.TokenSecretKey = b.loadSecretVariableFromEnv("AUTH_TOKEN_SECRET_KEY", "superTokenSecretKey")
+	b.config.Audit.AuditURL = b.loadStringVariableFromEnv("AUDIT_URL", "")
+	b.config.Audit.AuditFile = b.loadStringVariableFromEnv("AUDIT_FILE", "")
+	b.config.Audit.SyslogURL = b.loadStringVariableFromEnv("AUDIT_SYSLOG_URL", "")
+	b.config.Audit.SyslogCAFile = b.loadStringVariableFromEnv("AUDIT_SYSLOG_CA_FILE", "")
+	b.config.Audit.SyslogAppName = b.loadStringVariableFromEnv("AUDIT_SYSLOG_APP_NAME", "shortener")
+	b.config.Audit.PubSubURL = b.loadStringVariableFromEnv("AUDIT_PUBSUB_URL", "")
+	b.config.Audit.PubSubSubject = b.loadStringVariableFromEnv("AUDIT_PUBSUB_SUBJECT", "audit.shortener")
+	b.config.Audit.FileMaxSizeMB = b.loadIntVariableFromEnv("AUDIT_FILE_MAX_SIZE_MB", "100")
+	b.config.Audit.FileRotateInterval = b.loadDurationVariableFromEnv("AUDIT_FILE_ROTATE_INTERVAL", "24h")
+	b.config.Audit.FileMaxBackups = b.loadIntVariableFromEnv("AUDIT_FILE_MAX_BACKUPS", "7")
+	b.config.Audit.FileCompress = b.loadBoolVariableFromEnv("AUDIT_FILE_COMPRESS", "true")
+	b.config.Audit.FileFlushInterval = b.loadDurationVariableFromEnv("AUDIT_FILE_FLUSH_INTERVAL", "1s")
+	b.config.Audit.HMACKey = b.loadSecretVariableFromEnv("AUDIT_HMAC_KEY", "")
+	b.config.Audit.CheckpointInterval = b.loadIntVariableFromEnv("AUDIT_CHECKPOINT_INTERVAL", "1000")
+	b.config.Audit.OutboxPath = b.loadStringVariableFromEnv("AUDIT_OUTBOX_PATH", "audit_outbox.jsonl")
+	b.config.Audit.DeadLetterPath = b.loadStringVariableFromEnv("AUDIT_DEAD_LETTER_PATH", "audit_dead_letter.jsonl")
+	b.config.Audit.BatchSize = b.loadIntVariableFromEnv("AUDIT_BATCH_SIZE", "100")
+	b.config.Audit.FlushInterval = b.loadDurationVariableFromEnv("AUDIT_FLUSH_INTERVAL", "1s")
+	b.config.Audit.RequestTimeout = b.loadDurationVariableFromEnv("AUDIT_REQUEST_TIMEOUT", "5s")
+	b.config.Audit.MaxAttempts = b.loadIntVariableFromEnv("AUDIT_MAX_ATTEMPTS", "10")
+	b.config.Audit.RetryBaseDelay = b.loadDurationVariableFromEnv("AUDIT_RETRY_BASE_DELAY", "500ms")
+	b.config.Audit.RetryMaxDelay = b.loadDurationVariableFromEnv("AUDIT_RETRY_MAX_DELAY", "1m")
+	b.config.Server.BaseURL = b.loadStringVariableFromEnv("BASE_URL", "")
+	b.config.Server.Address = b.loadStringVariableFromEnv("SERVER_ADDRESS", "localhost:8080")
+	b.config.Server.ShortLinksLength = b.loadIntVariableFromEnv("SHORT_LINKS_LENGTH", "8")
+	b.config.Server.TrustedProxies = b.loadStringVariableFromEnv("TRUSTED_PROXIES", "")
+	b.config.Server.CompressionMinSize = b.loadIntVariableFromEnv("COMPRESSION_MIN_SIZE", "1024")
+	b.config.Server.ReadHeaderTimeout = b.loadDurationVariableFromEnv("SERVER_READ_HEADER_TIMEOUT", "5s")
+	b.config.Server.ReadTimeout = b.loadDurationVariableFromEnv("SERVER_READ_TIMEOUT", "30s")
+	b.config.Server.WriteTimeout = b.loadDurationVariableFromEnv("SERVER_WRITE_TIMEOUT", "30s")
+	b.config.Server.IdleTimeout = b.loadDurationVariableFromEnv("SERVER_IDLE_TIMEOUT", "2m")
+	b.config.Server.ShutdownDrainDelay = b.loadDurationVariableFromEnv("SERVER_SHUTDOWN_DRAIN_DELAY", "5s")
+	b.config.TLS.CertFile = b.loadStringVariableFromEnv("TLS_CERT_FILE", "")
+	b.config.TLS.KeyFile = b.loadStringVariableFromEnv("TLS_KEY_FILE", "")
+	b.config.TLS.MinVersion = b.loadStringVariableFromEnv("TLS_MIN_VERSION", "1.2")
+	b.config.TLS.CipherSuites = b.loadStringVariableFromEnv("TLS_CIPHER_SUITES", "")
+	b.config.TLS.ReloadInterval = b.loadDurationVariableFromEnv("TLS_RELOAD_INTERVAL", "1m")
+	b.config.TLS.RedirectAddress = b.loadStringVariableFromEnv("HTTP_REDIRECT_ADDRESS", "")
+	b.config.Links.NormalizeSortQuery = b.loadBoolVariableFromEnv("URL_NORMALIZE_SORT_QUERY", "false")
+	b.config.Links.NormalizeStripTrackingParams = b.loadBoolVariableFromEnv("URL_NORMALIZE_STRIP_TRACKING_PARAMS", "true")
+	b.config.Links.NormalizeStripTrailingSlash = b.loadBoolVariableFromEnv("URL_NORMALIZE_STRIP_TRAILING_SLASH", "true")
+	b.config.Policy.AllowedSchemes = b.loadStringVariableFromEnv("URL_ALLOWED_SCHEMES", "http,https")
+	b.config.Policy.DomainAllowListFile = b.loadStringVariableFromEnv("URL_DOMAIN_ALLOW_LIST_FILE", "")
+	b.config.Policy.DomainDenyListFile = b.loadStringVariableFromEnv("URL_DOMAIN_DENY_LIST_FILE", "")
+	b.config.Policy.BlockPrivateAddresses = b.loadBoolVariableFromEnv("URL_BLOCK_PRIVATE_ADDRESSES", "true")
+	b.config.Policy.MaxURLLength = b.loadIntVariableFromEnv("URL_MAX_LENGTH", "2048")
+	b.config.URLCheck.BlocklistFiles = b.loadStringVariableFromEnv("URL_BLOCKLIST_FILES", "")
+	b.config.URLCheck.ReloadInterval = b.loadDurationVariableFromEnv("URL_BLOCKLIST_RELOAD_INTERVAL", "30s")
+	b.config.URLCheck.CheckOnRedirect = b.loadBoolVariableFromEnv("URL_CHECK_ON_REDIRECT", "false")
+	b.config.Webhooks.Workers = b.loadIntVariableFromEnv("WEBHOOK_WORKERS", "4")
+	b.config.Webhooks.QueueSize = b.loadIntVariableFromEnv("WEBHOOK_QUEUE_SIZE", "1000")
+	b.config.Webhooks.RequestTimeout = b.loadDurationVariableFromEnv("WEBHOOK_REQUEST_TIMEOUT", "5s")
+	b.config.Webhooks.MaxAttempts = b.loadIntVariableFromEnv("WEBHOOK_MAX_ATTEMPTS", "5")
+	b.config.Webhooks.RetryBaseDelay = b.loadDurationVariableFromEnv("WEBHOOK_RETRY_BASE_DELAY", "1s")
+	b.config.Webhooks.MaxInFlight = b.loadIntVariableFromEnv("WEBHOOK_MAX_IN_FLIGHT", "2")
+	b.config.Webhooks.AllowPrivateAddresses = b.loadBoolVariableFromEnv("WEBHOOK_ALLOW_PRIVATE_ADDRESSES", "false")
+	b.config.RequestLimits.MaxBodySize = b.loadIntVariableFromEnv("REQUEST_MAX_BODY_SIZE", "65536")
+	b.config.RequestLimits.MaxBatchBodySize = b.loadIntVariableFromEnv("REQUEST_MAX_BATCH_BODY_SIZE", "4194304")
+	b.config.RequestLimits.MaxDecompressedSize = b.loadIntVariableFromEnv("REQUEST_MAX_DECOMPRESSED_SIZE", "8388608")
+	b.config.RequestLimits.MaxDecompressionRatio = b.loadIntVariableFromEnv("REQUEST_MAX_DECOMPRESSION_RATIO", "100")
+	b.config.RateLimit.Store = b.loadStringVariableFromEnv("RATE_LIMIT_STORE", "memory")
+	b.config.RateLimit.CreatePerMinute = b.loadIntVariableFromEnv("RATE_LIMIT_CREATE_PER_MINUTE", "0")
+	b.config.RateLimit.CreateBurst = b.loadIntVariableFromEnv("RATE_LIMIT_CREATE_BURST", "20")
+	b.config.RateLimit.BatchPerMinute = b.loadIntVariableFromEnv("RATE_LIMIT_BATCH_PER_MINUTE", "0")
+	b.config.RateLimit.BatchBurst = b.loadIntVariableFromEnv("RATE_LIMIT_BATCH_BURST", "5")
+	b.config.RateLimit.RedirectPerMinute = b.loadIntVariableFromEnv("RATE_LIMIT_REDIRECT_PER_MINUTE", "0")
+	b.config.RateLimit.RedirectBurst = b.loadIntVariableFromEnv("RATE_LIMIT_REDIRECT_BURST", "200")
+	b.config.RateLimit.MaxBatchSize = b.loadIntVariableFromEnv("BATCH_MAX_SIZE", "1000")
+	b.config.RequestLogging.SampleRatio = b.loadFloatVariableFromEnv("REQUEST_LOG_SAMPLE_RATIO", "1")
+	b.config.RequestLogging.MaxBodySize = b.loadIntVariableFromEnv("REQUEST_LOG_MAX_BODY_SIZE", "0")
+	b.config.Tracing.Exporter = b.loadStringVariableFromEnv("TRACING_EXPORTER", "none")
+	b.config.Tracing.OTLPEndpoint = b.loadStringVariableFromEnv("TRACING_OTLP_ENDPOINT", "")
+	b.config.Tracing.ServiceName = b.loadStringVariableFromEnv("TRACING_SERVICE_NAME", "shortener")
+	b.config.Tracing.SampleRatio = b.loadFloatVariableFromEnv("TRACING_SAMPLE_RATIO", "1")
+}
+
+const appConfigUsage = `  VARIABLE                                        DEFAULT                  DESCRIPTION
+  LOGGING_LEVEL                                   info                     logging level: debug, info, warn or error
+  CONFIG_RELOAD_INTERVAL                          30s                      config file change check interval, 0 disables it
+  APP_ENV                                         development              environment: development or production
+  DATABASE_CONN_STRING, -d                                                 database DSN
+  FILE_STORAGE_PATH, -f                           storage.json             storage path to save dump and load all data
+  LINKS_DEDUPLICATION_SCOPE, -dedup-scope         global                   links deduplication scope: global, user or none
+  AUTH_TOKEN_LIFE_TIME_HOURS                      24                       auth token lifetime in hours
+  AUTH_TOKEN_SECRET_KEY                           superTokenSecretKey      auth token signing key
+  AUDIT_URL, -audit-url                                                    audit HTTP endpoint URL
+  AUDIT_FILE, -audit-file                                                  audit log file path
+  AUDIT_SYSLOG_URL, -audit-syslog                                          audit syslog server: udp://host:514, tcp://host:601 or tls://host:6514
+  AUDIT_SYSLOG_CA_FILE                                                     PEM file with CA certificates of the tls syslog server
+  AUDIT_SYSLOG_APP_NAME                           shortener                APP-NAME of audit syslog messages
+  AUDIT_PUBSUB_URL, -audit-pubsub                                          audit pub/sub broker: nats://host:4222
+  AUDIT_PUBSUB_SUBJECT                            audit.shortener          audit subject prefix, events are published to <prefix>.<action>
+  AUDIT_FILE_MAX_SIZE_MB                          100                      audit file size in megabytes to rotate at, 0 disables it
+  AUDIT_FILE_ROTATE_INTERVAL                      24h                      audit file rotation interval, 0 disables it
+  AUDIT_FILE_MAX_BACKUPS                          7                        rotated audit files to keep, 0 keeps all
+  AUDIT_FILE_COMPRESS                             true                     gzip rotated audit files
+  AUDIT_FILE_FLUSH_INTERVAL                       1s                       audit file flush interval
+  AUDIT_HMAC_KEY                                                           audit records signing key, empty disables the hash chain
+  AUDIT_CHECKPOINT_INTERVAL                       1000                     audit records between checkpoints, 0 disables them
+  AUDIT_OUTBOX_PATH, -audit-outbox                audit_outbox.jsonl       file to spool audit events until they are delivered to audit URL
+  AUDIT_DEAD_LETTER_PATH                          audit_dead_letter.jsonl  file for audit events that could not be delivered
+  AUDIT_BATCH_SIZE                                100                      audit events per request
+  AUDIT_FLUSH_INTERVAL                            1s                       audit outbox check interval
+  AUDIT_REQUEST_TIMEOUT                           5s                       audit request timeout
+  AUDIT_MAX_ATTEMPTS                              10                       audit delivery attempts before the dead letter file
+  AUDIT_RETRY_BASE_DELAY                          500ms                    initial delay between audit delivery attempts
+  AUDIT_RETRY_MAX_DELAY                           1m                       maximum delay between audit delivery attempts
+  BASE_URL, -b                                                             short links url prefix
+  SERVER_ADDRESS, -a                              localhost:8080           startup address
+  SHORT_LINKS_LENGTH                              8                        short links length, from 4 to 32
+  TRUSTED_PROXIES                                                          comma separated proxy addresses and subnets trusted with X-Forwarded-For
+  COMPRESSION_MIN_SIZE                            1024                     minimum response body size in bytes to compress
+  SERVER_READ_HEADER_TIMEOUT                      5s                       request headers read timeout
+  SERVER_READ_TIMEOUT                             30s                      request read timeout
+  SERVER_WRITE_TIMEOUT                            30s                      request handling and response write timeout
+  SERVER_IDLE_TIMEOUT                             2m                       keep-alive connection idle timeout
+  SERVER_SHUTDOWN_DRAIN_DELAY                     5s                       delay between failing readiness and stopping servers on shutdown
+  TLS_CERT_FILE, -tls-cert                                                 TLS certificate PEM file, enables HTTPS
+  TLS_KEY_FILE, -tls-key                                                   TLS private key PEM file
+  TLS_MIN_VERSION                                 1.2                      minimum TLS version: 1.2 or 1.3
+  TLS_CIPHER_SUITES                                                        comma separated TLS 1.2 cipher suites, empty uses Go defaults
+  TLS_RELOAD_INTERVAL                             1m                       certificate files change check interval, 0 disables it
+  HTTP_REDIRECT_ADDRESS                                                    address of the HTTP server redirecting to HTTPS on the BASE_URL host, empty disables it
+  URL_NORMALIZE_SORT_QUERY                        false                    sort query parameters by name
+  URL_NORMALIZE_STRIP_TRACKING_PARAMS             true                     strip tracking parameters like utm_*
+  URL_NORMALIZE_STRIP_TRAILING_SLASH              true                     strip trailing slash in path
+  URL_ALLOWED_SCHEMES                             http,https               comma separated allowed URL schemes
+  URL_DOMAIN_ALLOW_LIST_FILE, -domain-allow-list                           file with allowed destination domains
+  URL_DOMAIN_DENY_LIST_FILE, -domain-deny-list                             file with denied destination domains
+  URL_BLOCK_PRIVATE_ADDRESSES                     true                     deny links to private and loopback addresses
+  URL_MAX_LENGTH                                  2048                     maximum URL length
+  URL_BLOCKLIST_FILES, -blocklist                                          comma separated files with malicious URL hash prefixes
+  URL_BLOCKLIST_RELOAD_INTERVAL                   30s                      blocklist files change check interval
+  URL_CHECK_ON_REDIRECT                           false                    check links against blocklists on every redirect
+  WEBHOOK_WORKERS                                 4                        concurrent webhook deliveries
+  WEBHOOK_QUEUE_SIZE                              1000                     webhook events queue size, new events are dropped when it is full
+  WEBHOOK_REQUEST_TIMEOUT                         5s                       webhook request timeout
+  WEBHOOK_MAX_ATTEMPTS                            5                        webhook delivery attempts
+  WEBHOOK_RETRY_BASE_DELAY                        1s                       initial delay between webhook attempts, doubled after each attempt
+  WEBHOOK_MAX_IN_FLIGHT                           2                        concurrent deliveries to one webhook
+  WEBHOOK_ALLOW_PRIVATE_ADDRESSES                 false                    deliver webhooks to private and loopback addresses, for local development only
+  REQUEST_MAX_BODY_SIZE                           65536                    maximum decompressed request body size in bytes
+  REQUEST_MAX_BATCH_BODY_SIZE                     4194304                  maximum batch request body size in bytes
+  REQUEST_MAX_DECOMPRESSED_SIZE                   8388608                  maximum compressed and decompressed request body size in bytes
+  REQUEST_MAX_DECOMPRESSION_RATIO                 100                      maximum ratio of decompressed to compressed request body size
+  RATE_LIMIT_STORE                                memory                   rate limit counters store: memory or postgres
+  RATE_LIMIT_CREATE_PER_MINUTE                    0                        link creations per minute, 0 disables the limit
+  RATE_LIMIT_CREATE_BURST                         20                       link creations in a row
+  RATE_LIMIT_BATCH_PER_MINUTE                     0                        batch requests per minute, 0 disables the limit
+  RATE_LIMIT_BATCH_BURST                          5                        batch requests in a row
+  RATE_LIMIT_REDIRECT_PER_MINUTE                  0                        redirects per minute, 0 disables the limit
+  RATE_LIMIT_REDIRECT_BURST                       200                      redirects in a row
+  BATCH_MAX_SIZE                                  1000                     maximum links in a batch request, 0 disables the limit
+  REQUEST_LOG_SAMPLE_RATIO                        1                        share of logged requests from 0 to 1, 5xx errors are always logged
+  REQUEST_LOG_MAX_BODY_SIZE                       0                        logged bytes of text request and response bodies, 0 disables it
+  TRACING_EXPORTER, -tracing                      none                     spans exporter: none, stdout or otlp
+  TRACING_OTLP_ENDPOINT                                                    OTLP/HTTP collector address like http://localhost:4318
+  TRACING_SERVICE_NAME                            shortener                service name in exported spans
+  TRACING_SAMPLE_RATIO                            1                        share of recorded traces from 0 to 1
+`
@@ -1,63 +1,65 @@
 // Package config предоставляет функциональность для управления конфигурацией приложения.
-// Конфигурация загружается из флагов командной строки и переменных окружения,
-// с приоритетом переменных окружения над флагами.
+// Конфигурация загружается из флагов командной строки, переменных окружения и файла JSON или YAML.
+// Значение берется из первого источника, в котором оно указано: флаги, переменные окружения,
+// файл конфигурации, значения по умолчанию.
 //
 //go:generate go run ../../cmd/reset
 package config
 
 import (
-	"errors"
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
-// DBFlagsInitialConfig содержит начальную конфигурацию базы данных из флагов.
-type DBFlagsInitialConfig struct {
-	// DatabaseDSN содержит строку подключения к базе данных.
-	DatabaseDSN *string
-}
-
-// FlagsInitialConfig содержит начальную конфигурацию приложения из флагов командной строки.
+// FlagsInitialConfig содержит значения флагов командной строки. Флаги полей AppConfig регистрируются
+// по тегам flag сгенерированной функцией registerAppConfigFlags.
 type FlagsInitialConfig struct {
-	// StoragePath содержит путь к файлу хранилища данных.
-	StoragePath *string
-	// StartupAddress содержит адрес запуска сервера.
-	StartupAddress *string
-	// BaseURL содержит базовый URL для коротких ссылок.
-	BaseURL *string
-
-	// DB содержит конфигурацию базы данных.
-	DB *DBFlagsInitialConfig
-
-	// AuditURL содержит URL HTTP-эндпоинта для аудита.
-	AuditURL *string
-	// AuditFile содержит путь к файлу логов аудита.
-	AuditFile *string
+	// Values содержит значения флагов по именам соответствующих переменных окружения.
+	Values map[string]*string
 }
 
 // DBConfig содержит конфигурацию базы данных и хранилища.
+// generate:validate
 type DBConfig struct {
 	// DatabaseDSN содержит строку подключения к базе данных.
-	DatabaseDSN string
+	DatabaseDSN Secret `env:"DATABASE_CONN_STRING" flag:"d" usage:"database DSN"`
 	// StoragePath содержит путь к файлу хранилища данных.
-	StoragePath string
+	StoragePath string `env:"FILE_STORAGE_PATH" flag:"f" default:"storage.json" usage:"storage path to save dump and load all data"`
+	// DeduplicationScope содержит область дедупликации ссылок: global, user или none.
+	DeduplicationScope string `env:"LINKS_DEDUPLICATION_SCOPE" flag:"dedup-scope" default:"global" usage:"links deduplication scope: global, user or none" validate:"required,oneof=global user none"`
 }
 
 // AuthConfig содержит конфигурацию аутентификации.
 type AuthConfig struct {
 	// TokenLifeTimeHours содержит время жизни токена в часах.
-	TokenLifeTimeHours int
-	// TokenSecretKey содержит секретный ключ для подписи токенов.
-	TokenSecretKey string
+	TokenLifeTimeHours int `env:"AUTH_TOKEN_LIFE_TIME_HOURS" default:"24" usage:"auth token lifetime in hours"`
+	// TokenSecretKey содержит секретный ключ для подписи токенов, значение по умолчанию запрещено в production.
+	TokenSecretKey Secret `env:"AUTH_TOKEN_SECRET_KEY" default:"superTokenSecretKey" usage:"auth token signing key"`
 }
 
 // AppConfig содержит полную конфигурацию приложения.
+// Поля загружаются по тегам env, flag и default сгенерированным методом loadAppConfigFields,
+// тег usage содержит описание для справки.
+// Поля с тегом reload применяются без перезапуска сервиса, см. Watcher.
+// Поля проверяются по тегам validate сгенерированным методом Validate.
 // generate:reset
+// generate:validate
+// generate:config
 type AppConfig struct {
-	// LoggingLevel содержит уровень логирования.
-	LoggingLevel string
+	// LoggingLevel содержит уровень логирования: debug, info, warn или error.
+	LoggingLevel string `env:"LOGGING_LEVEL" default:"info" usage:"logging level: debug, info, warn or error" reload:"true" validate:"required,oneof=debug info warn error"`
+	// ReloadInterval содержит интервал проверки изменения файла конфигурации, 0 отключает проверку.
+	// Конфигурация также перечитывается по сигналу SIGHUP.
+	ReloadInterval time.Duration `env:"CONFIG_RELOAD_INTERVAL" default:"30s" usage:"config file change check interval, 0 disables it" validate:"min=0"`
+	// Environment содержит окружение запуска: development или production, в production запрещены слабые секреты.
+	// Рабочее развертывание задает APP_ENV=production явно.
+	Environment string `env:"APP_ENV" default:"development" usage:"environment: development or production" validate:"required,oneof=development production"`
 
 	// DB содержит конфигурацию базы данных.
 	DB DBConfig
@@ -67,213 +69,507 @@ type AppConfig struct {
 	// Audit содержит конфигурацию аудита.
 	Audit struct {
 		// AuditURL содержит URL для отправки событий аудита.
-		AuditURL string
+		AuditURL string `env:"AUDIT_URL" flag:"audit-url" usage:"audit HTTP endpoint URL"`
 		// AuditFile содержит путь к файлу логов аудита.
-		AuditFile string
+		AuditFile string `env:"AUDIT_FILE" flag:"audit-file" usage:"audit log file path"`
+		// SyslogURL содержит адрес syslog-сервера вида udp://host:514, tcp://host:601 или tls://host:6514.
+		SyslogURL string `env:"AUDIT_SYSLOG_URL" flag:"audit-syslog" usage:"audit syslog server: udp://host:514, tcp://host:601 or tls://host:6514"`
+		// SyslogCAFile содержит путь к PEM-файлу корневых сертификатов для tls.
+		SyslogCAFile string `env:"AUDIT_SYSLOG_CA_FILE" usage:"PEM file with CA certificates of the tls syslog server"`
+		// SyslogAppName содержит APP-NAME сообщений syslog.
+		SyslogAppName string `env:"AUDIT_SYSLOG_APP_NAME" default:"shortener" usage:"APP-NAME of audit syslog messages"`
+		// PubSubURL содержит адрес брокера сообщений вида nats://host:4222.
+		PubSubURL string `env:"AUDIT_PUBSUB_URL" flag:"audit-pubsub" usage:"audit pub/sub broker: nats://host:4222"`
+		// PubSubSubject содержит префикс темы, событие публикуется в "<префикс>.<действие>".
+		PubSubSubject string `env:"AUDIT_PUBSUB_SUBJECT" default:"audit.shortener" usage:"audit subject prefix, events are published to <prefix>.<action>"`
+		// FileMaxSizeMB содержит размер файла аудита в мегабайтах, после которого он ротируется, 0 отключает ротацию по размеру.
+		FileMaxSizeMB int `env:"AUDIT_FILE_MAX_SIZE_MB" default:"100" usage:"audit file size in megabytes to rotate at, 0 disables it"`
+		// FileRotateInterval содержит интервал ротации файла аудита, 0 отключает ротацию по времени.
+		FileRotateInterval time.Duration `env:"AUDIT_FILE_ROTATE_INTERVAL" default:"24h" usage:"audit file rotation interval, 0 disables it" validate:"min=0"`
+		// FileMaxBackups содержит количество хранимых ротированных файлов аудита, 0 хранит все.
+		FileMaxBackups int `env:"AUDIT_FILE_MAX_BACKUPS" default:"7" usage:"rotated audit files to keep, 0 keeps all"`
+		// FileCompress включает сжатие ротированных файлов аудита gzip.
+		FileCompress bool `env:"AUDIT_FILE_COMPRESS" default:"true" usage:"gzip rotated audit files"`
+		// FileFlushInterval содержит интервал сброса буфера файла аудита на диск.
+		FileFlushInterval time.Duration `env:"AUDIT_FILE_FLUSH_INTERVAL" default:"1s" usage:"audit file flush interval" validate:"min=1ms"`
+		// HMACKey содержит ключ подписи записей файла аудита, пустой ключ отключает цепочку хешей.
+		HMACKey Secret `env:"AUDIT_HMAC_KEY" usage:"audit records signing key, empty disables the hash chain"`
+		// CheckpointInterval содержит количество записей файла аудита между контрольными точками, 0 отключает их.
+		CheckpointInterval int `env:"AUDIT_CHECKPOINT_INTERVAL" default:"1000" usage:"audit records between checkpoints, 0 disables them"`
+		// OutboxPath содержит путь к файлу очереди событий, ожидающих отправки на AuditURL.
+		OutboxPath string `env:"AUDIT_OUTBOX_PATH" flag:"audit-outbox" default:"audit_outbox.jsonl" usage:"file to spool audit events until they are delivered to audit URL"`
+		// DeadLetterPath содержит путь к файлу событий, которые не удалось доставить.
+		DeadLetterPath string `env:"AUDIT_DEAD_LETTER_PATH" default:"audit_dead_letter.jsonl" usage:"file for audit events that could not be delivered"`
+		// BatchSize содержит максимальное количество событий в одном запросе.
+		BatchSize int `env:"AUDIT_BATCH_SIZE" default:"100" usage:"audit events per request" validate:"min=1"`
+		// FlushInterval содержит интервал проверки очереди событий.
+		FlushInterval time.Duration `env:"AUDIT_FLUSH_INTERVAL" default:"1s" usage:"audit outbox check interval" validate:"min=1ms"`
+		// RequestTimeout содержит таймаут одного запроса к AuditURL.
+		RequestTimeout time.Duration `env:"AUDIT_REQUEST_TIMEOUT" default:"5s" usage:"audit request timeout" validate:"min=1ms"`
+		// MaxAttempts содержит количество попыток доставки пачки событий перед переносом в DeadLetterPath.
+		MaxAttempts int `env:"AUDIT_MAX_ATTEMPTS" default:"10" usage:"audit delivery attempts before the dead letter file" validate:"min=1"`
+		// RetryBaseDelay содержит начальную задержку между попытками доставки.
+		RetryBaseDelay time.Duration `env:"AUDIT_RETRY_BASE_DELAY" default:"500ms" usage:"initial delay between audit delivery attempts" validate:"min=1ms"`
+		// RetryMaxDelay содержит максимальную задержку между попытками доставки.
+		RetryMaxDelay time.Duration `env:"AUDIT_RETRY_MAX_DELAY" default:"1m" usage:"maximum delay between audit delivery attempts" validate:"min=1ms"`
 	}
 
 	// Server содержит конфигурацию сервера.
 	Server struct {
 		// BaseURL содержит базовый URL для генерации коротких ссылок.
-		BaseURL string
+		BaseURL string `env:"BASE_URL" flag:"b" usage:"short links url prefix" validate:"required,url"`
 		// Address содержит адрес, на котором запускается сервер.
-		Address string
-		// ShortLinksLength содержит длину генерируемых коротких ссылок.
-		ShortLinksLength int
+		Address string `env:"SERVER_ADDRESS" flag:"a" default:"localhost:8080" usage:"startup address"`
+		// ShortLinksLength содержит длину генерируемых коротких ссылок, от 4 до 32 символов.
+		ShortLinksLength int `env:"SHORT_LINKS_LENGTH" default:"8" usage:"short links length, from 4 to 32" reload:"true" validate:"min=4,max=32"`
+		// TrustedProxies содержит адреса и подсети прокси через запятую, которым доверяется заголовок X-Forwarded-For.
+		// Пустое значение не доверяет никому, и адресом клиента считается адрес соединения.
+		TrustedProxies string `env:"TRUSTED_PROXIES" usage:"comma separated proxy addresses and subnets trusted with X-Forwarded-For"`
+		// CompressionMinSize содержит минимальный размер тела ответа в байтах, начиная с которого ответ сжимается.
+		CompressionMinSize int `env:"COMPRESSION_MIN_SIZE" default:"1024" usage:"minimum response body size in bytes to compress" validate:"min=0"`
+		// ReadHeaderTimeout содержит время на чтение заголовков запроса.
+		ReadHeaderTimeout time.Duration `env:"SERVER_READ_HEADER_TIMEOUT" default:"5s" usage:"request headers read timeout" validate:"min=1ms"`
+		// ReadTimeout содержит время на чтение всего запроса вместе с телом.
+		ReadTimeout time.Duration `env:"SERVER_READ_TIMEOUT" default:"30s" usage:"request read timeout" validate:"min=1ms"`
+		// WriteTimeout содержит время на обработку запроса и запись ответа.
+		WriteTimeout time.Duration `env:"SERVER_WRITE_TIMEOUT" default:"30s" usage:"request handling and response write timeout" validate:"min=1ms"`
+		// IdleTimeout содержит время ожидания следующего запроса в keep-alive соединении.
+		IdleTimeout time.Duration `env:"SERVER_IDLE_TIMEOUT" default:"2m" usage:"keep-alive connection idle timeout" validate:"min=1ms"`
+		// ShutdownDrainDelay содержит время между отказом readiness и остановкой серверов,
+		// за него балансировщики успевают перестать отправлять новые запросы.
+		ShutdownDrainDelay time.Duration `env:"SERVER_SHUTDOWN_DRAIN_DELAY" default:"5s" usage:"delay between failing readiness and stopping servers on shutdown" validate:"min=0"`
+	}
+
+	// TLS содержит настройки HTTPS, сервер принимает HTTPS и HTTP/2, если указаны сертификат и ключ.
+	TLS struct {
+		// CertFile содержит путь к PEM-файлу сертификата, цепочка промежуточных сертификатов следует за ним.
+		CertFile string `env:"TLS_CERT_FILE" flag:"tls-cert" usage:"TLS certificate PEM file, enables HTTPS"`
+		// KeyFile содержит путь к PEM-файлу закрытого ключа.
+		KeyFile string `env:"TLS_KEY_FILE" flag:"tls-key" usage:"TLS private key PEM file"`
+		// MinVersion содержит минимальную версию TLS: 1.2 или 1.3.
+		MinVersion string `env:"TLS_MIN_VERSION" default:"1.2" usage:"minimum TLS version: 1.2 or 1.3" validate:"required,oneof=1.2 1.3"`
+		// CipherSuites содержит имена разрешенных наборов шифров TLS 1.2 через запятую, пустое значение использует наборы Go.
+		CipherSuites string `env:"TLS_CIPHER_SUITES" usage:"comma separated TLS 1.2 cipher suites, empty uses Go defaults"`
+		// ReloadInterval содержит интервал проверки изменения файлов сертификата, 0 отключает проверку.
+		// Сертификат также перечитывается по сигналу SIGHUP.
+		ReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" default:"1m" usage:"certificate files change check interval, 0 disables it" validate:"min=0"`
+		// RedirectAddress содержит адрес HTTP-сервера, перенаправляющего запросы на HTTPS по адресу BASE_URL, пустое значение отключает его.
+		RedirectAddress string `env:"HTTP_REDIRECT_ADDRESS" usage:"address of the HTTP server redirecting to HTTPS on the BASE_URL host, empty disables it"`
+	}
+
+	// Links содержит настройки нормализации сокращаемых URL.
+	Links struct {
+		// NormalizeSortQuery включает сортировку параметров запроса по имени.
+		NormalizeSortQuery bool `env:"URL_NORMALIZE_SORT_QUERY" default:"false" usage:"sort query parameters by name"`
+		// NormalizeStripTrackingParams включает удаление параметров отслеживания (utm_* и т.п.).
+		NormalizeStripTrackingParams bool `env:"URL_NORMALIZE_STRIP_TRACKING_PARAMS" default:"true" usage:"strip tracking parameters like utm_*"`
+		// NormalizeStripTrailingSlash включает удаление завершающего слеша в пути.
+		NormalizeStripTrailingSlash bool `env:"URL_NORMALIZE_STRIP_TRAILING_SLASH" default:"true" usage:"strip trailing slash in path"`
+	}
+
+	// Policy содержит правила проверки сокращаемых URL.
+	Policy struct {
+		// AllowedSchemes содержит разрешенные схемы URL через запятую.
+		AllowedSchemes string `env:"URL_ALLOWED_SCHEMES" default:"http,https" usage:"comma separated allowed URL schemes"`
+		// DomainAllowListFile содержит путь к файлу разрешенных доменов, пустой список разрешает все домены.
+		DomainAllowListFile string `env:"URL_DOMAIN_ALLOW_LIST_FILE" flag:"domain-allow-list" usage:"file with allowed destination domains"`
+		// DomainDenyListFile содержит путь к файлу запрещенных доменов.
+		DomainDenyListFile string `env:"URL_DOMAIN_DENY_LIST_FILE" flag:"domain-deny-list" usage:"file with denied destination domains"`
+		// BlockPrivateAddresses запрещает ссылки на приватные и loopback адреса.
+		BlockPrivateAddresses bool `env:"URL_BLOCK_PRIVATE_ADDRESSES" default:"true" usage:"deny links to private and loopback addresses"`
+		// MaxURLLength содержит максимальную длину сокращаемого URL.
+		MaxURLLength int `env:"URL_MAX_LENGTH" default:"2048" usage:"maximum URL length"`
+	}
+
+	// URLCheck содержит настройки проверки URL по блоклистам вредоносных ссылок.
+	URLCheck struct {
+		// BlocklistFiles содержит пути к файлам с префиксами хешей через запятую.
+		BlocklistFiles string `env:"URL_BLOCKLIST_FILES" flag:"blocklist" usage:"comma separated files with malicious URL hash prefixes" reload:"true"`
+		// ReloadInterval содержит интервал проверки изменений файлов блоклистов.
+		ReloadInterval time.Duration `env:"URL_BLOCKLIST_RELOAD_INTERVAL" default:"30s" usage:"blocklist files change check interval" validate:"min=1ms"`
+		// CheckOnRedirect включает проверку ссылки при каждом переходе.
+		CheckOnRedirect bool `env:"URL_CHECK_ON_REDIRECT" default:"false" usage:"check links against blocklists on every redirect" reload:"true"`
+	}
+
+	// Webhooks содержит настройки доставки событий на вебхуки пользователей.
+	Webhooks struct {
+		// Workers содержит количество одновременных доставок.
+		Workers int `env:"WEBHOOK_WORKERS" default:"4" usage:"concurrent webhook deliveries" validate:"min=1"`
+		// QueueSize содержит размер очереди событий, при переполнении новые события отбрасываются.
+		QueueSize int `env:"WEBHOOK_QUEUE_SIZE" default:"1000" usage:"webhook events queue size, new events are dropped when it is full" validate:"min=1"`
+		// RequestTimeout содержит таймаут одного запроса к вебхуку.
+		RequestTimeout time.Duration `env:"WEBHOOK_REQUEST_TIMEOUT" default:"5s" usage:"webhook request timeout" validate:"min=1ms"`
+		// MaxAttempts содержит количество попыток доставки события.
+		MaxAttempts int `env:"WEBHOOK_MAX_ATTEMPTS" default:"5" usage:"webhook delivery attempts" validate:"min=1"`
+		// RetryBaseDelay содержит начальную задержку между попытками, она удваивается после каждой попытки.
+		RetryBaseDelay time.Duration `env:"WEBHOOK_RETRY_BASE_DELAY" default:"1s" usage:"initial delay between webhook attempts, doubled after each attempt" validate:"min=1ms"`
+		// MaxInFlight содержит количество одновременных доставок одному вебхуку, остальные откладываются.
+		MaxInFlight int `env:"WEBHOOK_MAX_IN_FLIGHT" default:"2" usage:"concurrent deliveries to one webhook" validate:"min=1"`
+		// AllowPrivateAddresses разрешает доставку на loopback, частные и link-local адреса,
+		// не зависит от URL_BLOCK_PRIVATE_ADDRESSES и нужна только для локальной разработки.
+		AllowPrivateAddresses bool `env:"WEBHOOK_ALLOW_PRIVATE_ADDRESSES" default:"false" usage:"deliver webhooks to private and loopback addresses, for local development only"`
+	}
+
+	// RequestLimits содержит ограничения размера тел запросов, 0 отключает ограничение.
+	RequestLimits struct {
+		// MaxBodySize содержит максимальный размер тела запроса в байтах после распаковки.
+		MaxBodySize int `env:"REQUEST_MAX_BODY_SIZE" default:"65536" usage:"maximum decompressed request body size in bytes" validate:"min=0"`
+		// MaxBatchBodySize содержит максимальный размер тела пакетных запросов создания и удаления ссылок.
+		MaxBatchBodySize int `env:"REQUEST_MAX_BATCH_BODY_SIZE" default:"4194304" usage:"maximum batch request body size in bytes" validate:"min=0"`
+		// MaxDecompressedSize содержит максимальный размер сжатого тела запроса и его распакованного содержимого.
+		MaxDecompressedSize int `env:"REQUEST_MAX_DECOMPRESSED_SIZE" default:"8388608" usage:"maximum compressed and decompressed request body size in bytes" validate:"min=0"`
+		// MaxDecompressionRatio содержит максимальное отношение размера распакованного тела запроса к сжатому.
+		MaxDecompressionRatio int `env:"REQUEST_MAX_DECOMPRESSION_RATIO" default:"100" usage:"maximum ratio of decompressed to compressed request body size" validate:"min=0"`
+	}
+
+	// RateLimit содержит ограничения частоты запросов с одного IP-адреса и от одного пользователя.
+	// Ограничения работают по алгоритму token bucket, 0 запросов в минуту отключает ограничение.
+	// По умолчанию ограничения выключены и включаются заданием количества запросов в минуту.
+	RateLimit struct {
+		// Store содержит хранилище счетчиков: memory или postgres, postgres разделяет ограничения между экземплярами сервиса.
+		Store string `env:"RATE_LIMIT_STORE" default:"memory" usage:"rate limit counters store: memory or postgres" validate:"required,oneof=memory postgres"`
+		// CreatePerMinute содержит количество запросов создания ссылки в минуту.
+		CreatePerMinute int `env:"RATE_LIMIT_CREATE_PER_MINUTE" default:"0" usage:"link creations per minute, 0 disables the limit" reload:"true" validate:"min=0"`
+		// CreateBurst содержит количество запросов создания ссылки, которые можно отправить подряд.
+		CreateBurst int `env:"RATE_LIMIT_CREATE_BURST" default:"20" usage:"link creations in a row" reload:"true"`
+		// BatchPerMinute содержит количество пакетных запросов создания ссылок в минуту.
+		BatchPerMinute int `env:"RATE_LIMIT_BATCH_PER_MINUTE" default:"0" usage:"batch requests per minute, 0 disables the limit" reload:"true" validate:"min=0"`
+		// BatchBurst содержит количество пакетных запросов, которые можно отправить подряд.
+		BatchBurst int `env:"RATE_LIMIT_BATCH_BURST" default:"5" usage:"batch requests in a row" reload:"true"`
+		// RedirectPerMinute содержит количество переходов по коротким ссылкам в минуту.
+		RedirectPerMinute int `env:"RATE_LIMIT_REDIRECT_PER_MINUTE" default:"0" usage:"redirects per minute, 0 disables the limit" reload:"true" validate:"min=0"`
+		// RedirectBurst содержит количество переходов, которые можно выполнить подряд.
+		RedirectBurst int `env:"RATE_LIMIT_REDIRECT_BURST" default:"200" usage:"redirects in a row" reload:"true"`
+		// MaxBatchSize содержит максимальное количество ссылок в одном пакетном запросе, 0 отключает ограничение.
+		MaxBatchSize int `env:"BATCH_MAX_SIZE" default:"1000" usage:"maximum links in a batch request, 0 disables the limit" reload:"true" validate:"min=0"`
+	}
+
+	// RequestLogging содержит настройки логирования запросов.
+	RequestLogging struct {
+		// SampleRatio содержит долю логируемых запросов от 0 до 1, запросы с ошибками 5xx логируются всегда.
+		SampleRatio float64 `env:"REQUEST_LOG_SAMPLE_RATIO" default:"1" usage:"share of logged requests from 0 to 1, 5xx errors are always logged" validate:"min=0,max=1"`
+		// MaxBodySize содержит количество логируемых байт текстовых тел запроса и ответа, 0 отключает их логирование.
+		MaxBodySize int `env:"REQUEST_LOG_MAX_BODY_SIZE" default:"0" usage:"logged bytes of text request and response bodies, 0 disables it" validate:"min=0"`
+	}
+
+	// Tracing содержит настройки трассировки запросов.
+	Tracing struct {
+		// Exporter содержит способ экспорта спанов: none, stdout или otlp.
+		Exporter string `env:"TRACING_EXPORTER" flag:"tracing" default:"none" usage:"spans exporter: none, stdout or otlp" validate:"required,oneof=none stdout otlp"`
+		// OTLPEndpoint содержит адрес OTLP/HTTP коллектора, например http://localhost:4318.
+		OTLPEndpoint string `env:"TRACING_OTLP_ENDPOINT" usage:"OTLP/HTTP collector address like http://localhost:4318"`
+		// ServiceName содержит имя сервиса в экспортируемых спанах.
+		ServiceName string `env:"TRACING_SERVICE_NAME" default:"shortener" usage:"service name in exported spans"`
+		// SampleRatio содержит долю записываемых трасс от 0 до 1, дочерние спаны следуют решению родителя.
+		SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" default:"1" usage:"share of recorded traces from 0 to 1" validate:"min=0,max=1"`
 	}
 }
 
 // AppConfigBuilder предоставляет паттерн Builder для построения конфигурации приложения.
 type AppConfigBuilder struct {
-	config      *AppConfig
-	flagsConfig *FlagsInitialConfig
+	config *AppConfig
+	// flagValues содержит непустые значения флагов по именам переменных окружения.
+	flagValues map[string]string
+	// fileValues содержит значения из файла конфигурации по именам переменных окружения.
+	fileValues map[string]string
 	// Errors содержит список ошибок, возникших при построении конфигурации.
 	Errors []error
 }
 
-var (
-	defaultStoragePath        = "storage.json"
-	defaultShortLinksLength   = 8
-	defaultStartupAddress     = "localhost:8080"
-	defaultLoggingLevel       = "info"
-	defaultTokenLifeTimeHours = 24
-	defaultTokenSecretKey     = "superTokenSecretKey"
+// Допустимые значения области дедупликации ссылок.
+const (
+	// DeduplicationScopeGlobal - одинаковый URL сокращается один раз для всех пользователей.
+	DeduplicationScopeGlobal = "global"
+	// DeduplicationScopeUser - одинаковый URL сокращается один раз для каждого пользователя.
+	DeduplicationScopeUser = "user"
+	// DeduplicationScopeNone - каждый запрос создает новую короткую ссылку.
+	DeduplicationScopeNone = "none"
+)
+
+// Допустимые способы экспорта спанов.
+const (
+	// TracingExporterNone - спаны не записываются, контекст трассировки только передается дальше.
+	TracingExporterNone = "none"
+	// TracingExporterStdout - спаны выводятся в stdout, подходит для локальной отладки.
+	TracingExporterStdout = "stdout"
+	// TracingExporterOTLP - спаны отправляются в OTLP/HTTP коллектор.
+	TracingExporterOTLP = "otlp"
+)
+
+// Допустимые минимальные версии TLS.
+const (
+	TLSVersion12 = "1.2"
+	TLSVersion13 = "1.3"
+)
+
+// Допустимые хранилища счетчиков ограничения частоты запросов.
+const (
+	// RateLimitStoreMemory - счетчики хранятся в памяти каждого экземпляра сервиса.
+	RateLimitStoreMemory = "memory"
+	// RateLimitStorePostgres - счетчики хранятся в базе данных и общие для всех экземпляров.
+	RateLimitStorePostgres = "postgres"
 )
 
 // NewAppConfigBuilder создает новый экземпляр AppConfigBuilder с указанной начальной конфигурацией флагов.
 func NewAppConfigBuilder(flagsConfig *FlagsInitialConfig) *AppConfigBuilder {
 	return &AppConfigBuilder{
-		config: &AppConfig{}, flagsConfig: flagsConfig,
+		config: &AppConfig{}, flagValues: flagsConfig.values(),
 	}
 }
 
-// WithStartupAddress устанавливает адрес запуска сервера из переменной окружения SERVER_ADDRESS
-// или флага командной строки. Если ни один не указан, используется значение по умолчанию.
-func (b *AppConfigBuilder) WithStartupAddress() *AppConfigBuilder {
-	def := defaultStartupAddress
+// values возвращает непустые значения флагов по именам соответствующих переменных окружения.
+func (f *FlagsInitialConfig) values() map[string]string {
+	values := make(map[string]string)
 
-	if b.flagsConfig.StartupAddress != nil && *b.flagsConfig.StartupAddress != "" {
-		def = *b.flagsConfig.StartupAddress
+	for envName, value := range f.Values {
+		if value != nil && *value != "" {
+			values[envName] = *value
+		}
 	}
 
-	b.config.Server.Address = b.loadStringVariableFromEnv("SERVER_ADDRESS", &def)
-
-	return b
+	return values
 }
 
-// WithDatabaseDSN устанавливает строку подключения к базе данных из переменной окружения
-// DATABASE_CONN_STRING или флага командной строки.
-func (b *AppConfigBuilder) WithDatabaseDSN() *AppConfigBuilder {
-	def := ""
+// WithConfigFile загружает файл конфигурации JSON или YAML, путь к которому указан во флаге -c (-config)
+// или переменной окружения CONFIG. Должен вызываться первым, значения файла используются при загрузке полей.
+func (b *AppConfigBuilder) WithConfigFile() *AppConfigBuilder {
+	if path := b.configFilePath(); path != "" {
+		values, err := loadConfigFile(path)
+		if err != nil {
+			b.Errors = append(b.Errors, fmt.Errorf("configuration error: config file '%s': %w", path, err))
+		}
 
-	if b.flagsConfig.DB != nil && b.flagsConfig.DB.DatabaseDSN != nil && *b.flagsConfig.DB.DatabaseDSN != "" {
-		def = *b.flagsConfig.DB.DatabaseDSN
+		b.fileValues = values
 	}
 
-	b.config.DB.DatabaseDSN = b.loadStringVariableFromEnv("DATABASE_CONN_STRING", &def)
 	return b
 }
 
-// WithStoragePath устанавливает путь к файлу хранилища из переменной окружения
-// FILE_STORAGE_PATH или флага командной строки. Если ни один не указан, используется значение по умолчанию.
-func (b *AppConfigBuilder) WithStoragePath() *AppConfigBuilder {
-	def := defaultStoragePath
-
-	if b.flagsConfig.StoragePath != nil && *b.flagsConfig.StoragePath != "" {
-		def = *b.flagsConfig.StoragePath
-	}
+// configFilePath возвращает путь к файлу конфигурации, пустой, если файл не используется.
+func (b *AppConfigBuilder) configFilePath() string {
+	return b.loadStringVariableFromEnv("CONFIG", "")
+}
 
-	b.config.DB.StoragePath = b.loadStringVariableFromEnv("FILE_STORAGE_PATH", &def)
+// WithFields загружает все поля AppConfig по тегам env, flag и default сгенерированным методом loadAppConfigFields.
+// Должен вызываться после WithConfigFile, остальные методы проверяют загруженные значения.
+func (b *AppConfigBuilder) WithFields() *AppConfigBuilder {
+	b.loadAppConfigFields()
 
 	return b
 }
 
-// WithTokenLifeTime устанавливает время жизни токена из переменной окружения
-// AUTH_TOKEN_LIFE_TIME_HOURS. Если не указано, используется значение по умолчанию.
-func (b *AppConfigBuilder) WithTokenLifeTime() *AppConfigBuilder {
-	def := defaultTokenLifeTimeHours
+// WithTrustedProxies проверяет адреса доверенных прокси из TRUSTED_PROXIES.
+// Пустое значение допустимо, тогда заголовки прокси игнорируются.
+func (b *AppConfigBuilder) WithTrustedProxies() *AppConfigBuilder {
+	if b.config.Server.TrustedProxies == "" {
+		return b
+	}
 
-	b.config.Auth.TokenLifeTimeHours = b.loadIntVariableFromEnv("AUTH_TOKEN_LIFE_TIME_HOURS", &def)
+	for _, proxy := range strings.Split(b.config.Server.TrustedProxies, ",") {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			b.Errors = append(b.Errors, fmt.Errorf("configuration error: TRUSTED_PROXIES contains invalid address '%s'", proxy))
+		}
+	}
 
 	return b
 }
 
-// WithTokenSecretKey устанавливает секретный ключ токена из переменной окружения
-// AUTH_TOKEN_SECRET_KEY. Если не указано, используется значение по умолчанию.
-func (b *AppConfigBuilder) WithTokenSecretKey() *AppConfigBuilder {
-	b.config.Auth.TokenSecretKey = b.loadStringVariableFromEnv("AUTH_TOKEN_SECRET_KEY", &defaultTokenSecretKey)
+// WithTLS проверяет согласованность настроек HTTPS: сертификат и ключ указываются вместе, перенаправление
+// на HTTPS требует сертификата, наборы шифров TLS_CIPHER_SUITES известны Go и совместимы с HTTP/2.
+func (b *AppConfigBuilder) WithTLS() *AppConfigBuilder {
+	if (b.config.TLS.CertFile == "") != (b.config.TLS.KeyFile == "") {
+		b.Errors = append(b.Errors, fmt.Errorf("configuration error: TLS_CERT_FILE and TLS_KEY_FILE must be specified together"))
+	}
 
-	return b
-}
+	if b.config.TLS.RedirectAddress != "" && b.config.TLS.CertFile == "" {
+		b.Errors = append(b.Errors, fmt.Errorf("configuration error: HTTP_REDIRECT_ADDRESS requires TLS_CERT_FILE"))
+	}
 
-// WithBaseURL устанавливает базовый URL из переменной окружения BASE_URL или флага командной строки.
-func (b *AppConfigBuilder) WithBaseURL() *AppConfigBuilder {
-	b.config.Server.BaseURL = b.loadStringVariableFromEnv("BASE_URL", b.flagsConfig.BaseURL)
-	return b
-}
+	if b.config.TLS.CipherSuites != "" {
+		supported := make(map[string]bool)
+		for _, suite := range tls.CipherSuites() {
+			supported[suite.Name] = true
+		}
+
+		for _, name := range strings.Split(b.config.TLS.CipherSuites, ",") {
+			if !supported[strings.TrimSpace(name)] {
+				b.Errors = append(b.Errors, fmt.Errorf("configuration error: TLS_CIPHER_SUITES contains unknown or insecure suite '%s'", name))
+			}
+		}
+
+		// HTTP/2 requires this suite for TLS 1.2 (RFC 7540, section 9.2.2)
+		if !strings.Contains(b.config.TLS.CipherSuites, "_WITH_AES_128_GCM_SHA256") {
+			b.Errors = append(b.Errors, fmt.Errorf("configuration error: TLS_CIPHER_SUITES must contain an ECDHE AES_128_GCM_SHA256 suite required by HTTP/2"))
+		}
+	}
 
-// WithShortLinksLength устанавливает длину коротких ссылок из переменной окружения
-// SHORT_LINKS_LENGTH. Если не указано, используется значение по умолчанию.
-func (b *AppConfigBuilder) WithShortLinksLength() *AppConfigBuilder {
-	b.config.Server.ShortLinksLength = b.loadIntVariableFromEnv("SHORT_LINKS_LENGTH", &defaultShortLinksLength)
 	return b
 }
 
-// WithLoggingLevel устанавливает уровень логирования из переменной окружения
-// LOGGING_LEVEL. Если не указано, используется значение по умолчанию.
-func (b *AppConfigBuilder) WithLoggingLevel() *AppConfigBuilder {
-	b.config.LoggingLevel = b.loadStringVariableFromEnv("LOGGING_LEVEL", &defaultLoggingLevel)
-	return b
-}
+// WithRateLimit проверяет ограничения частоты запросов: хранилище postgres требует DATABASE_CONN_STRING,
+// а включенное ограничение - положительного количества запросов подряд.
+func (b *AppConfigBuilder) WithRateLimit() *AppConfigBuilder {
+	if b.config.RateLimit.Store == RateLimitStorePostgres && b.config.DB.DatabaseDSN == "" {
+		b.Errors = append(b.Errors, fmt.Errorf("configuration error: RATE_LIMIT_STORE postgres requires DATABASE_CONN_STRING"))
+	}
 
-// WithAuditFile устанавливает путь к файлу аудита из флага командной строки.
-func (b *AppConfigBuilder) WithAuditFile() *AppConfigBuilder {
-	if b.flagsConfig.AuditFile != nil {
-		b.config.Audit.AuditFile = *b.flagsConfig.AuditFile
+	budgets := []struct {
+		name      string
+		perMinute int
+		burst     int
+	}{
+		{"CREATE", b.config.RateLimit.CreatePerMinute, b.config.RateLimit.CreateBurst},
+		{"BATCH", b.config.RateLimit.BatchPerMinute, b.config.RateLimit.BatchBurst},
+		{"REDIRECT", b.config.RateLimit.RedirectPerMinute, b.config.RateLimit.RedirectBurst},
+	}
+
+	for _, budget := range budgets {
+		if budget.perMinute > 0 && budget.burst < 1 {
+			b.Errors = append(b.Errors, fmt.Errorf("configuration error: RATE_LIMIT_%s_BURST must be positive", budget.name))
+		}
 	}
 
 	return b
 }
 
-// WithAuditURL устанавливает URL аудита из флага командной строки.
-func (b *AppConfigBuilder) WithAuditURL() *AppConfigBuilder {
-	if b.flagsConfig.AuditURL != nil {
-		b.config.Audit.AuditURL = *b.flagsConfig.AuditURL
+// WithTracing проверяет, что для экспорта спанов в OTLP указан адрес коллектора TRACING_OTLP_ENDPOINT.
+func (b *AppConfigBuilder) WithTracing() *AppConfigBuilder {
+	if b.config.Tracing.Exporter == TracingExporterOTLP && b.config.Tracing.OTLPEndpoint == "" {
+		b.Errors = append(b.Errors, fmt.Errorf("configuration error: TRACING_OTLP_ENDPOINT is required for otlp exporter"))
 	}
 
 	return b
 }
 
-// Build завершает построение конфигурации и возвращает готовую AppConfig.
-// Если при построении возникли ошибки, они возвращаются объединенными.
+// Build завершает построение конфигурации, проверяет ее по тегам validate и возвращает готовую AppConfig.
+// Если при построении или проверке возникли ошибки, они возвращаются в ConfigError.
 func (b *AppConfigBuilder) Build() (*AppConfig, error) {
-	return b.config, errors.Join(b.Errors...)
+	b.Errors = append(b.Errors, flattenErrors(b.config.Validate())...)
+
+	if len(b.Errors) > 0 {
+		return b.config, &ConfigError{Errors: b.Errors}
+	}
+
+	return b.config, nil
 }
 
-// loadStringVariableFromEnv загружает строковое значение из переменной окружения.
-// Если переменная окружения не установлена, используется значение по умолчанию.
-// Если значение пустое и значение по умолчанию не указано, добавляется ошибка.
-func (b *AppConfigBuilder) loadStringVariableFromEnv(envName string, Default *string) string {
-	value := os.Getenv(envName)
+// lookupVariable возвращает значение параметра из источника с наивысшим приоритетом: флага командной строки,
+// переменной окружения или файла конфигурации. Если skipEmpty, пустые значения пропускаются.
+func (b *AppConfigBuilder) lookupVariable(envName string, skipEmpty bool) (string, bool) {
+	if value, ok := b.flagValues[envName]; ok {
+		return value, true
+	}
 
-	if value == "" && Default != nil {
-		value = *Default
+	if value, ok := os.LookupEnv(envName); ok && (value != "" || !skipEmpty) {
+		return value, true
 	}
 
-	if value == "" {
-		b.Errors = append(b.Errors, fmt.Errorf("configuration error: '%s' not specified", envName))
+	if value, ok := b.fileValues[envName]; ok && (value != "" || !skipEmpty) {
+		return value, true
 	}
 
-	return value
+	return "", false
 }
 
-// loadIntVariableFromEnv загружает целочисленное значение из переменной окружения.
-// Значение преобразуется из строки в int. Если преобразование не удается, добавляется ошибка.
-func (b *AppConfigBuilder) loadIntVariableFromEnv(envName string, Default *int) int {
-	stringedDefault := strconv.Itoa(*Default)
-	value := b.loadStringVariableFromEnv(envName, &stringedDefault)
-
-	if value == "" {
-		return 0
+// loadStringVariableFromEnv загружает строковое значение из флага, переменной окружения или файла конфигурации.
+// Если значение не указано, используется значение по умолчанию. Пустое значение заменяется значением
+// по умолчанию, если оно задано, иначе считается допустимым.
+func (b *AppConfigBuilder) loadStringVariableFromEnv(envName string, Default string) string {
+	if value, ok := b.lookupVariable(envName, Default != ""); ok {
+		return value
 	}
 
+	return Default
+}
+
+// loadIntVariableFromEnv загружает целочисленное значение из флага, переменной окружения или файла конфигурации.
+// Если преобразование не удается, добавляется ошибка и используется значение по умолчанию.
+func (b *AppConfigBuilder) loadIntVariableFromEnv(envName string, Default string) int {
+	value := b.loadStringVariableFromEnv(envName, Default)
+
 	numericValue, err := strconv.Atoi(value)
 
 	if err != nil {
-		b.Errors = append(b.Errors, fmt.Errorf("configuration error: could not convert %s to int: %w", envName, err))
+		b.Errors = append(b.Errors, fmt.Errorf("configuration error: %s must be an integer, got '%s'", envName, value))
+		numericValue, _ = strconv.Atoi(Default)
 	}
 
 	return numericValue
 }
 
-// CreateFLagsInitialConfig создает и инициализирует FlagsInitialConfig с флагами командной строки.
-// Флаги должны быть распарсены с помощью flag.Parse() перед использованием.
-func CreateFLagsInitialConfig() *FlagsInitialConfig {
-	return &FlagsInitialConfig{
-		StartupAddress: flag.String("a", "", "startup address"),
-		BaseURL:        flag.String("b", "", "short links url prefix"),
-		DB: &DBFlagsInitialConfig{
-			DatabaseDSN: flag.String("d", "", "Database DSN"),
-		},
-		StoragePath: flag.String("f", "", "storage path to save dump and load all data"),
-		AuditURL:    flag.String("audit-url", "", "audit HTTP endpoint URL"),
-		AuditFile:   flag.String("audit-file", "", "audit log file path"),
+// loadBoolVariableFromEnv загружает логическое значение из флага, переменной окружения или файла конфигурации.
+// Если преобразование не удается, добавляется ошибка и используется значение по умолчанию.
+func (b *AppConfigBuilder) loadBoolVariableFromEnv(envName string, Default string) bool {
+	value := b.loadStringVariableFromEnv(envName, Default)
+
+	boolValue, err := strconv.ParseBool(value)
+
+	if err != nil {
+		b.Errors = append(b.Errors, fmt.Errorf("configuration error: %s must be true or false, got '%s'", envName, value))
+		boolValue, _ = strconv.ParseBool(Default)
+	}
+
+	return boolValue
+}
+
+// loadFloatVariableFromEnv загружает дробное значение из флага, переменной окружения или файла конфигурации.
+// Если преобразование не удается, добавляется ошибка и используется значение по умолчанию.
+func (b *AppConfigBuilder) loadFloatVariableFromEnv(envName string, Default string) float64 {
+	value := b.loadStringVariableFromEnv(envName, Default)
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		b.Errors = append(b.Errors, fmt.Errorf("configuration error: %s must be a number, got '%s'", envName, value))
+		floatValue, _ = strconv.ParseFloat(Default, 64)
 	}
+
+	return floatValue
+}
+
+// loadDurationVariableFromEnv загружает длительность в формате time.ParseDuration, например "30s",
+// из флага, переменной окружения или файла конфигурации.
+// Если преобразование не удается, добавляется ошибка и используется значение по умолчанию.
+func (b *AppConfigBuilder) loadDurationVariableFromEnv(envName string, Default string) time.Duration {
+	value := b.loadStringVariableFromEnv(envName, Default)
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil {
+		b.Errors = append(b.Errors, fmt.Errorf("configuration error: %s must be a duration with a unit like 500ms, 30s or 1m30s, got '%s'", envName, value))
+		duration, _ = time.ParseDuration(Default)
+	}
+
+	return duration
+}
+
+// CreateFLagsInitialConfig регистрирует флаги командной строки файла конфигурации -c (-config) и полей AppConfig
+// по их тегам flag. Флаги должны быть распарсены с помощью flag.Parse() перед использованием.
+func CreateFLagsInitialConfig() *FlagsInitialConfig {
+	values := registerAppConfigFlags(flag.CommandLine)
+
+	configFile := flag.String("c", "", "JSON or YAML config file (CONFIG)")
+	flag.StringVar(configFile, "config", "", "JSON or YAML config file (CONFIG)")
+	values["CONFIG"] = configFile
+
+	return &FlagsInitialConfig{Values: values}
+}
+
+// Usage возвращает справку по переменным окружения параметров конфигурации
+// с флагами, значениями по умолчанию и описаниями из тегов полей AppConfig.
+func Usage() string {
+	return appConfigUsage
 }
 
-// GetConfig создает полную конфигурацию приложения из флагов и переменных окружения.
+// GetConfig создает полную конфигурацию приложения из флагов, переменных окружения и файла конфигурации.
 // Возвращает готовую конфигурацию или ошибки, возникшие при ее построении.
 var GetConfig = func(flagsConfig *FlagsInitialConfig) (*AppConfig, error) {
 	return NewAppConfigBuilder(flagsConfig).
-		WithBaseURL().
-		WithDatabaseDSN().
-		WithStoragePath().
-		WithStartupAddress().
-		WithShortLinksLength().
-		WithLoggingLevel().
-		WithTokenSecretKey().
-		WithTokenLifeTime().
-		WithAuditFile().
-		WithAuditURL().
+		WithConfigFile().
+		WithFields().
+		WithTrustedProxies().
+		WithTLS().
+		WithRateLimit().
+		WithTracing().
 		Build()
 }
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterAppConfigFlags(t *testing.T) {
+	flags := flag.NewFlagSet("shortener", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	values := registerAppConfigFlags(flags)
+	require.NoError(t, flags.Parse([]string{"-a", "flag:8080", "-d", "postgres://flag", "-audit-file", "audit.log"}))
+
+	t.Setenv("SERVER_ADDRESS", "env:8080")
+	t.Setenv("BASE_URL", "http://localhost:8080")
+
+	cfg, err := GetConfig(&FlagsInitialConfig{Values: values})
+	require.NoError(t, err)
+
+	// Flags of nested named and anonymous structs take precedence over env
+	assert.Equal(t, "flag:8080", cfg.Server.Address)
+	assert.Equal(t, "postgres://flag", cfg.DB.DatabaseDSN.Value())
+	assert.Equal(t, "audit.log", cfg.Audit.AuditFile)
+	assert.Contains(t, flags.Lookup("a").Usage, "SERVER_ADDRESS, default localhost:8080")
+}
+
+func TestGetConfig_Defaults(t *testing.T) {
+	t.Setenv("BASE_URL", "http://localhost:8080")
+
+	cfg, err := GetConfig(&FlagsInitialConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, EnvironmentDevelopment, cfg.Environment)
+	assert.Equal(t, defaultTokenSecretKey, cfg.Auth.TokenSecretKey.Value())
+	assert.Equal(t, 8, cfg.Server.ShortLinksLength)
+	assert.Equal(t, 500*time.Millisecond, cfg.Audit.RetryBaseDelay)
+	assert.Equal(t, 64<<10, cfg.RequestLimits.MaxBodySize)
+	assert.True(t, cfg.Links.NormalizeStripTrackingParams)
+	assert.Equal(t, 1.0, cfg.Tracing.SampleRatio)
+}
+
+func TestUsage(t *testing.T) {
+	assert.Regexp(t, `\n  SERVER_ADDRESS, -a +localhost:8080 +startup address\n`, Usage())
+	assert.Regexp(t, `\n  AUDIT_HMAC_KEY +audit records signing key`, Usage())
+}
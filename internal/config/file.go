@@ -0,0 +1,151 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadConfigFile читает файл конфигурации JSON (.json) или YAML (.yaml, .yml) и возвращает его значения
+// по именам переменных окружения. Структура файла повторяет AppConfig, ключи записываются в snake_case,
+// например server.base_url или rate_limit.create_per_minute. Неизвестные ключи считаются ошибкой.
+func loadConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]any
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s', use .json, .yaml or .yml", filepath.Ext(path))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+
+	if err := collectFileValues(reflect.TypeOf(AppConfig{}), document, "", values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// collectFileValues сопоставляет ключи раздела файла полям структуры и сохраняет значения
+// по тегу env поля. Вложенные структуры соответствуют вложенным разделам.
+func collectFileValues(t reflect.Type, section map[string]any, prefix string, values map[string]string) error {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := range t.NumField() {
+		fields[fileKey(t.Field(i).Name)] = t.Field(i)
+	}
+
+	keys := make([]string, 0, len(section))
+	for key := range section {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var errs []error
+
+	for _, key := range keys {
+		path := prefix + key
+
+		field, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key '%s'", path))
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			nested, ok := section[key].(map[string]any)
+			if !ok {
+				errs = append(errs, fmt.Errorf("key '%s' must be a section", path))
+				continue
+			}
+
+			errs = append(errs, collectFileValues(field.Type, nested, path+".", values))
+			continue
+		}
+
+		value, err := fileValueString(section[key])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("key '%s': %w", path, err))
+			continue
+		}
+
+		values[field.Tag.Get("env")] = value
+	}
+
+	return errors.Join(errs...)
+}
+
+// fileValueString приводит значение из файла к строковому виду переменных окружения.
+// Списки объединяются через запятую.
+func fileValueString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			text, err := fileValueString(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, ","), nil
+	}
+
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+// fileKey возвращает ключ файла конфигурации для имени поля: BaseURL - base_url, HMACKey - hmac_key.
+func fileKey(name string) string {
+	runes := []rune(name)
+	key := make([]rune, 0, len(runes)+4)
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				key = append(key, '_')
+			}
+		}
+		key = append(key, unicode.ToLower(r))
+	}
+
+	return string(key)
+}
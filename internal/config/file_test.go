@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func Test_fileKey(t *testing.T) {
+	tests := map[string]string{
+		"Address":       "address",
+		"BaseURL":       "base_url",
+		"DatabaseDSN":   "database_dsn",
+		"HMACKey":       "hmac_key",
+		"FileMaxSizeMB": "file_max_size_mb",
+		"URLCheck":      "url_check",
+		"OTLPEndpoint":  "otlp_endpoint",
+		"DB":            "db",
+	}
+
+	for name, want := range tests {
+		assert.Equal(t, want, fileKey(name), name)
+	}
+}
+
+func TestAppConfig_EnvTags(t *testing.T) {
+	seen := make(map[string]string)
+
+	var walk func(typ reflect.Type, path string)
+	walk = func(typ reflect.Type, path string) {
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, path+field.Name+".")
+				continue
+			}
+
+			env := field.Tag.Get("env")
+			require.NotEmpty(t, env, "field %s%s has no env tag", path, field.Name)
+			require.NotContains(t, seen, env, "env %s is used by %s%s and %s", env, path, field.Name, seen[env])
+			seen[env] = path + field.Name
+		}
+	}
+
+	walk(reflect.TypeOf(AppConfig{}), "")
+}
+
+func TestGetConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+logging_level: debug
+server:
+  base_url: http://file.example
+  address: file:8080
+  trusted_proxies: [10.0.0.0/8, 192.168.0.1]
+  read_timeout: 10s
+db:
+  storage_path: file.json
+rate_limit:
+  create_per_minute: 30
+`)
+
+	t.Setenv("CONFIG", path)
+	t.Setenv("SERVER_ADDRESS", "env:8080")
+	t.Setenv("FILE_STORAGE_PATH", "env.json")
+
+	flagStoragePath := "flag.json"
+	cfg, err := GetConfig(&FlagsInitialConfig{Values: map[string]*string{"FILE_STORAGE_PATH": &flagStoragePath}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "flag.json", cfg.DB.StoragePath)
+	assert.Equal(t, "env:8080", cfg.Server.Address)
+	assert.Equal(t, "http://file.example", cfg.Server.BaseURL)
+	assert.Equal(t, "debug", cfg.LoggingLevel)
+	assert.Equal(t, "10.0.0.0/8,192.168.0.1", cfg.Server.TrustedProxies)
+	assert.Equal(t, 10*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 30, cfg.RateLimit.CreatePerMinute)
+	assert.Equal(t, 2*time.Minute, cfg.Server.IdleTimeout)
+}
+
+func TestGetConfig_JSONFile(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{
+		"server": {"base_url": "http://file.example", "short_links_length": 12},
+		"request_limits": {"max_decompressed_size": 16777216},
+		"audit": {"audit_url": "http://audit.example/events"}
+	}`)
+
+	t.Setenv("CONFIG", path)
+
+	cfg, err := GetConfig(&FlagsInitialConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 12, cfg.Server.ShortLinksLength)
+	assert.Equal(t, 16<<20, cfg.RequestLimits.MaxDecompressedSize)
+	assert.Equal(t, "http://audit.example/events", cfg.Audit.AuditURL)
+}
+
+func TestGetConfig_InvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"unknown key", "config.yaml", "server:\n  adress: localhost:8080\n", "unknown key 'server.adress'"},
+		{"unknown section", "config.json", `{"servers": {}}`, "unknown key 'servers'"},
+		{"value instead of section", "config.yaml", "server: localhost\n", "key 'server' must be a section"},
+		{"section instead of value", "config.yaml", "logging_level:\n  value: debug\n", "key 'logging_level'"},
+		{"unsupported format", "config.toml", "", "unsupported format"},
+		{"invalid value", "config.yaml", "server:\n  short_links_length: many\n", "SHORT_LINKS_LENGTH"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("CONFIG", writeConfigFile(t, test.file, test.content))
+			t.Setenv("BASE_URL", "http://localhost:8080")
+
+			_, err := GetConfig(&FlagsInitialConfig{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.wantErr)
+		})
+	}
+}
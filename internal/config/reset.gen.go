@@ -3,12 +3,16 @@
 
 package config
 
+import "time"
+
 func (rs *AppConfig) Reset() {
 	if rs == nil {
 		return
 	}
 
 	rs.LoggingLevel = ""
+	rs.ReloadInterval = *new(time.Duration)
+	rs.Environment = ""
 	if resetter, ok := interface{}(&rs.DB).(interface{ Reset() }); ok {
 		resetter.Reset()
 	} else {
@@ -21,7 +25,76 @@ func (rs *AppConfig) Reset() {
 	}
 	rs.Audit.AuditURL = ""
 	rs.Audit.AuditFile = ""
+	rs.Audit.SyslogURL = ""
+	rs.Audit.SyslogCAFile = ""
+	rs.Audit.SyslogAppName = ""
+	rs.Audit.PubSubURL = ""
+	rs.Audit.PubSubSubject = ""
+	rs.Audit.FileMaxSizeMB = 0
+	rs.Audit.FileRotateInterval = *new(time.Duration)
+	rs.Audit.FileMaxBackups = 0
+	rs.Audit.FileCompress = false
+	rs.Audit.FileFlushInterval = *new(time.Duration)
+	rs.Audit.HMACKey = *new(Secret)
+	rs.Audit.CheckpointInterval = 0
+	rs.Audit.OutboxPath = ""
+	rs.Audit.DeadLetterPath = ""
+	rs.Audit.BatchSize = 0
+	rs.Audit.FlushInterval = *new(time.Duration)
+	rs.Audit.RequestTimeout = *new(time.Duration)
+	rs.Audit.MaxAttempts = 0
+	rs.Audit.RetryBaseDelay = *new(time.Duration)
+	rs.Audit.RetryMaxDelay = *new(time.Duration)
 	rs.Server.BaseURL = ""
 	rs.Server.Address = ""
 	rs.Server.ShortLinksLength = 0
+	rs.Server.TrustedProxies = ""
+	rs.Server.CompressionMinSize = 0
+	rs.Server.ReadHeaderTimeout = *new(time.Duration)
+	rs.Server.ReadTimeout = *new(time.Duration)
+	rs.Server.WriteTimeout = *new(time.Duration)
+	rs.Server.IdleTimeout = *new(time.Duration)
+	rs.Server.ShutdownDrainDelay = *new(time.Duration)
+	rs.TLS.CertFile = ""
+	rs.TLS.KeyFile = ""
+	rs.TLS.MinVersion = ""
+	rs.TLS.CipherSuites = ""
+	rs.TLS.ReloadInterval = *new(time.Duration)
+	rs.TLS.RedirectAddress = ""
+	rs.Links.NormalizeSortQuery = false
+	rs.Links.NormalizeStripTrackingParams = false
+	rs.Links.NormalizeStripTrailingSlash = false
+	rs.Policy.AllowedSchemes = ""
+	rs.Policy.DomainAllowListFile = ""
+	rs.Policy.DomainDenyListFile = ""
+	rs.Policy.BlockPrivateAddresses = false
+	rs.Policy.MaxURLLength = 0
+	rs.URLCheck.BlocklistFiles = ""
+	rs.URLCheck.ReloadInterval = *new(time.Duration)
+	rs.URLCheck.CheckOnRedirect = false
+	rs.Webhooks.Workers = 0
+	rs.Webhooks.QueueSize = 0
+	rs.Webhooks.RequestTimeout = *new(time.Duration)
+	rs.Webhooks.MaxAttempts = 0
+	rs.Webhooks.RetryBaseDelay = *new(time.Duration)
+	rs.Webhooks.MaxInFlight = 0
+	rs.Webhooks.AllowPrivateAddresses = false
+	rs.RequestLimits.MaxBodySize = 0
+	rs.RequestLimits.MaxBatchBodySize = 0
+	rs.RequestLimits.MaxDecompressedSize = 0
+	rs.RequestLimits.MaxDecompressionRatio = 0
+	rs.RateLimit.Store = ""
+	rs.RateLimit.CreatePerMinute = 0
+	rs.RateLimit.CreateBurst = 0
+	rs.RateLimit.BatchPerMinute = 0
+	rs.RateLimit.BatchBurst = 0
+	rs.RateLimit.RedirectPerMinute = 0
+	rs.RateLimit.RedirectBurst = 0
+	rs.RateLimit.MaxBatchSize = 0
+	rs.RequestLogging.SampleRatio = 0
+	rs.RequestLogging.MaxBodySize = 0
+	rs.Tracing.Exporter = ""
+	rs.Tracing.OTLPEndpoint = ""
+	rs.Tracing.ServiceName = ""
+	rs.Tracing.SampleRatio = 0
 }
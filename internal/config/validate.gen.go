@@ -0,0 +1,206 @@
+// Code generated by reset tool. DO NOT EDIT.
+//go:generate go run github.com/Alexey-zaliznuak/shortener/cmd/reset
+
+package config
+
+import "errors"
+import "fmt"
+import "net/url"
+
+func (rs *DBConfig) Validate() error {
+	if rs == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if len(rs.DeduplicationScope) == 0 {
+		errs = append(errs, errors.New("LINKS_DEDUPLICATION_SCOPE is required"))
+	} else {
+		switch rs.DeduplicationScope {
+		case "global", "user", "none":
+		default:
+			errs = append(errs, fmt.Errorf("unknown LINKS_DEDUPLICATION_SCOPE '%v', expected one of: global, user, none", rs.DeduplicationScope))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (rs *AppConfig) Validate() error {
+	if rs == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if len(rs.LoggingLevel) == 0 {
+		errs = append(errs, errors.New("LOGGING_LEVEL is required"))
+	} else {
+		switch rs.LoggingLevel {
+		case "debug", "info", "warn", "error":
+		default:
+			errs = append(errs, fmt.Errorf("unknown LOGGING_LEVEL '%v', expected one of: debug, info, warn, error", rs.LoggingLevel))
+		}
+	}
+	if rs.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("CONFIG_RELOAD_INTERVAL must be at least 0, got %v", rs.ReloadInterval))
+	}
+	if len(rs.Environment) == 0 {
+		errs = append(errs, errors.New("APP_ENV is required"))
+	} else {
+		switch rs.Environment {
+		case "development", "production":
+		default:
+			errs = append(errs, fmt.Errorf("unknown APP_ENV '%v', expected one of: development, production", rs.Environment))
+		}
+	}
+	if validator, ok := interface{}(&rs.DB).(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if validator, ok := interface{}(&rs.Auth).(interface{ Validate() error }); ok {
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if rs.Audit.FileRotateInterval < 0 {
+		errs = append(errs, fmt.Errorf("AUDIT_FILE_ROTATE_INTERVAL must be at least 0, got %v", rs.Audit.FileRotateInterval))
+	}
+	if rs.Audit.FileFlushInterval < 1000000 {
+		errs = append(errs, fmt.Errorf("AUDIT_FILE_FLUSH_INTERVAL must be at least 1ms, got %v", rs.Audit.FileFlushInterval))
+	}
+	if rs.Audit.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("AUDIT_BATCH_SIZE must be at least 1, got %v", rs.Audit.BatchSize))
+	}
+	if rs.Audit.FlushInterval < 1000000 {
+		errs = append(errs, fmt.Errorf("AUDIT_FLUSH_INTERVAL must be at least 1ms, got %v", rs.Audit.FlushInterval))
+	}
+	if rs.Audit.RequestTimeout < 1000000 {
+		errs = append(errs, fmt.Errorf("AUDIT_REQUEST_TIMEOUT must be at least 1ms, got %v", rs.Audit.RequestTimeout))
+	}
+	if rs.Audit.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("AUDIT_MAX_ATTEMPTS must be at least 1, got %v", rs.Audit.MaxAttempts))
+	}
+	if rs.Audit.RetryBaseDelay < 1000000 {
+		errs = append(errs, fmt.Errorf("AUDIT_RETRY_BASE_DELAY must be at least 1ms, got %v", rs.Audit.RetryBaseDelay))
+	}
+	if rs.Audit.RetryMaxDelay < 1000000 {
+		errs = append(errs, fmt.Errorf("AUDIT_RETRY_MAX_DELAY must be at least 1ms, got %v", rs.Audit.RetryMaxDelay))
+	}
+	if len(rs.Server.BaseURL) == 0 {
+		errs = append(errs, errors.New("BASE_URL is required"))
+	} else {
+		if u, err := url.Parse(rs.Server.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("BASE_URL must be an absolute URL, got '%v'", rs.Server.BaseURL))
+		}
+	}
+	if rs.Server.ShortLinksLength < 4 || rs.Server.ShortLinksLength > 32 {
+		errs = append(errs, fmt.Errorf("SHORT_LINKS_LENGTH must be between 4 and 32, got %v", rs.Server.ShortLinksLength))
+	}
+	if rs.Server.CompressionMinSize < 0 {
+		errs = append(errs, fmt.Errorf("COMPRESSION_MIN_SIZE must be at least 0, got %v", rs.Server.CompressionMinSize))
+	}
+	if rs.Server.ReadHeaderTimeout < 1000000 {
+		errs = append(errs, fmt.Errorf("SERVER_READ_HEADER_TIMEOUT must be at least 1ms, got %v", rs.Server.ReadHeaderTimeout))
+	}
+	if rs.Server.ReadTimeout < 1000000 {
+		errs = append(errs, fmt.Errorf("SERVER_READ_TIMEOUT must be at least 1ms, got %v", rs.Server.ReadTimeout))
+	}
+	if rs.Server.WriteTimeout < 1000000 {
+		errs = append(errs, fmt.Errorf("SERVER_WRITE_TIMEOUT must be at least 1ms, got %v", rs.Server.WriteTimeout))
+	}
+	if rs.Server.IdleTimeout < 1000000 {
+		errs = append(errs, fmt.Errorf("SERVER_IDLE_TIMEOUT must be at least 1ms, got %v", rs.Server.IdleTimeout))
+	}
+	if rs.Server.ShutdownDrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("SERVER_SHUTDOWN_DRAIN_DELAY must be at least 0, got %v", rs.Server.ShutdownDrainDelay))
+	}
+	if len(rs.TLS.MinVersion) == 0 {
+		errs = append(errs, errors.New("TLS_MIN_VERSION is required"))
+	} else {
+		switch rs.TLS.MinVersion {
+		case "1.2", "1.3":
+		default:
+			errs = append(errs, fmt.Errorf("unknown TLS_MIN_VERSION '%v', expected one of: 1.2, 1.3", rs.TLS.MinVersion))
+		}
+	}
+	if rs.TLS.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("TLS_RELOAD_INTERVAL must be at least 0, got %v", rs.TLS.ReloadInterval))
+	}
+	if rs.URLCheck.ReloadInterval < 1000000 {
+		errs = append(errs, fmt.Errorf("URL_BLOCKLIST_RELOAD_INTERVAL must be at least 1ms, got %v", rs.URLCheck.ReloadInterval))
+	}
+	if rs.Webhooks.Workers < 1 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_WORKERS must be at least 1, got %v", rs.Webhooks.Workers))
+	}
+	if rs.Webhooks.QueueSize < 1 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_QUEUE_SIZE must be at least 1, got %v", rs.Webhooks.QueueSize))
+	}
+	if rs.Webhooks.RequestTimeout < 1000000 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_REQUEST_TIMEOUT must be at least 1ms, got %v", rs.Webhooks.RequestTimeout))
+	}
+	if rs.Webhooks.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1, got %v", rs.Webhooks.MaxAttempts))
+	}
+	if rs.Webhooks.RetryBaseDelay < 1000000 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_RETRY_BASE_DELAY must be at least 1ms, got %v", rs.Webhooks.RetryBaseDelay))
+	}
+	if rs.Webhooks.MaxInFlight < 1 {
+		errs = append(errs, fmt.Errorf("WEBHOOK_MAX_IN_FLIGHT must be at least 1, got %v", rs.Webhooks.MaxInFlight))
+	}
+	if rs.RequestLimits.MaxBodySize < 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_MAX_BODY_SIZE must be at least 0, got %v", rs.RequestLimits.MaxBodySize))
+	}
+	if rs.RequestLimits.MaxBatchBodySize < 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_MAX_BATCH_BODY_SIZE must be at least 0, got %v", rs.RequestLimits.MaxBatchBodySize))
+	}
+	if rs.RequestLimits.MaxDecompressedSize < 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_MAX_DECOMPRESSED_SIZE must be at least 0, got %v", rs.RequestLimits.MaxDecompressedSize))
+	}
+	if rs.RequestLimits.MaxDecompressionRatio < 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_MAX_DECOMPRESSION_RATIO must be at least 0, got %v", rs.RequestLimits.MaxDecompressionRatio))
+	}
+	if len(rs.RateLimit.Store) == 0 {
+		errs = append(errs, errors.New("RATE_LIMIT_STORE is required"))
+	} else {
+		switch rs.RateLimit.Store {
+		case "memory", "postgres":
+		default:
+			errs = append(errs, fmt.Errorf("unknown RATE_LIMIT_STORE '%v', expected one of: memory, postgres", rs.RateLimit.Store))
+		}
+	}
+	if rs.RateLimit.CreatePerMinute < 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_CREATE_PER_MINUTE must be at least 0, got %v", rs.RateLimit.CreatePerMinute))
+	}
+	if rs.RateLimit.BatchPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_BATCH_PER_MINUTE must be at least 0, got %v", rs.RateLimit.BatchPerMinute))
+	}
+	if rs.RateLimit.RedirectPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_REDIRECT_PER_MINUTE must be at least 0, got %v", rs.RateLimit.RedirectPerMinute))
+	}
+	if rs.RateLimit.MaxBatchSize < 0 {
+		errs = append(errs, fmt.Errorf("BATCH_MAX_SIZE must be at least 0, got %v", rs.RateLimit.MaxBatchSize))
+	}
+	if rs.RequestLogging.SampleRatio < 0 || rs.RequestLogging.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("REQUEST_LOG_SAMPLE_RATIO must be between 0 and 1, got %v", rs.RequestLogging.SampleRatio))
+	}
+	if rs.RequestLogging.MaxBodySize < 0 {
+		errs = append(errs, fmt.Errorf("REQUEST_LOG_MAX_BODY_SIZE must be at least 0, got %v", rs.RequestLogging.MaxBodySize))
+	}
+	if len(rs.Tracing.Exporter) == 0 {
+		errs = append(errs, errors.New("TRACING_EXPORTER is required"))
+	} else {
+		switch rs.Tracing.Exporter {
+		case "none", "stdout", "otlp":
+		default:
+			errs = append(errs, fmt.Errorf("unknown TRACING_EXPORTER '%v', expected one of: none, stdout, otlp", rs.Tracing.Exporter))
+		}
+	}
+	if rs.Tracing.SampleRatio < 0 || rs.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", rs.Tracing.SampleRatio))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Допустимые уровни логирования.
+const (
+	LoggingLevelDebug = "debug"
+	LoggingLevelInfo  = "info"
+	LoggingLevelWarn  = "warn"
+	LoggingLevelError = "error"
+)
+
+// configErrorPrefix содержит префикс сообщений об ошибках построения конфигурации.
+const configErrorPrefix = "configuration error: "
+
+// ConfigError содержит все ошибки, найденные при построении конфигурации, чтобы исправить их за один запуск.
+type ConfigError struct {
+	// Errors содержит найденные ошибки в порядке их обнаружения.
+	Errors []error
+}
+
+// Error возвращает отчет об ошибках конфигурации, каждая ошибка выводится отдельной строкой.
+func (e *ConfigError) Error() string {
+	if len(e.Errors) == 1 {
+		return "invalid configuration: " + strings.TrimPrefix(e.Errors[0].Error(), configErrorPrefix)
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "invalid configuration, %d errors:", len(e.Errors))
+
+	for _, err := range e.Errors {
+		report.WriteString("\n  - ")
+		report.WriteString(strings.TrimPrefix(err.Error(), configErrorPrefix))
+	}
+
+	return report.String()
+}
+
+// Unwrap возвращает найденные ошибки для errors.Is и errors.As.
+func (e *ConfigError) Unwrap() []error {
+	return e.Errors
+}
+
+// flattenErrors раскрывает ошибки, объединенные errors.Join, в том числе вложенные, чтобы каждая
+// ошибка сгенерированного Validate выводилась в отчете отдельной строкой.
+func flattenErrors(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		if err == nil {
+			return nil
+		}
+		return []error{err}
+	}
+
+	var errs []error
+	for _, inner := range joined.Unwrap() {
+		errs = append(errs, flattenErrors(inner)...)
+	}
+
+	return errs
+}
+
+// Dump возвращает действующую конфигурацию в формате YAML, пригодном для файла конфигурации.
+// Рядом с каждым значением указывается имя переменной окружения, секреты скрываются.
+func Dump(cfg *AppConfig) ([]byte, error) {
+	return yaml.Marshal(dumpSection(reflect.ValueOf(cfg).Elem()))
+}
+
+// dumpSection строит узел YAML раздела конфигурации с полями в порядке их объявления.
+func dumpSection(section reflect.Value) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+
+	for i := range section.NumField() {
+		field := section.Type().Field(i)
+		value := section.Field(i)
+
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: fileKey(field.Name)}
+
+		if field.Type.Kind() == reflect.Struct {
+			node.Content = append(node.Content, key, dumpSection(value))
+			continue
+		}
+
+		scalar := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(value.Interface()), LineComment: field.Tag.Get("env")}
+
+		switch field.Type.Kind() {
+		case reflect.Bool:
+			scalar.Tag = "!!bool"
+		case reflect.Int:
+			scalar.Tag = "!!int"
+		case reflect.Float64:
+			scalar.Tag = "!!float"
+		}
+
+		node.Content = append(node.Content, key, scalar)
+	}
+
+	return node
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConfig_Validation(t *testing.T) {
+	t.Setenv("BASE_URL", "http://localhost:8080")
+	t.Setenv("SHORT_LINKS_LENGTH", "64")
+	t.Setenv("LOGGING_LEVEL", "verbose")
+	t.Setenv("SERVER_READ_TIMEOUT", "30")
+	t.Setenv("URL_MAX_LENGTH", "long")
+
+	_, err := GetConfig(&FlagsInitialConfig{})
+
+	var configErr *ConfigError
+	require.ErrorAs(t, err, &configErr)
+	assert.Len(t, configErr.Errors, 4)
+	assert.Equal(t, `invalid configuration, 4 errors:
+  - SERVER_READ_TIMEOUT must be a duration with a unit like 500ms, 30s or 1m30s, got '30'
+  - URL_MAX_LENGTH must be an integer, got 'long'
+  - unknown LOGGING_LEVEL 'verbose', expected one of: debug, info, warn, error
+  - SHORT_LINKS_LENGTH must be between 4 and 32, got 64`, err.Error())
+}
+
+func TestGetConfig_ValidationNested(t *testing.T) {
+	t.Setenv("BASE_URL", "localhost:8080")
+	t.Setenv("LINKS_DEDUPLICATION_SCOPE", "tenant")
+	t.Setenv("TRACING_SAMPLE_RATIO", "1.5")
+
+	_, err := GetConfig(&FlagsInitialConfig{})
+
+	var configErr *ConfigError
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, []string{
+		"unknown LINKS_DEDUPLICATION_SCOPE 'tenant', expected one of: global, user, none",
+		"BASE_URL must be an absolute URL, got 'localhost:8080'",
+		"TRACING_SAMPLE_RATIO must be between 0 and 1, got 1.5",
+	}, errorMessages(configErr.Errors))
+}
+
+func TestGetConfig_ValidationDurations(t *testing.T) {
+	t.Setenv("BASE_URL", "http://localhost:8080")
+	t.Setenv("AUDIT_FLUSH_INTERVAL", "0s")
+	t.Setenv("SERVER_READ_HEADER_TIMEOUT", "0s")
+
+	_, err := GetConfig(&FlagsInitialConfig{})
+
+	var configErr *ConfigError
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, []string{
+		"AUDIT_FLUSH_INTERVAL must be at least 1ms, got 0s",
+		"SERVER_READ_HEADER_TIMEOUT must be at least 1ms, got 0s",
+	}, errorMessages(configErr.Errors))
+}
+
+func errorMessages(errs []error) []string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return messages
+}
+
+func TestConfigError_Single(t *testing.T) {
+	err := &ConfigError{Errors: []error{errors.New("configuration error: SHORT_LINKS_LENGTH must be between 4 and 32, got 2")}}
+	assert.Equal(t, "invalid configuration: SHORT_LINKS_LENGTH must be between 4 and 32, got 2", err.Error())
+}
+
+func TestDump(t *testing.T) {
+	t.Setenv("BASE_URL", "http://localhost:8080")
+	t.Setenv("AUTH_TOKEN_SECRET_KEY", "token-secret")
+	t.Setenv("SERVER_READ_TIMEOUT", "10s")
+
+	cfg, err := GetConfig(&FlagsInitialConfig{})
+	require.NoError(t, err)
+
+	dump, err := Dump(cfg)
+	require.NoError(t, err)
+	assert.Contains(t, string(dump), "read_timeout: 10s # SERVER_READ_TIMEOUT\n")
+	assert.Contains(t, string(dump), "short_links_length: 8 # SHORT_LINKS_LENGTH\n")
+	assert.NotContains(t, string(dump), "token-secret")
+
+	// The dump is a valid config file
+	t.Setenv("SERVER_READ_TIMEOUT", "")
+	t.Setenv("CONFIG", writeConfigFile(t, "config.yaml", string(dump)))
+
+	loaded, err := GetConfig(&FlagsInitialConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Server, loaded.Server)
+	assert.Equal(t, cfg.RateLimit, loaded.RateLimit)
+}
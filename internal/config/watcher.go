@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ReloadHandler применяет новую конфигурацию, changed содержит имена переменных окружения измененных параметров.
+type ReloadHandler func(cfg *AppConfig, changed []string)
+
+// Watcher перечитывает конфигурацию по запросу или при изменении файла конфигурации.
+// Новая конфигурация проверяется так же, как при запуске, и заменяет текущую атомарно.
+// Изменяются только поля с тегом reload, изменения остальных полей, например адреса
+// или строки подключения к базе данных, игнорируются до перезапуска с предупреждением в логе.
+type Watcher struct {
+	flagsConfig *FlagsInitialConfig
+	path        string
+
+	current atomic.Pointer[AppConfig]
+
+	// mu упорядочивает перезагрузки и защищает handlers и state.
+	mu       sync.Mutex
+	handlers []ReloadHandler
+	state    os.FileInfo
+}
+
+// Current возвращает текущую конфигурацию, ее нельзя изменять.
+func (w *Watcher) Current() *AppConfig {
+	return w.current.Load()
+}
+
+// OnReload добавляет обработчик, вызываемый после замены конфигурации.
+func (w *Watcher) OnReload(handler ReloadHandler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handlers = append(w.handlers, handler)
+}
+
+// Reload строит конфигурацию заново и применяет изменения полей с тегом reload.
+// Если новая конфигурация содержит ошибки, текущая остается без изменений.
+// Возвращает имена переменных окружения примененных параметров.
+func (w *Watcher) Reload() ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.path != "" {
+		if info, err := os.Stat(w.path); err == nil {
+			w.state = info
+		}
+	}
+
+	loaded, err := GetConfig(w.flagsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	next := *w.Current()
+	changed, ignored := applyReloadable(reflect.ValueOf(&next).Elem(), reflect.ValueOf(loaded).Elem())
+
+	for _, name := range ignored {
+		logger.Log.Warn("Configuration change requires restart", zap.String("setting", name))
+	}
+
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	w.current.Store(&next)
+
+	for _, handler := range w.handlers {
+		handler(&next, changed)
+	}
+
+	return changed, nil
+}
+
+// Watch перечитывает конфигурацию каждые interval, если файл конфигурации изменился, пока ctx не завершен.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration) {
+	if w.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !w.changed() {
+				continue
+			}
+
+			changed, err := w.Reload()
+			if err != nil {
+				logger.Log.Error("Configuration reload failed", zap.Error(err))
+				continue
+			}
+
+			logger.Log.Info("Configuration reloaded", zap.String("file", w.path), zap.Strings("changed", changed))
+		}
+	}
+}
+
+func (w *Watcher) changed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false
+	}
+
+	return w.state == nil || !info.ModTime().Equal(w.state.ModTime()) || info.Size() != w.state.Size()
+}
+
+// applyReloadable копирует в current отличающиеся значения полей loaded с тегом reload.
+// Возвращает имена переменных окружения скопированных и проигнорированных полей.
+func applyReloadable(current reflect.Value, loaded reflect.Value) (changed []string, ignored []string) {
+	for i := range current.NumField() {
+		field := current.Type().Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			nestedChanged, nestedIgnored := applyReloadable(current.Field(i), loaded.Field(i))
+			changed = append(changed, nestedChanged...)
+			ignored = append(ignored, nestedIgnored...)
+			continue
+		}
+
+		if current.Field(i).Equal(loaded.Field(i)) {
+			continue
+		}
+
+		if field.Tag.Get("reload") != "true" {
+			ignored = append(ignored, field.Tag.Get("env"))
+			continue
+		}
+
+		current.Field(i).Set(loaded.Field(i))
+		changed = append(changed, field.Tag.Get("env"))
+	}
+
+	return changed, ignored
+}
+
+// NewWatcher создает Watcher с текущей конфигурацией cfg, построенной из flagsConfig.
+func NewWatcher(flagsConfig *FlagsInitialConfig, cfg *AppConfig) *Watcher {
+	w := &Watcher{
+		flagsConfig: flagsConfig,
+		path:        NewAppConfigBuilder(flagsConfig).configFilePath(),
+	}
+	w.current.Store(cfg)
+
+	if w.path != "" {
+		if info, err := os.Stat(w.path); err == nil {
+			w.state = info
+		}
+	}
+
+	return w
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+logging_level: info
+server:
+  base_url: http://localhost:8080
+  address: localhost:8080
+  short_links_length: 8
+`)
+	t.Setenv("CONFIG", path)
+
+	cfg, err := GetConfig(&FlagsInitialConfig{})
+	require.NoError(t, err)
+
+	watcher := NewWatcher(&FlagsInitialConfig{}, cfg)
+	assert.False(t, watcher.changed())
+
+	var applied *AppConfig
+	watcher.OnReload(func(cfg *AppConfig, changed []string) { applied = cfg })
+
+	changed, err := watcher.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.Nil(t, applied)
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+logging_level: debug
+server:
+  base_url: http://localhost:8080
+  address: localhost:9090
+  short_links_length: 12
+`), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	assert.True(t, watcher.changed())
+
+	changed, err = watcher.Reload()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"LOGGING_LEVEL", "SHORT_LINKS_LENGTH"}, changed)
+	assert.False(t, watcher.changed())
+
+	current := watcher.Current()
+	assert.Same(t, current, applied)
+	assert.Equal(t, "debug", current.LoggingLevel)
+	assert.Equal(t, 12, current.Server.ShortLinksLength)
+	// Address requires restart
+	assert.Equal(t, "localhost:8080", current.Server.Address)
+	// The initial configuration is not modified
+	assert.Equal(t, "info", cfg.LoggingLevel)
+
+	// Invalid configuration keeps the current one
+	require.NoError(t, os.WriteFile(path, []byte("logging_level: warn\nserver:\n  short_links_length: many\n"), 0o600))
+
+	_, err = watcher.Reload()
+	require.Error(t, err)
+	assert.Same(t, current, watcher.Current())
+}
@@ -1,19 +1,23 @@
 package handler
 
 import (
-	"context"
 	"database/sql"
 	"net/http"
 
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/service"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-func checkHealth(db *sql.DB) gin.HandlerFunc {
+// checkHealth checks the links storage, the in-memory storage is always available.
+func checkHealth(healthService *service.HealthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		err := db.PingContext(context.Background())
-
-		if err != nil {
-			c.String(http.StatusInternalServerError, "Database connection failed: %s", err.Error())
+		if err := healthService.PingStorage(c.Request.Context()); err != nil {
+			logger.FromContext(c.Request.Context()).Error("Storage ping failed", zap.Error(err))
+			c.String(http.StatusInternalServerError, "Database connection failed")
 			return
 		}
 
@@ -21,8 +25,52 @@ func checkHealth(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
-func RegisterAppHandlerRoutes(router *gin.Engine, db *sql.DB) {
+// liveness reports that the process serves requests.
+// @Summary      Liveness probe
+// @Description  Returns 200 while the process is able to handle requests, dependencies are not checked
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  map[string]string  "Alive"
+// @Router       /healthz [get]
+func liveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": model.HealthStatusUp})
+	}
+}
+
+// readiness reports whether the service can serve requests.
+// @Summary      Readiness probe
+// @Description  Checks the storage, pending migrations, audit receivers and whether the shutdown has started
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  model.Readiness  "All components are up"
+// @Failure      503  {object}  model.Readiness  "Some components are down"
+// @Router       /readyz [get]
+func readiness(healthService *service.HealthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := healthService.Readiness(c.Request.Context())
+
+		status := http.StatusOK
+		if result.Status != model.HealthStatusReady {
+			status = http.StatusServiceUnavailable
+		}
+
+		c.JSON(status, result)
+	}
+}
+
+// RegisterAppHandlerRoutes registers service routes:
+//   - GET /ping - check the links storage
+//   - GET /healthz - liveness probe
+//   - GET /readyz - readiness probe
+//   - GET /metrics - metrics in the Prometheus text format
+func RegisterAppHandlerRoutes(router *gin.Engine, db *sql.DB, healthService *service.HealthService) {
 	if db != nil {
-		router.GET("/ping", checkHealth(db))
+		metrics.RegisterDBStats(db)
 	}
+
+	router.GET("/ping", checkHealth(healthService))
+	router.GET("/healthz", liveness())
+	router.GET("/readyz", readiness(healthService))
+	router.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
 }
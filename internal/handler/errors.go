@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/handler/middleware"
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
+	"github.com/Alexey-zaliznuak/shortener/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+)
+
+// Error codes returned in APIError.Code.
+const (
+	ErrorCodeInvalidRequest = "invalid_request"
+	ErrorCodeInvalidURL     = "invalid_url"
+	ErrorCodeInvalidWebhook = "invalid_webhook"
+	ErrorCodeUnauthorized   = "unauthorized"
+	ErrorCodeNotFound       = "not_found"
+	ErrorCodeGone           = "gone"
+	ErrorCodeBatchTooLarge  = "batch_too_large"
+	ErrorCodeBodyTooLarge   = "body_too_large"
+	ErrorCodeRateLimited    = "rate_limited"
+	ErrorCodeUnsupported    = "unsupported_encoding"
+	ErrorCodeInternal       = "internal_error"
+)
+
+const (
+	mimeProblemJSON = "application/problem+json"
+	mimeJSON        = "application/json"
+	mimePlain       = "text/plain"
+)
+
+// invalidRequestError marks client errors found while reading the request, e.g. malformed JSON.
+type invalidRequestError struct {
+	err error
+}
+
+func (e *invalidRequestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidRequestError) Unwrap() error {
+	return e.err
+}
+
+// APIError is an error response in the problem details format (RFC 9457).
+type APIError struct {
+	// Type contains URI reference identifying the problem type.
+	Type string `json:"type"`
+	// Title contains short summary of the problem type.
+	Title string `json:"title"`
+	// Status contains HTTP status code.
+	Status int `json:"status"`
+	// Detail contains explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Code contains machine readable error code.
+	Code string `json:"code"`
+	// URL contains the rejected URL for URL policy violations.
+	URL string `json:"url,omitempty"`
+}
+
+// invalidRequest wraps err from request parsing so it is reported as 400.
+func invalidRequest(err error) error {
+	return &invalidRequestError{err: err}
+}
+
+// newAPIError maps service and repository errors to the API error model.
+// Internal errors are replaced by a generic message to not leak details to clients.
+func newAPIError(err error) *APIError {
+	var violation *service.PolicyViolationError
+	var requestErr *invalidRequestError
+	var validationErr *jwt.ValidationError
+
+	switch {
+	case errors.As(err, &violation):
+		return &APIError{
+			Status: http.StatusUnprocessableEntity,
+			Code:   violation.Code,
+			Detail: violation.Message,
+			URL:    violation.URL,
+		}
+	case errors.Is(err, service.ErrInvalidURL):
+		return &APIError{Status: http.StatusBadRequest, Code: ErrorCodeInvalidURL, Detail: err.Error()}
+	case errors.Is(err, service.ErrBatchTooLarge):
+		return &APIError{Status: http.StatusRequestEntityTooLarge, Code: ErrorCodeBatchTooLarge, Detail: err.Error()}
+	case errors.Is(err, middleware.ErrRateLimited):
+		return &APIError{Status: http.StatusTooManyRequests, Code: ErrorCodeRateLimited, Detail: "too many requests, retry later"}
+	case errors.Is(err, middleware.ErrRequestBodyTooLarge), errors.Is(err, middleware.ErrDecompressionRatioExceeded):
+		return &APIError{Status: http.StatusRequestEntityTooLarge, Code: ErrorCodeBodyTooLarge, Detail: "request body is too large"}
+	case errors.Is(err, middleware.ErrUnsupportedContentEncoding):
+		return &APIError{Status: http.StatusUnsupportedMediaType, Code: ErrorCodeUnsupported, Detail: "content encoding must be gzip, br or zstd"}
+	case errors.Is(err, middleware.ErrInvalidContentEncoding):
+		return &APIError{Status: http.StatusBadRequest, Code: ErrorCodeInvalidRequest, Detail: middleware.ErrInvalidContentEncoding.Error()}
+	case errors.Is(err, service.ErrInvalidWebhook):
+		return &APIError{Status: http.StatusBadRequest, Code: ErrorCodeInvalidWebhook, Detail: err.Error()}
+	case errors.Is(err, service.ErrWebhookNotFound):
+		return &APIError{Status: http.StatusNotFound, Code: ErrorCodeNotFound, Detail: "webhook not found"}
+	case errors.As(err, &requestErr):
+		return &APIError{Status: http.StatusBadRequest, Code: ErrorCodeInvalidRequest, Detail: requestErr.Error()}
+	case errors.Is(err, database.ErrNotFound):
+		return &APIError{Status: http.StatusNotFound, Code: ErrorCodeNotFound, Detail: "short link not found"}
+	case errors.Is(err, database.ErrObjectDeleted):
+		return &APIError{Status: http.StatusGone, Code: ErrorCodeGone, Detail: "short link has been deleted"}
+	case errors.Is(err, http.ErrNoCookie), errors.Is(err, repository.ErrTokenValidation), errors.As(err, &validationErr):
+		return &APIError{Status: http.StatusUnauthorized, Code: ErrorCodeUnauthorized, Detail: "authorization required"}
+	default:
+		return &APIError{Status: http.StatusInternalServerError, Code: ErrorCodeInternal}
+	}
+}
+
+// respondError writes err as problem+json or plain text depending on the Accept header.
+// preferJSON selects the format when the client accepts anything.
+func respondError(c *gin.Context, err error, preferJSON bool) {
+	apiErr := newAPIError(err)
+	apiErr.Type = "about:blank"
+	apiErr.Title = http.StatusText(apiErr.Status)
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		logger.FromContext(c.Request.Context()).Error("Request failed",
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.FullPath()),
+			zap.Error(err),
+		)
+	}
+
+	offers := []string{mimePlain, mimeProblemJSON, mimeJSON}
+	if preferJSON {
+		offers = []string{mimeProblemJSON, mimeJSON, mimePlain}
+	}
+
+	switch c.NegotiateFormat(offers...) {
+	case mimeProblemJSON, mimeJSON:
+		c.Header("Content-Type", mimeProblemJSON)
+		c.JSON(apiErr.Status, apiErr)
+	default:
+		detail := apiErr.Detail
+		if detail == "" {
+			detail = apiErr.Title
+		}
+		c.String(apiErr.Status, detail)
+	}
+}
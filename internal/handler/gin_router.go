@@ -1,15 +1,48 @@
 package handler
 
 import (
+	"net/http"
+	"strings"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
 	"github.com/Alexey-zaliznuak/shortener/internal/handler/middleware"
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-func NewRouter() *gin.Engine {
+func NewRouter(cfg *config.AppConfig) *gin.Engine {
 	router := gin.Default()
 
-	router.Use(middleware.RequestAndResponseGzipCompressing())
-	router.Use(middleware.RequestLogging())
+	// Proxies are validated by the configuration, nil trusts none of them
+	var proxies []string
+	if cfg.Server.TrustedProxies != "" {
+		proxies = strings.Split(cfg.Server.TrustedProxies, ",")
+	}
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		logger.Log.Error("Trusted proxies are ignored", zap.Error(err))
+		_ = router.SetTrustedProxies(nil)
+	}
+
+	router.Use(middleware.RequestMetrics())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Compression(
+		cfg.Server.CompressionMinSize,
+		cfg.RequestLimits.MaxDecompressedSize,
+		cfg.RequestLimits.MaxDecompressionRatio,
+		rejectRequest,
+	))
+	router.Use(middleware.BodyLimit(map[string]int{
+		http.MethodPost + " /api/shorten/batch": cfg.RequestLimits.MaxBatchBodySize,
+		http.MethodDelete + " /api/user/urls":   cfg.RequestLimits.MaxBatchBodySize,
+	}, cfg.RequestLimits.MaxBodySize, rejectRequest))
+	router.Use(middleware.RequestLogging(cfg.RequestLogging.SampleRatio, cfg.RequestLogging.MaxBodySize))
 
 	return router
 }
+
+// rejectRequest responds to requests rejected by middlewares.
+func rejectRequest(c *gin.Context, err error) {
+	respondError(c, err, true)
+}
@@ -5,10 +5,11 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 
-	"github.com/Alexey-zaliznuak/shortener/internal/handler/audit"
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
 	"github.com/Alexey-zaliznuak/shortener/internal/model"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
@@ -22,35 +23,54 @@ import (
 // @Tags         links
 // @Param        shortcut  path  string  true  "Short URL identifier"
 // @Success      307  "Temporary redirect to the original URL"
-// @Failure      400  {string}  string  "Invalid shortcut"
-// @Failure      410  "Link has been deleted"
-// @Failure      500  {string}  string  "Internal server error"
+// @Success      200  {string}  string  "Warning page for quarantined links"
+// @Failure      404  {object}  APIError  "Short link not found"
+// @Failure      410  {object}  APIError  "Link has been deleted"
+// @Failure      429  {object}  APIError  "Too many requests, see Retry-After"
+// @Failure      500  {object}  APIError  "Internal server error"
 // @Router       /{shortcut} [get]
 func redirect(linksService *service.LinksService, authService *service.AuthService, auditor *audit.AuditorShortURLOperationManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		shortcut := c.Param("shortcut")
-		fullURL, err := linksService.GetFullURLFromShort(shortcut)
+		link, err := linksService.GetLink(c.Request.Context(), shortcut)
+
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			metrics.Redirects.WithLabelValues(metrics.RedirectMiss).Inc()
+		case errors.Is(err, database.ErrObjectDeleted):
+			metrics.Redirects.WithLabelValues(metrics.RedirectGone).Inc()
+		case err == nil:
+			metrics.Redirects.WithLabelValues(metrics.RedirectHit).Inc()
+		}
 
 		if err != nil {
-			if err == database.ErrObjectDeleted {
-				c.Status(http.StatusGone)
-				return
-			}
-
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, err, false)
 			return
 		}
 
-		claims, err := authService.GetOrCreateAndSaveAuthorization(c)
+		claims, err := authService.GetOrCreateAndSaveVisitorAuthorization(c)
 
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			respondError(c, err, false)
 			return
 		}
 
-		auditor.AuditNotify(audit.ShortURLActionGet, claims.ID, fullURL)
+		event := audit.NewRequestPayload(c, audit.ShortURLActionGet, claims.UserID)
+		event.URL = link.FullURL
+		event.Shortcut = link.Shortcut
+
+		if link.IsQuarantined {
+			event.Status = http.StatusOK
+			auditor.AuditNotify(event)
 
-		c.Redirect(http.StatusTemporaryRedirect, fullURL)
+			renderQuarantineWarning(c, link.FullURL)
+			return
+		}
+
+		event.Status = http.StatusTemporaryRedirect
+		auditor.AuditNotify(event)
+
+		c.Redirect(http.StatusTemporaryRedirect, link.FullURL)
 	}
 }
 
@@ -63,14 +83,18 @@ func redirect(linksService *service.LinksService, authService *service.AuthServi
 // @Param        url  body  string  true  "Full URL to shorten (raw text)"
 // @Success      201  {string}  string  "Created short URL"
 // @Success      409  {string}  string  "URL already exists, returns existing short URL"
-// @Failure      400  {string}  string  "Invalid request"
+// @Failure      400  {object}  APIError  "Invalid request"
+// @Failure      422  {object}  APIError  "URL rejected by policy"
+// @Failure      413  {object}  APIError  "Request body is too large"
+// @Failure      429  {object}  APIError  "Too many requests, see Retry-After"
+// @Failure      500  {object}  APIError  "Internal server error"
 // @Router       / [post]
 func createLink(linksService *service.LinksService, auditor *audit.AuditorShortURLOperationManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		body, err := c.GetRawData()
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, invalidRequest(err), false)
 			return
 		}
 
@@ -81,25 +105,25 @@ func createLink(linksService *service.LinksService, auditor *audit.AuditorShortU
 		link, claims, created, err := linksService.CreateLink(link.ToCreateDto(), c)
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			auditFailure(c, auditor, audit.ShortURLActionCreate, fullURL, err)
+			respondError(c, err, false)
 			return
 		}
 
-		auditor.AuditNotify(audit.ShortURLActionCreate, claims.ID, fullURL)
-
 		url, err := linksService.BuildShortURL(link.Shortcut, c)
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, err, false)
 			return
 		}
 
 		status := http.StatusCreated
 		if !created {
-			fmt.Printf("Duplicate: %s", link.FullURL)
 			status = http.StatusConflict
 		}
 
+		auditCreated(c, auditor, audit.ShortURLActionCreate, claims.UserID, fullURL, link, status)
+
 		c.String(status, url)
 	}
 }
@@ -113,15 +137,18 @@ func createLink(linksService *service.LinksService, auditor *audit.AuditorShortU
 // @Param        request  body  model.CreateShortURLRequest  true  "URL to shorten"
 // @Success      201  {object}  model.CreateShortURLResponse  "Short URL created"
 // @Success      409  {object}  model.CreateShortURLResponse  "URL already exists"
-// @Failure      400  {string}  string  "Invalid request"
-// @Failure      500  {string}  string  "Internal server error"
+// @Failure      400  {object}  APIError  "Invalid request"
+// @Failure      422  {object}  APIError  "URL rejected by policy"
+// @Failure      413  {object}  APIError  "Request body is too large"
+// @Failure      429  {object}  APIError  "Too many requests, see Retry-After"
+// @Failure      500  {object}  APIError  "Internal server error"
 // @Router       /api/shorten [post]
 func createLinkWithJSONAPI(linksService *service.LinksService, auditor *audit.AuditorShortURLOperationManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		body, err := c.GetRawData()
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, invalidRequest(err), true)
 			return
 		}
 
@@ -129,7 +156,7 @@ func createLinkWithJSONAPI(linksService *service.LinksService, auditor *audit.Au
 		err = json.Unmarshal(body, &request)
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, invalidRequest(err), true)
 			return
 		}
 
@@ -138,16 +165,15 @@ func createLinkWithJSONAPI(linksService *service.LinksService, auditor *audit.Au
 		link, claims, created, err := linksService.CreateLink(l, c)
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			auditFailure(c, auditor, audit.ShortURLActionCreate, request.FullURL, err)
+			respondError(c, err, true)
 			return
 		}
 
-		auditor.AuditNotify(audit.ShortURLActionCreate, claims.ID, request.FullURL)
-
 		shortURL, err := linksService.BuildShortURL(link.Shortcut, c)
 
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			respondError(c, err, true)
 			return
 		}
 
@@ -156,6 +182,8 @@ func createLinkWithJSONAPI(linksService *service.LinksService, auditor *audit.Au
 			status = http.StatusConflict
 		}
 
+		auditCreated(c, auditor, audit.ShortURLActionCreate, claims.UserID, request.FullURL, link, status)
+
 		c.JSON(status, &model.CreateShortURLResponse{Result: shortURL})
 	}
 }
@@ -168,15 +196,18 @@ func createLinkWithJSONAPI(linksService *service.LinksService, auditor *audit.Au
 // @Produce      json
 // @Param        request  body  []model.CreateLinkWithCorrelationIDRequestItem  true  "Array of URLs to shorten with correlation IDs"
 // @Success      201  {array}  model.CreateLinkWithCorrelationIDResponseItem  "Array of created short URLs"
-// @Failure      400  {string}  string  "Invalid request"
-// @Failure      500  {string}  string  "Internal server error"
+// @Failure      400  {object}  APIError  "Invalid request"
+// @Failure      422  {object}  APIError  "URL rejected by policy"
+// @Failure      413  {object}  APIError  "Batch contains too many links or body is too large"
+// @Failure      429  {object}  APIError  "Too many requests, see Retry-After"
+// @Failure      500  {object}  APIError  "Internal server error"
 // @Router       /api/shorten/batch [post]
-func createLinkBatch(linksService *service.LinksService) gin.HandlerFunc {
+func createLinkBatch(linksService *service.LinksService, auditor *audit.AuditorShortURLOperationManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		body, err := c.GetRawData()
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, invalidRequest(err), true)
 			return
 		}
 
@@ -185,17 +216,23 @@ func createLinkBatch(linksService *service.LinksService) gin.HandlerFunc {
 		err = json.Unmarshal(body, &request)
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, invalidRequest(err), true)
 			return
 		}
 
-		response, err := linksService.BulkCreateWithCorrelationID(request, c)
+		response, claims, err := linksService.BulkCreateWithCorrelationID(request, c)
 
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			auditFailure(c, auditor, audit.ShortURLActionBatchCreate, "", err)
+			respondError(c, err, true)
 			return
 		}
 
+		// Response items follow the order of the request ones
+		for i, item := range response {
+			auditCreated(c, auditor, audit.ShortURLActionBatchCreate, claims.UserID, request[i].FullURL, item.Link, http.StatusCreated)
+		}
+
 		c.JSON(http.StatusCreated, response)
 	}
 }
@@ -207,7 +244,7 @@ func createLinkBatch(linksService *service.LinksService) gin.HandlerFunc {
 // @Produce      json
 // @Success      200  {array}  model.GetUserLinksRequestItem  "User's links"
 // @Success      204  "User has no links or no valid authentication"
-// @Failure      500  {string}  string  "Internal server error"
+// @Failure      500  {object}  APIError  "Internal server error"
 // @Router       /api/user/urls [get]
 // @Security     CookieAuth
 func getUserLinks(linksService *service.LinksService, authService *service.AuthService) gin.HandlerFunc {
@@ -216,13 +253,13 @@ func getUserLinks(linksService *service.LinksService, authService *service.AuthS
 		links, err := linksService.GetUserLinks(c)
 
 		if err != nil && err != http.ErrNoCookie {
-			c.String(http.StatusInternalServerError, err.Error())
+			respondError(c, err, true)
 			return
 		}
 
 		_, err = authService.CreateAndSaveAuthorization(c)
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			respondError(c, err, true)
 			return
 		}
 
@@ -231,7 +268,7 @@ func getUserLinks(linksService *service.LinksService, authService *service.AuthS
 
 			_, err = authService.CreateAndSaveAuthorization(c)
 			if err != nil {
-				c.String(http.StatusInternalServerError, err.Error())
+				respondError(c, err, true)
 				return
 			}
 			return
@@ -243,7 +280,7 @@ func getUserLinks(linksService *service.LinksService, authService *service.AuthS
 		}
 
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			respondError(c, err, true)
 			return
 		}
 
@@ -262,16 +299,17 @@ func getUserLinks(linksService *service.LinksService, authService *service.AuthS
 // @Accept       json
 // @Param        shortcuts  body  []string  true  "Array of shortcut identifiers to delete"
 // @Success      202  "Deletion request accepted"
-// @Failure      400  {string}  string  "Invalid request"
-// @Failure      500  {string}  string  "Internal server error"
+// @Failure      400  {object}  APIError  "Invalid request"
+// @Failure      404  {object}  APIError  "Short link not found"
+// @Failure      500  {object}  APIError  "Internal server error"
 // @Router       /api/user/urls [delete]
 // @Security     CookieAuth
-func deleteUserLinks(linksService *service.LinksService) gin.HandlerFunc {
+func deleteUserLinks(linksService *service.LinksService, auditor *audit.AuditorShortURLOperationManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		body, err := c.GetRawData()
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, invalidRequest(err), true)
 			return
 		}
 
@@ -280,21 +318,48 @@ func deleteUserLinks(linksService *service.LinksService) gin.HandlerFunc {
 		err = json.Unmarshal(body, &request)
 
 		if err != nil {
-			c.String(http.StatusBadRequest, err.Error())
+			respondError(c, invalidRequest(err), true)
 			return
 		}
 
-		err = linksService.DeleteUserLinks(request, c)
+		deleted, claims, err := linksService.DeleteUserLinks(request, c)
 
 		if err != nil {
-			c.String(http.StatusInternalServerError, err.Error())
+			auditFailure(c, auditor, audit.ShortURLActionDelete, "", err)
+			respondError(c, err, true)
 			return
 		}
 
+		// Only links which were actually marked deleted are audited
+		for _, shortcut := range deleted {
+			event := audit.NewRequestPayload(c, audit.ShortURLActionDelete, claims.UserID)
+			event.Shortcut = shortcut
+			event.Status = http.StatusAccepted
+			auditor.AuditNotify(event)
+		}
+
 		c.Status(http.StatusAccepted)
 	}
 }
 
+// auditCreated notifies auditor about the created or already existing link.
+// The event keeps the URL submitted by the client, status 409 tells it conflicted with the existing link.
+func auditCreated(c *gin.Context, auditor *audit.AuditorShortURLOperationManager, action audit.ShortURLAction, userID string, submittedURL string, link *model.Link, status int) {
+	event := audit.NewRequestPayload(c, action, userID)
+	event.URL = submittedURL
+	event.Shortcut = link.Shortcut
+	event.Status = status
+	auditor.AuditNotify(event)
+}
+
+// auditFailure notifies auditor about the rejected operation with status of the error response.
+func auditFailure(c *gin.Context, auditor *audit.AuditorShortURLOperationManager, action audit.ShortURLAction, fullURL string, err error) {
+	event := audit.NewRequestPayload(c, action, "")
+	event.URL = fullURL
+	event.Status = newAPIError(err).Status
+	auditor.AuditNotify(event)
+}
+
 // RegisterLinksRoutes registers all URL shortener routes to the provided Gin engine.
 // It sets up the following endpoints:
 //   - GET /:shortcut - redirect to full URL
@@ -315,10 +380,10 @@ func RegisterLinksRoutes(router *gin.Engine, linksService *service.LinksService,
 
 	router.POST("/", createLink(linksService, auditor))
 	router.POST("/api/shorten", createLinkWithJSONAPI(linksService, auditor))
-	router.POST("/api/shorten/batch", createLinkBatch(linksService))
+	router.POST("/api/shorten/batch", createLinkBatch(linksService, auditor))
 
 	router.GET("/api/user/urls", getUserLinks(linksService, authService))
-	router.DELETE("/api/user/urls", deleteUserLinks(linksService))
+	router.DELETE("/api/user/urls", deleteUserLinks(linksService, auditor))
 
 	// router.GET("/api/public/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
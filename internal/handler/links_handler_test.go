@@ -1,23 +1,45 @@
 package handler
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
 	"github.com/Alexey-zaliznuak/shortener/internal/config"
-	"github.com/Alexey-zaliznuak/shortener/internal/handler/audit"
+	"github.com/Alexey-zaliznuak/shortener/internal/handler/middleware"
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/ratelimit"
 	"github.com/Alexey-zaliznuak/shortener/internal/service"
+	"github.com/Alexey-zaliznuak/shortener/internal/service/urlcheck"
 	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func generateRandomString() string {
@@ -79,9 +101,8 @@ func Test_links_createLink(t *testing.T) {
 	}
 
 	client := resty.New()
-	router := NewRouter()
-
 	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
 	var db *sql.DB
 	var err error
 
@@ -155,21 +176,38 @@ func Test_links_createLinkWithJSONAPI(t *testing.T) {
 				notEmptyResponse:    true,
 			},
 		},
+		{
+			name:        "Create link to private address",
+			requestBody: `{"url": "http://10.0.0.1/admin"}`,
+			want: want{
+				code:                http.StatusUnprocessableEntity,
+				responseBody:        `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"URL points to a private or loopback address","code":"private_address","url":"http://10.0.0.1/admin"}`,
+				responseContentType: "application/problem+json",
+			},
+		},
 		{
 			name:        "Create link with invalid URL",
 			requestBody: `{"url": "not valid link"}`,
 			want: want{
 				code:                http.StatusBadRequest,
-				responseBody:        "create link error: invalid URL: 'not valid link'",
-				responseContentType: "text/plain",
+				responseBody:        `{"type":"about:blank","title":"Bad Request","status":400,"detail":"create link error: invalid URL: 'not valid link'","code":"invalid_url"}`,
+				responseContentType: "application/problem+json",
+			},
+		},
+		{
+			name:        "Create link with malformed JSON",
+			requestBody: `{"url": `,
+			want: want{
+				code:                http.StatusBadRequest,
+				responseBody:        `{"type":"about:blank","title":"Bad Request","status":400,"detail":"unexpected end of JSON input","code":"invalid_request"}`,
+				responseContentType: "application/problem+json",
 			},
 		},
 	}
 
 	client := resty.New()
-	router := NewRouter()
-
 	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
 	var db *sql.DB
 	var err error
 
@@ -220,9 +258,8 @@ func Test_links_CreateAndGet(t *testing.T) {
 		},
 	))
 
-	router := NewRouter()
-
 	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
 	var db *sql.DB
 	var err error
 
@@ -260,3 +297,578 @@ func Test_links_CreateAndGet(t *testing.T) {
 		assert.Equal(t, fullURL, response.Header().Get("Location"))
 	})
 }
+
+type maliciousHostChecker struct {
+	host string
+}
+
+func (c *maliciousHostChecker) Check(ctx context.Context, rawURL string) (urlcheck.Verdict, error) {
+	return urlcheck.Verdict{Malicious: strings.Contains(rawURL, c.host), ThreatType: "malware"}, nil
+}
+
+func Test_links_QuarantinedRedirect(t *testing.T) {
+	client := resty.New().SetRedirectPolicy(resty.RedirectPolicyFunc(
+		func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	))
+
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
+	cfg.DB.DatabaseDSN = ""
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	linksService := service.NewLinksService(r, cfg)
+	linksService.UseURLChecker(&maliciousHostChecker{host: "malware.example"})
+
+	RegisterLinksRoutes(router, linksService, service.NewAuthService(cfg), audit.NewAuditorShortURLOperationManager(), nil)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	fullURL := "https://malware.example/" + generateRandomString()
+
+	response, err := client.R().SetBody(fullURL).Post(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+
+	res := strings.Split(string(response.Body()), "/")
+	response, err = client.R().Get(server.URL + "/" + res[len(res)-1])
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode())
+	assert.Empty(t, response.Header().Get("Location"))
+	assert.Contains(t, response.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, string(response.Body()), fullURL)
+}
+
+func Test_links_ErrorResponses(t *testing.T) {
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
+	cfg.DB.DatabaseDSN = ""
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	RegisterLinksRoutes(router, service.NewLinksService(r, cfg), service.NewAuthService(cfg), audit.NewAuditorShortURLOperationManager(), nil)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := resty.New()
+
+	t.Run("Missing shortcut is not found", func(t *testing.T) {
+		response, err := client.R().Get(server.URL + "/missingShortcut")
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusNotFound, response.StatusCode())
+		assert.Contains(t, response.Header().Get("Content-Type"), "text/plain")
+		assert.Equal(t, "short link not found", string(response.Body()))
+	})
+
+	t.Run("Missing shortcut for JSON client", func(t *testing.T) {
+		response, err := client.R().SetHeader("Accept", "application/json").Get(server.URL + "/missingShortcut")
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusNotFound, response.StatusCode())
+		assert.Contains(t, response.Header().Get("Content-Type"), "application/problem+json")
+		assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"short link not found","code":"not_found"}`, string(response.Body()))
+	})
+
+	t.Run("Plain text endpoint for JSON client", func(t *testing.T) {
+		response, err := client.R().SetHeader("Accept", "application/problem+json").SetBody("not valid link").Post(server.URL)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode())
+		assert.Contains(t, response.Header().Get("Content-Type"), "application/problem+json")
+		assert.Contains(t, string(response.Body()), `"code":"invalid_url"`)
+	})
+
+	t.Run("Body above limit", func(t *testing.T) {
+		response, err := client.R().SetBody("https://example.com/" + strings.Repeat("a", cfg.RequestLimits.MaxBodySize)).Post(server.URL)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode())
+	})
+
+	t.Run("Decompression bomb", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		gw, _ := gzip.NewWriterLevel(body, gzip.BestCompression)
+		_, _ = gw.Write(make([]byte, 2*cfg.RequestLimits.MaxDecompressedSize))
+		require.NoError(t, gw.Close())
+
+		response, err := client.R().
+			SetHeader("Content-Encoding", "gzip").
+			SetHeader("Content-Type", "application/json").
+			SetBody(body.Bytes()).
+			Post(server.URL + "/api/shorten")
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode())
+		assert.Contains(t, string(response.Body()), `"code":"body_too_large"`)
+	})
+
+	t.Run("Unsupported content encoding", func(t *testing.T) {
+		response, err := client.R().SetHeader("Content-Encoding", "deflate").SetBody("https://example.com").Post(server.URL + "/api/shorten")
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, response.StatusCode())
+		assert.Contains(t, string(response.Body()), `"code":"unsupported_encoding"`)
+	})
+}
+
+type recordingAuditor struct {
+	mu     sync.Mutex
+	events []audit.AuditPayload
+}
+
+func (a *recordingAuditor) Audit(payload audit.AuditPayload) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.events = append(a.events, payload)
+	return nil
+}
+
+func (a *recordingAuditor) actions() map[audit.ShortURLAction][]audit.AuditPayload {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	result := make(map[audit.ShortURLAction][]audit.AuditPayload)
+	for _, event := range a.events {
+		result[event.Action] = append(result[event.Action], event)
+	}
+	return result
+}
+
+func Test_links_AuditEvents(t *testing.T) {
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
+	cfg.DB.DatabaseDSN = ""
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	recorder := &recordingAuditor{}
+	auditor := audit.NewAuditorShortURLOperationManager()
+	auditor.UseAuditor(recorder)
+
+	linksService := service.NewLinksService(r, cfg)
+	linksService.UseAuditor(auditor)
+	authService := service.NewAuthService(cfg)
+	authService.UseAuditor(auditor)
+
+	RegisterLinksRoutes(router, linksService, authService, auditor, nil)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := resty.New().SetHeader("User-Agent", "audit-test").SetHeader(audit.RequestIDHeader, "request-1")
+	host := generateRandomString()
+
+	response, err := client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(fmt.Sprintf(`[{"correlation_id": "1", "original_url": "https://%s.com"}]`, host)).
+		Post(server.URL + "/api/shorten/batch")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+
+	// Authorization issued for the batch request is reused for deletion
+	client.SetCookies(response.Cookies())
+
+	require.Len(t, recorder.actions()[audit.ShortURLActionBatchCreate], 1)
+	shortcut := recorder.actions()[audit.ShortURLActionBatchCreate][0].Shortcut
+
+	// Anonymous visitors of the short link get a token, but it is not audited
+	response, err = resty.New().SetRedirectPolicy(resty.NoRedirectPolicy()).R().Get(server.URL + "/" + shortcut)
+	require.Error(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, response.StatusCode())
+	require.NotEmpty(t, response.Cookies())
+
+	// Conflict keeps the submitted URL, not the URL of the existing link
+	submittedURL := fmt.Sprintf("HTTPS://%s.COM", host)
+	response, err = client.R().SetBody(submittedURL).Post(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, response.StatusCode())
+
+	// Repeated deletion marks nothing and is not audited
+	for range 2 {
+		response, err = client.R().SetBody(fmt.Sprintf(`["%s"]`, shortcut)).Delete(server.URL + "/api/user/urls")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusAccepted, response.StatusCode())
+	}
+
+	events := recorder.actions()
+
+	require.Len(t, events[audit.AuthActionTokenIssued], 1)
+	userID := events[audit.AuthActionTokenIssued][0].UserID
+	assert.NotEmpty(t, userID)
+
+	require.Len(t, events[audit.ShortURLActionBatchCreate], 1)
+	created := events[audit.ShortURLActionBatchCreate][0]
+	assert.Equal(t, userID, created.UserID)
+	assert.NotEmpty(t, created.Shortcut)
+	assert.Equal(t, http.StatusCreated, created.Status)
+	assert.Equal(t, "request-1", created.RequestID)
+	assert.Equal(t, "audit-test", created.UserAgent)
+	assert.NotEmpty(t, created.ClientIP)
+
+	require.Len(t, events[audit.ShortURLActionCreate], 1)
+	conflicted := events[audit.ShortURLActionCreate][0]
+	assert.Equal(t, submittedURL, conflicted.URL)
+	assert.Equal(t, created.Shortcut, conflicted.Shortcut)
+	assert.Equal(t, http.StatusConflict, conflicted.Status)
+
+	require.Len(t, events[audit.ShortURLActionDelete], 1)
+	deleted := events[audit.ShortURLActionDelete][0]
+	assert.Equal(t, userID, deleted.UserID)
+	assert.Equal(t, shortcut, deleted.Shortcut)
+	assert.Equal(t, http.StatusAccepted, deleted.Status)
+}
+
+func Test_links_Metrics(t *testing.T) {
+	client := resty.New().SetRedirectPolicy(resty.RedirectPolicyFunc(
+		func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	))
+
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
+	cfg.DB.DatabaseDSN = ""
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	RegisterLinksRoutes(router, service.NewLinksService(r, cfg), service.NewAuthService(cfg), audit.NewAuditorShortURLOperationManager(), nil)
+	RegisterAppHandlerRoutes(router, nil, service.NewHealthService(r))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	created := metrics.LinksCreated.Value()
+	hits := metrics.Redirects.WithLabelValues(metrics.RedirectHit).Value()
+	misses := metrics.Redirects.WithLabelValues(metrics.RedirectMiss).Value()
+
+	response, err := client.R().SetBody(generateRandomURL()).Post(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+
+	res := strings.Split(string(response.Body()), "/")
+
+	response, err = client.R().Get(server.URL + "/" + res[len(res)-1])
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, response.StatusCode())
+
+	response, err = client.R().Get(server.URL + "/" + generateRandomString())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, response.StatusCode())
+
+	assert.Equal(t, created+1, metrics.LinksCreated.Value())
+	assert.Equal(t, hits+1, metrics.Redirects.WithLabelValues(metrics.RedirectHit).Value())
+	assert.Equal(t, misses+1, metrics.Redirects.WithLabelValues(metrics.RedirectMiss).Value())
+
+	response, err = client.R().Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode())
+	assert.Equal(t, metrics.ContentType, response.Header().Get("Content-Type"))
+	assert.Contains(t, string(response.Body()), `shortener_http_requests_total{method="GET",route="/:shortcut",status="307"}`)
+	assert.Contains(t, string(response.Body()), `shortener_http_request_duration_seconds_bucket{method="POST",route="/",le="+Inf"}`)
+}
+
+func Test_links_RateLimit(t *testing.T) {
+	client := resty.New()
+
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	cfg.DB.DatabaseDSN = ""
+	cfg.RateLimit.CreatePerMinute = 1
+	cfg.RateLimit.CreateBurst = 2
+	cfg.RateLimit.MaxBatchSize = 2
+
+	router := NewRouter(cfg)
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	authService := service.NewAuthService(cfg)
+
+	RegisterRateLimits(router, cfg, ratelimit.NewInMemoryRateLimitRepository(), authService)
+	RegisterLinksRoutes(router, service.NewLinksService(r, cfg), authService, audit.NewAuditorShortURLOperationManager(), nil)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		response, err := client.R().SetBody(generateRandomURL()).Post(server.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, response.StatusCode())
+		assert.Equal(t, "2", response.Header().Get(middleware.RateLimitLimitHeader))
+		assert.Equal(t, strconv.Itoa(remaining), response.Header().Get(middleware.RateLimitRemainingHeader))
+	}
+
+	// Both create routes share the budget
+	apiErr := &APIError{}
+	response, err := client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(fmt.Sprintf(`{"url":"%s"}`, generateRandomURL())).
+		SetError(apiErr).
+		Post(server.URL + "/api/shorten")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode())
+	assert.Equal(t, "60", response.Header().Get("Retry-After"))
+	assert.Equal(t, "0", response.Header().Get(middleware.RateLimitRemainingHeader))
+	assert.Equal(t, ErrorCodeRateLimited, apiErr.Code)
+
+	// Other budgets are not affected
+	apiErr = &APIError{}
+	response, err = client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(`[{"correlation_id":"1","original_url":"https://a.example.com"},{"correlation_id":"2","original_url":"https://b.example.com"},{"correlation_id":"3","original_url":"https://c.example.com"}]`).
+		SetError(apiErr).
+		Post(server.URL + "/api/shorten/batch")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode())
+	assert.Equal(t, ErrorCodeBatchTooLarge, apiErr.Code)
+}
+
+func Test_app_Health(t *testing.T) {
+	client := resty.New()
+
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
+	cfg.DB.DatabaseDSN = ""
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	auditFailing := atomic.Bool{}
+	healthService := service.NewHealthService(r)
+	healthService.AddCheck("audit", func(ctx context.Context) error {
+		if auditFailing.Load() {
+			return errors.New("audit receiver is unavailable")
+		}
+		return nil
+	})
+
+	RegisterAppHandlerRoutes(router, nil, healthService)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	response, err := client.R().Get(server.URL + "/ping")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode())
+
+	response, err = client.R().Get(server.URL + "/healthz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode())
+	assert.JSONEq(t, `{"status":"up"}`, string(response.Body()))
+
+	readiness := &model.Readiness{}
+	response, err = client.R().SetResult(readiness).Get(server.URL + "/readyz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode())
+	assert.Equal(t, model.HealthStatusReady, readiness.Status)
+	assert.Equal(t, model.HealthStatusUp, readiness.Components["storage"].Status)
+	assert.Equal(t, model.HealthStatusUp, readiness.Components["audit"].Status)
+
+	auditFailing.Store(true)
+
+	readiness = &model.Readiness{}
+	response, err = client.R().SetError(readiness).Get(server.URL + "/readyz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode())
+	assert.Equal(t, model.HealthStatusNotReady, readiness.Status)
+	assert.Equal(t, model.HealthStatusDown, readiness.Components["audit"].Status)
+	assert.NotContains(t, string(response.Body()), "audit receiver is unavailable")
+
+	auditFailing.Store(false)
+	healthService.StartShutdown()
+
+	readiness = &model.Readiness{}
+	response, err = client.R().SetError(readiness).Get(server.URL + "/readyz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode())
+	assert.Equal(t, model.HealthStatusDown, readiness.Components["shutdown"].Status)
+
+	// Liveness does not depend on readiness
+	response, err = client.R().Get(server.URL + "/healthz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode())
+}
+
+func Test_links_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	otel.SetTracerProvider(tracerProvider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	router := NewRouter(cfg)
+	cfg.DB.DatabaseDSN = ""
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	RegisterLinksRoutes(router, service.NewLinksService(r, cfg), service.NewAuthService(cfg), audit.NewAuditorShortURLOperationManager(), nil)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	response, err := resty.New().R().
+		SetHeader("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01").
+		SetBody(generateRandomURL()).
+		Post(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+
+	require.NoError(t, tracerProvider.Shutdown(context.Background()))
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	serverSpan, ok := spans["POST /"]
+	require.True(t, ok)
+	assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	assert.Contains(t, serverSpan.Attributes(), attribute.Int("http.response.status_code", http.StatusCreated))
+
+	serviceSpan, ok := spans["LinksService.CreateLink"]
+	require.True(t, ok)
+	assert.Equal(t, serverSpan.SpanContext().TraceID(), serviceSpan.SpanContext().TraceID())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), serviceSpan.Parent().SpanID())
+	assert.Equal(t, codes.Unset, serviceSpan.Status().Code)
+}
+
+func Test_links_RequestID(t *testing.T) {
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	cfg.DB.DatabaseDSN = ""
+	router := NewRouter(cfg)
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	recorder := &recordingAuditor{}
+	auditor := audit.NewAuditorShortURLOperationManager()
+	auditor.UseAuditor(recorder)
+
+	linksService := service.NewLinksService(r, cfg)
+	linksService.UseAuditor(auditor)
+
+	RegisterLinksRoutes(router, linksService, service.NewAuthService(cfg), auditor, nil)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		requestID string
+		honored   bool
+	}{
+		{name: "Honored", requestID: "client-request-1", honored: true},
+		{name: "Generated", requestID: ""},
+		{name: "Too long", requestID: strings.Repeat("a", 129)},
+		{name: "Not printable", requestID: "id with spaces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := resty.New().R().SetHeader(audit.RequestIDHeader, tt.requestID).SetBody(generateRandomURL()).Post(server.URL)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusCreated, response.StatusCode())
+
+			requestID := response.Header().Get(audit.RequestIDHeader)
+
+			if tt.honored {
+				assert.Equal(t, tt.requestID, requestID)
+			} else {
+				_, err := uuid.Parse(requestID)
+				assert.NoError(t, err)
+			}
+
+			events := recorder.actions()[audit.ShortURLActionCreate]
+			require.NotEmpty(t, events)
+			assert.Equal(t, requestID, events[len(events)-1].RequestID)
+		})
+	}
+}
+
+func Test_links_RequestLogging(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	defer func() { logger.Log = previous }()
+
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	cfg.DB.DatabaseDSN = ""
+	cfg.RequestLogging.MaxBodySize = 16
+
+	router := NewRouter(cfg)
+
+	r, err := link.NewLinksRepository(context.Background(), cfg, nil)
+	require.NoError(t, err)
+
+	RegisterLinksRoutes(router, service.NewLinksService(r, cfg), service.NewAuthService(cfg), audit.NewAuditorShortURLOperationManager(), nil)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := resty.New()
+
+	response, err := client.R().SetHeader("Content-Type", "text/plain").SetBody(generateRandomURL()).Post(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+	require.NotEmpty(t, response.Cookies())
+
+	token := response.Cookies()[0].Value
+	client.SetCookies(response.Cookies())
+
+	response, err = client.R().
+		SetHeader(audit.RequestIDHeader, "logged-request").
+		SetHeader("Authorization", "Bearer secret-token").
+		SetHeader("Content-Type", "text/plain").
+		SetBody(generateRandomURL()).
+		Post(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+
+	entries := logs.FilterMessage("Request handled").FilterField(zap.String("requestID", "logged-request")).All()
+	require.Len(t, entries, 1)
+
+	fields := entries[0].ContextMap()
+
+	assert.Equal(t, int64(http.StatusCreated), fields["status"])
+	assert.Equal(t, "/", fields["route"])
+
+	requestHeaders, ok := fields["requestHeaders"].(map[string]string)
+	require.True(t, ok)
+	assert.Equal(t, "[REDACTED]", requestHeaders["Authorization"])
+	assert.Contains(t, requestHeaders["Cookie"], "Authorization=[REDACTED]")
+	assert.NotContains(t, requestHeaders["Cookie"], token)
+
+	assert.Len(t, fields["requestBody"], 16)
+	assert.Equal(t, true, fields["requestBodyTruncated"])
+	assert.Len(t, fields["responseBody"], 16)
+
+	t.Run("Sampling", func(t *testing.T) {
+		cfg.RequestLogging.SampleRatio = 0
+		router := NewRouter(cfg)
+		RegisterLinksRoutes(router, service.NewLinksService(r, cfg), service.NewAuthService(cfg), audit.NewAuditorShortURLOperationManager(), nil)
+
+		server := httptest.NewServer(router)
+		defer server.Close()
+
+		response, err := client.R().SetHeader(audit.RequestIDHeader, "not-sampled").SetBody(generateRandomURL()).Post(server.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, response.StatusCode())
+
+		assert.Zero(t, logs.FilterField(zap.String("requestID", "not-sampled")).Len())
+	})
+}
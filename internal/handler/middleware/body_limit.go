@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// ErrRequestBodyTooLarge is returned by request bodies exceeding the size limit.
+	ErrRequestBodyTooLarge = errors.New("request body is too large")
+	// ErrDecompressionRatioExceeded is returned by compressed request bodies inflating too much.
+	ErrDecompressionRatioExceeded = errors.New("request body decompression ratio is too high")
+)
+
+// limitedReadCloser fails reads after more than limit bytes, unlike io.LimitReader
+// the handler gets an error instead of a silently truncated body.
+type limitedReadCloser struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, ErrRequestBodyTooLarge
+	}
+
+	// One more byte is read to tell a body of exactly limit bytes from a larger one
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+
+	if r.remaining < 0 {
+		return n + int(r.remaining), ErrRequestBodyTooLarge
+	}
+
+	return n, err
+}
+
+func newLimitedReadCloser(body io.ReadCloser, limit int64) *limitedReadCloser {
+	return &limitedReadCloser{ReadCloser: body, remaining: limit}
+}
+
+// BodyLimit limits the size of request bodies read by handlers. Limits are keyed by "METHOD route",
+// other routes use defaultLimit, non-positive limits disable the check. Requests with larger
+// Content-Length are rejected before the handler, reads of larger bodies fail with ErrRequestBodyTooLarge.
+func BodyLimit(limits map[string]int, defaultLimit int, reject func(c *gin.Context, err error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, ok := limits[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			limit = defaultLimit
+		}
+
+		if limit <= 0 || c.Request.Body == nil {
+			return
+		}
+
+		if c.Request.ContentLength > int64(limit) {
+			reject(c, ErrRequestBodyTooLarge)
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = newLimitedReadCloser(c.Request.Body, int64(limit))
+	}
+}
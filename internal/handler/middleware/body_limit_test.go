@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimit(t *testing.T) {
+	router := gin.New()
+
+	router.Use(BodyLimit(map[string]int{"POST /batch": 16}, 8, func(c *gin.Context, err error) {
+		c.String(http.StatusRequestEntityTooLarge, err.Error())
+	}))
+
+	echo := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if errors.Is(err, ErrRequestBodyTooLarge) {
+			c.String(http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	}
+	router.POST("/", echo)
+	router.POST("/batch", echo)
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		contentLength bool
+		wantStatus    int
+	}{
+		{"within default limit", "/", "12345678", true, http.StatusOK},
+		{"declared length above limit", "/", "123456789", true, http.StatusRequestEntityTooLarge},
+		{"streamed body above limit", "/", "123456789", false, http.StatusRequestEntityTooLarge},
+		{"route limit", "/batch", "1234567890123456", false, http.StatusOK},
+		{"above route limit", "/batch", "12345678901234567", false, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(test.body)
+			if !test.contentLength {
+				// Unknown length like chunked requests
+				body = io.MultiReader(body)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, test.path, body)
+			if !test.contentLength {
+				request.ContentLength = -1
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, test.wantStatus, recorder.Code)
+			if test.wantStatus == http.StatusOK {
+				assert.Equal(t, test.body, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -2,106 +2,407 @@ package middleware
 
 import (
 	"compress/gzip"
+	"errors"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
 	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"github.com/andybalholm/brotli"
 	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/zap"
 )
 
-var acceptedContentTypesForCompressing = strings.Join(([]string{"text/html", "application/json"}), "")
+// Content codings supported for requests and responses.
+const (
+	EncodingGzip     = "gzip"
+	EncodingBrotli   = "br"
+	EncodingZstd     = "zstd"
+	EncodingIdentity = "identity"
+)
+
+var (
+	// ErrInvalidContentEncoding is passed to the rejection handler for bodies which can not be decoded.
+	ErrInvalidContentEncoding = errors.New("request body does not match its content encoding")
+	// ErrUnsupportedContentEncoding is passed to the rejection handler for unknown request encodings.
+	ErrUnsupportedContentEncoding = errors.New("unsupported content encoding")
+)
+
+// preferredEncodings lists response encodings in the order chosen for equal q-values.
+var preferredEncodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// compressibleContentTypes are compressed in addition to text/*, *+json and *+xml types.
+var compressibleContentTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"image/svg+xml":          true,
+}
+
+// encoder compresses a response, it is reset and reused between requests.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	EncodingGzip: {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+	EncodingBrotli: {New: func() any {
+		// Higher levels are too slow for dynamic responses
+		return brotli.NewWriterLevel(nil, 4)
+	}},
+	EncodingZstd: {New: func() any {
+		w, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+		if err != nil {
+			logger.Log.Error("Zstd encoder is not created", zap.Error(err))
+			return nil
+		}
+		return w
+	}},
+}
+
+// negotiateEncoding returns the supported encoding with the highest q-value in Accept-Encoding,
+// empty if the response must not be compressed.
+func negotiateEncoding(values []string) string {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(part, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			if name == "" {
+				continue
+			}
+			if name == "x-gzip" {
+				name = EncodingGzip
+			}
+
+			weight := 1.0
+
+			for _, param := range strings.Split(params, ";") {
+				key, value, ok := strings.Cut(param, "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+					continue
+				}
+
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				weight = q
+			}
+
+			if name == "*" {
+				wildcard = weight
+			} else {
+				weights[name] = weight
+			}
+		}
+	}
+
+	best, bestWeight := "", 0.0
+
+	for _, encoding := range preferredEncodings {
+		weight, ok := weights[encoding]
+		if !ok {
+			weight = wildcard
+		}
 
+		if weight > bestWeight {
+			best, bestWeight = encoding, weight
+		}
+	}
+
+	return best
+}
+
+// isCompressibleContentType reports whether responses of the type benefit from compression.
+func isCompressibleContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		compressibleContentTypes[mediaType]
+}
+
+// responseWriterWithCompress buffers the beginning of the body until minSize bytes are written,
+// then decides on compression by the response status, type and size.
 type responseWriterWithCompress struct {
 	gin.ResponseWriter
-	gzipWriter *gzip.Writer
+	request  *http.Request
+	encoding string
+	minSize  int
+
+	buffer  []byte
+	decided bool
+	encoder encoder
 }
 
 func (w *responseWriterWithCompress) Write(b []byte) (int, error) {
-	return w.gzipWriter.Write(b)
-}
+	if !w.decided {
+		w.buffer = append(w.buffer, b...)
+
+		if len(w.buffer) < w.minSize {
+			return len(b), nil
+		}
+
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
 
-func (w *responseWriterWithCompress) WriteHeader(statusCode int) {
-	w.ResponseWriter.Header().Set("Content-Encoding", "gzip")
-	w.ResponseWriter.WriteHeader(statusCode)
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
 }
 
-func (w *responseWriterWithCompress) Close() error {
-	return w.gzipWriter.Close()
+func (w *responseWriterWithCompress) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
 }
 
-func newResponseWriterWithCompress(w gin.ResponseWriter) *responseWriterWithCompress {
-	return &responseWriterWithCompress{
-		ResponseWriter: w,
-		gzipWriter:     gzip.NewWriter(w),
+// WriteHeaderNow sends headers, so the decision is made without waiting for the body.
+func (w *responseWriterWithCompress) WriteHeaderNow() {
+	if !w.decided {
+		utils.LogErrorWrapper(w.decide())
 	}
+	w.ResponseWriter.WriteHeaderNow()
 }
 
-type compressReader struct {
-	r  io.ReadCloser
-	zr *gzip.Reader
+// Written reports true for buffered bodies, so gin does not override the status.
+func (w *responseWriterWithCompress) Written() bool {
+	return len(w.buffer) > 0 || w.ResponseWriter.Written()
 }
 
-func newCompressReader(r io.ReadCloser) (*compressReader, error) {
-	zr, err := gzip.NewReader(r)
-	if err != nil {
-		return nil, err
+func (w *responseWriterWithCompress) Flush() {
+	if !w.decided {
+		utils.LogErrorWrapper(w.decide())
 	}
 
-	return &compressReader{
-		r:  r,
-		zr: zr,
-	}, nil
-}
+	if w.encoder != nil {
+		utils.LogErrorWrapper(w.encoder.Flush())
+	}
 
-func (c compressReader) Read(p []byte) (n int, err error) {
-	return c.zr.Read(p)
+	w.ResponseWriter.Flush()
 }
 
-func (c *compressReader) Close() error {
-	if err := c.r.Close(); err != nil {
+// decide sets headers of the chosen encoding and writes the buffered body.
+func (w *responseWriterWithCompress) decide() error {
+	w.decided = true
+
+	header := w.Header()
+
+	if header.Get("Content-Type") == "" && len(w.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+
+	if isCompressibleContentType(header.Get("Content-Type")) {
+		header.Add("Vary", "Accept-Encoding")
+
+		if w.shouldCompress() {
+			if e, _ := encoderPools[w.encoding].Get().(encoder); e != nil {
+				e.Reset(w.ResponseWriter)
+				w.encoder = e
+
+				header.Set("Content-Encoding", w.encoding)
+				header.Del("Content-Length")
+			}
+		}
+	}
+
+	buffer := w.buffer
+	w.buffer = nil
+
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buffer)
 		return err
 	}
-	return c.zr.Close()
+
+	_, err := w.ResponseWriter.Write(buffer)
+	return err
 }
 
-func RequestAndResponseGzipCompressing() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var newWriter *responseWriterWithCompress
+func (w *responseWriterWithCompress) shouldCompress() bool {
+	status := w.Status()
+	header := w.Header()
+
+	switch {
+	case w.encoding == "":
+		return false
+	case w.request.Method == http.MethodHead:
+		return false
+	case status < http.StatusOK, status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	case header.Get("Content-Encoding") != "", header.Get("Content-Range") != "":
+		return false
+	case strings.Contains(strings.ToLower(header.Get("Cache-Control")), "no-transform"):
+		return false
+	}
 
-		acceptEncodings := strings.Join(c.Request.Header.Values("Accept-Encoding"), "")
-		contentType := c.Request.Header.Get("Content-Type")
-		supportsGzip := strings.Contains(acceptedContentTypesForCompressing, contentType) && strings.Contains(acceptEncodings, "gzip")
+	return true
+}
 
-		if supportsGzip {
-			newWriter = newResponseWriterWithCompress(c.Writer)
-			c.Writer = newWriter
+// Close writes short bodies and finishes the compressed stream, the encoder is returned to the pool.
+func (w *responseWriterWithCompress) Close() error {
+	var err error
 
-			defer func() { utils.LogErrorWrapper(newWriter.Close()) }()
+	// The body is still buffered only if it is shorter than minSize, it is sent as is
+	if !w.decided && len(w.buffer) > 0 {
+		w.encoding = ""
+		err = w.decide()
+	}
+
+	if w.encoder != nil {
+		err = errors.Join(err, w.encoder.Close())
+
+		w.encoder.Reset(nil)
+		encoderPools[w.encoding].Put(w.encoder)
+		w.encoder = nil
+	}
+
+	return err
+}
+
+// ratioCheckThreshold is the decoded size from which the decompression ratio is checked,
+// small repetitive bodies compress well and are harmless.
+const ratioCheckThreshold = 64 << 10
+
+// zstdMaxWindow limits memory allocated by the zstd decoder for a request.
+const zstdMaxWindow = 8 << 20
+
+// decodeRequestBody returns the reader of the decoded body and its closer.
+func decodeRequestBody(encoding string, body io.Reader) (io.Reader, func() error, error) {
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		r, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, nil, errors.Join(ErrInvalidContentEncoding, err)
+		}
+		return r, r.Close, nil
+	case EncodingBrotli:
+		return brotli.NewReader(body), func() error { return nil }, nil
+	case EncodingZstd:
+		r, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+		if err != nil {
+			return nil, nil, errors.Join(ErrInvalidContentEncoding, err)
 		}
+		return r, func() error { r.Close(); return nil }, nil
+	}
+
+	return nil, nil, ErrUnsupportedContentEncoding
+}
+
+// countingReader counts bytes read from the compressed body.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// readCloserWithDecoder reads the decoded body and closes both the decoder and the original body.
+// Errors of corrupted streams are returned to handlers reading the body. Reads fail when the body
+// inflates more than maxRatio times the compressed bytes read, so bombs are stopped early.
+type readCloserWithDecoder struct {
+	io.Reader
+	body         io.ReadCloser
+	compressed   *countingReader
+	decoded      int64
+	maxRatio     int
+	closeDecoder func() error
+}
+
+func (r *readCloserWithDecoder) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.decoded += int64(n)
+
+	if r.maxRatio > 0 && r.decoded > ratioCheckThreshold && r.decoded > int64(r.maxRatio)*r.compressed.n {
+		return n, ErrDecompressionRatioExceeded
+	}
+
+	return n, err
+}
 
-		contentEncoding := c.Request.Header.Get("Content-Encoding")
-		sendsGzip := strings.Contains(contentEncoding, "gzip")
-		if sendsGzip {
-			compressReader, err := newCompressReader(c.Request.Body)
+func (r *readCloserWithDecoder) Close() error {
+	return errors.Join(r.closeDecoder(), r.body.Close())
+}
 
+// Compression decodes request bodies sent with Content-Encoding gzip, br or zstd and compresses
+// responses with the encoding negotiated by Accept-Encoding. Responses are compressed if their
+// type is textual and the body is at least minSize bytes. Both compressed and decoded bodies are
+// limited to maxDecodedSize bytes and may inflate at most maxRatio times, non-positive values
+// disable the checks. reject writes the response for bodies which can not be decoded.
+func Compression(minSize int, maxDecodedSize int, maxRatio int, reject func(c *gin.Context, err error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if encoding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding"))); encoding != "" && encoding != EncodingIdentity {
+			var body io.ReadCloser = c.Request.Body
+			if maxDecodedSize > 0 {
+				body = newLimitedReadCloser(body, int64(maxDecodedSize))
+			}
+
+			compressed := &countingReader{Reader: body}
+
+			reader, closeDecoder, err := decodeRequestBody(encoding, compressed)
 			if err != nil {
-				newWriter.WriteHeader(http.StatusInternalServerError)
-				newWriter.Write([]byte("Invalid content"))
-				// прошлый ревьювер проигнорировал :(
-				// почему то если ловлю ошибку(намеренно тестировал) получаю
-				// [GIN-debug] [WARNING] Headers were already written. Wanted to override status code 500 with 400
-				// и в логере есть тело {"level":"info"..."body":"unexpected end of JSON input"...}
-				// но если не установлю []byte("Invalid content") то прийдет пустой респонс
-				// после моего лога и лога гина еще приходит
-				// Error #01: flate: closed writer
-				// но фактически все вроде как работает
+				reject(c, err)
+				c.Abort()
 				return
 			}
-			c.Request.Body = compressReader
-			defer func() { utils.LogErrorWrapper(compressReader.Close()) }()
+
+			var decoded io.ReadCloser = &readCloserWithDecoder{
+				Reader:       reader,
+				body:         body,
+				compressed:   compressed,
+				maxRatio:     maxRatio,
+				closeDecoder: closeDecoder,
+			}
+			if maxDecodedSize > 0 {
+				decoded = newLimitedReadCloser(decoded, int64(maxDecodedSize))
+			}
+
+			c.Request.Body = decoded
+			c.Request.Header.Del("Content-Encoding")
+			c.Request.Header.Del("Content-Length")
+			c.Request.ContentLength = -1
 		}
 
+		writer := &responseWriterWithCompress{
+			ResponseWriter: c.Writer,
+			request:        c.Request,
+			encoding:       negotiateEncoding(c.Request.Header.Values("Accept-Encoding")),
+			minSize:        minSize,
+		}
+		c.Writer = writer
+
+		defer func() {
+			c.Writer = writer.ResponseWriter
+			utils.LogErrorWrapper(writer.Close())
+		}()
+
 		c.Next()
 	}
 }
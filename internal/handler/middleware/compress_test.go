@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_negotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"gzip", EncodingGzip},
+		{"x-gzip", EncodingGzip},
+		{"gzip, deflate, br", EncodingBrotli},
+		{"gzip, br, zstd", EncodingZstd},
+		{"br;q=0.5, gzip;q=0.8", EncodingGzip},
+		{"gzip;q=0, br;q=0", ""},
+		{"*", EncodingZstd},
+		{"*;q=0.1, gzip", EncodingGzip},
+		{"zstd;q=0, *", EncodingBrotli},
+		{"identity", ""},
+		{"GZIP;Q=0.5", EncodingGzip},
+		{"gzip;q=invalid", ""},
+		{"deflate", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.acceptEncoding, func(t *testing.T) {
+			assert.Equal(t, test.want, negotiateEncoding([]string{test.acceptEncoding}))
+		})
+	}
+}
+
+func newCompressionRouter(minSize int, maxDecodedSize int, maxRatio int) *gin.Engine {
+	router := gin.New()
+
+	router.Use(Compression(minSize, maxDecodedSize, maxRatio, func(c *gin.Context, err error) {
+		c.String(http.StatusBadRequest, err.Error())
+	}))
+
+	router.GET("/json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"result": strings.Repeat("a", 2048)})
+	})
+	router.GET("/short", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"result": "a"})
+	})
+	router.GET("/binary", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", bytes.Repeat([]byte{1}, 2048))
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	return router
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	var reader io.Reader
+
+	switch encoding {
+	case EncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		reader = r
+	case EncodingBrotli:
+		reader = brotli.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		r, err := zstd.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		defer r.Close()
+		reader = r
+	default:
+		return string(body)
+	}
+
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	return string(decoded)
+}
+
+func TestCompression_Response(t *testing.T) {
+	router := newCompressionRouter(1024, 0, 0)
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{"gzip", "/json", "gzip", EncodingGzip},
+		{"brotli", "/json", "gzip;q=0.5, br", EncodingBrotli},
+		{"zstd", "/json", "zstd", EncodingZstd},
+		{"not accepted", "/json", "", ""},
+		{"shorter than minimum", "/short", "gzip", ""},
+		{"not compressible type", "/binary", "gzip", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Repeated requests reuse pooled encoders
+			for range 2 {
+				request := httptest.NewRequest(http.MethodGet, test.path, nil)
+				request.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, request)
+
+				require.Equal(t, http.StatusOK, recorder.Code)
+				assert.Equal(t, test.wantEncoding, recorder.Header().Get("Content-Encoding"))
+
+				body := decode(t, test.wantEncoding, recorder.Body.Bytes())
+				if test.path == "/json" {
+					assert.Contains(t, body, strings.Repeat("a", 2048))
+					assert.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+				}
+			}
+		})
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/empty", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Content-Encoding"))
+	assert.Empty(t, recorder.Body.Bytes())
+}
+
+func TestCompression_Request(t *testing.T) {
+	router := newCompressionRouter(0, 1<<20, 100)
+
+	gzipBody := &bytes.Buffer{}
+	gw := gzip.NewWriter(gzipBody)
+	_, _ = gw.Write([]byte("https://example.com"))
+	require.NoError(t, gw.Close())
+
+	zstdBody := &bytes.Buffer{}
+	zw, err := zstd.NewWriter(zstdBody)
+	require.NoError(t, err)
+	_, _ = zw.Write([]byte("https://example.com"))
+	require.NoError(t, zw.Close())
+
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		wantStatus int
+		wantBody   string
+	}{
+		{"gzip", EncodingGzip, gzipBody.Bytes(), http.StatusOK, "https://example.com"},
+		{"zstd", EncodingZstd, zstdBody.Bytes(), http.StatusOK, "https://example.com"},
+		{"identity", EncodingIdentity, []byte("https://example.com"), http.StatusOK, "https://example.com"},
+		{"invalid gzip", EncodingGzip, []byte("https://example.com"), http.StatusBadRequest, ErrInvalidContentEncoding.Error()},
+		{"unsupported", "deflate", []byte("https://example.com"), http.StatusBadRequest, ErrUnsupportedContentEncoding.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(test.body))
+			request.Header.Set("Content-Encoding", test.encoding)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, test.wantStatus, recorder.Code)
+			assert.Contains(t, recorder.Body.String(), test.wantBody)
+		})
+	}
+}
+
+// bomb returns size zero bytes compressed with the encoding.
+func bomb(t *testing.T, encoding string, size int) []byte {
+	body := &bytes.Buffer{}
+
+	var w io.WriteCloser
+	switch encoding {
+	case EncodingGzip:
+		w, _ = gzip.NewWriterLevel(body, gzip.BestCompression)
+	case EncodingBrotli:
+		w = brotli.NewWriterLevel(body, 5)
+	case EncodingZstd:
+		zw, err := zstd.NewWriter(body, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		require.NoError(t, err)
+		w = zw
+	}
+
+	chunk := make([]byte, 64<<10)
+	for written := 0; written < size; written += len(chunk) {
+		_, err := w.Write(chunk)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	return body.Bytes()
+}
+
+func TestCompression_Bombs(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxDecodedSize int
+		maxRatio       int
+		wantErr        error
+	}{
+		{"decoded size limit", 1 << 20, 0, ErrRequestBodyTooLarge},
+		{"ratio limit", 0, 100, ErrDecompressionRatioExceeded},
+	}
+
+	for _, test := range tests {
+		router := newCompressionRouter(0, test.maxDecodedSize, test.maxRatio)
+
+		for _, encoding := range []string{EncodingGzip, EncodingBrotli, EncodingZstd} {
+			t.Run(test.name+" "+encoding, func(t *testing.T) {
+				body := bomb(t, encoding, 16<<20)
+				require.Less(t, len(body), 64<<10)
+
+				request := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(body))
+				request.Header.Set("Content-Encoding", encoding)
+
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, request)
+
+				assert.Equal(t, http.StatusBadRequest, recorder.Code)
+				assert.Contains(t, recorder.Body.String(), test.wantErr.Error())
+			})
+		}
+	}
+
+	// Compressed bodies are limited as well, incompressible data is larger than its gzip stream
+	random := make([]byte, 4096)
+	_, _ = rand.Read(random)
+
+	compressed := &bytes.Buffer{}
+	gw := gzip.NewWriter(compressed)
+	_, _ = gw.Write(random)
+	require.NoError(t, gw.Close())
+
+	router := newCompressionRouter(0, 1024, 0)
+
+	request := httptest.NewRequest(http.MethodPost, "/echo", compressed)
+	request.Header.Set("Content-Encoding", EncodingGzip)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), ErrRequestBodyTooLarge.Error())
+}
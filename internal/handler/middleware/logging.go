@@ -2,76 +2,208 @@ package middleware
 
 import (
 	"bytes"
-	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
-	"io"
-
 	"github.com/Alexey-zaliznuak/shortener/internal/logger"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders are logged without values, cookies are logged by names.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+}
+
+// sensitiveBodyFields matches values of JSON fields with credentials, like secrets of webhooks
+// returned on creation and rotation. Values cut by the body size limit are matched too.
+var sensitiveBodyFields = regexp.MustCompile(`("secret"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+
+// limitedBuffer keeps the first limit bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if available := b.limit - b.Len(); len(p) > available {
+		b.truncated = true
+		p = p[:max(available, 0)]
+	}
+	return b.Buffer.Write(p)
+}
+
+// teeReadCloser copies the body read by handlers, so it is not buffered entirely.
+type teeReadCloser struct {
+	io.ReadCloser
+	copy io.Writer
+}
+
+func (r *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	_, _ = r.copy.Write(p[:n])
+	return n, err
+}
+
 type responseWriterWithBody struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	maxBodySize int
+	body        *limitedBuffer
+	size        int
 }
 
-func (w responseWriterWithBody) Write(b []byte) (int, error) {
-	w.body.Write(b)
+func (w *responseWriterWithBody) Write(b []byte) (int, error) {
+	w.capture(b)
 	return w.ResponseWriter.Write(b)
 }
 
-func RequestLogging() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-		requestID, err := uuid.NewRandom()
+func (w *responseWriterWithBody) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
 
-		if err != nil {
-			logger.Log.Error(fmt.Errorf("UUID generation error: %w", err).Error())
-		}
+// capture counts written bytes and keeps the beginning of textual bodies.
+func (w *responseWriterWithBody) capture(b []byte) {
+	w.size += len(b)
 
-		var reqBody []byte
+	if w.maxBodySize <= 0 {
+		return
+	}
 
-		if c.Request.Body != nil {
-			reqBody, _ = io.ReadAll(c.Request.Body)
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBody))
+	if w.body == nil {
+		if !isLoggableContentType(w.Header().Get("Content-Type")) {
+			w.maxBodySize = 0
+			return
 		}
+		w.body = &limitedBuffer{limit: w.maxBodySize}
+	}
+
+	_, _ = w.body.Write(b)
+}
 
-		headers := make(map[string]string, len(c.Request.Header))
-		for key, vals := range c.Request.Header {
-			headers[key] = strings.Join(vals, ",")
+// RequestLogging logs handled requests with redacted credentials. Requests failed with 5xx are always
+// logged, others with probability sampleRatio. Textual bodies are logged up to maxBodySize bytes, 0 disables it.
+func RequestLogging(sampleRatio float64, maxBodySize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		var requestBody *limitedBuffer
+
+		if maxBodySize > 0 && c.Request.Body != nil && isLoggableContentType(c.GetHeader("Content-Type")) {
+			requestBody = &limitedBuffer{limit: maxBodySize}
+			c.Request.Body = &teeReadCloser{ReadCloser: c.Request.Body, copy: requestBody}
 		}
 
-		logger.Log.Info("Request received",
-			zap.String("method", c.Request.Method),
-			zap.String("URL", c.Request.URL.String()),
-			zap.String("requestID", requestID.String()),
-			zap.Any("headers", headers),
-			zap.String("body", string(reqBody)),
-		)
-
-		customWriter := &responseWriterWithBody{body: &bytes.Buffer{}, ResponseWriter: c.Writer}
-		c.Writer = customWriter
+		writer := &responseWriterWithBody{ResponseWriter: c.Writer, maxBodySize: maxBodySize}
+		c.Writer = writer
+
 		c.Next()
 
-		latency := time.Since(start)
 		status := c.Writer.Status()
-		respHeaders := c.Writer.Header()
-		respBody := customWriter.body.String()
 
-		logger.Log.Info("Response sent",
+		if status < http.StatusInternalServerError && rand.Float64() >= sampleRatio {
+			return
+		}
+
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("route", c.FullPath()),
+			zap.String("clientIP", c.ClientIP()),
 			zap.Int("status", status),
-			zap.String("statusText", http.StatusText(status)),
-			zap.Duration("latency", latency),
-			zap.Any("headers", respHeaders),
-			zap.String("body", respBody),
-
-			// если написать c.Writer.Size() будет другой результат, очень маленький(например 10 при не сжатых 180)
-			zap.Int("size", customWriter.body.Len()),
-		)
+			zap.Duration("latency", time.Since(start)),
+			// Size before compression, c.Writer.Size() counts compressed bytes
+			zap.Int("size", writer.size),
+			zap.Any("requestHeaders", redactHeaders(c.Request.Header)),
+			zap.Any("responseHeaders", redactHeaders(c.Writer.Header())),
+		}
+
+		if requestBody != nil {
+			fields = append(fields, zap.String("requestBody", redactBody(requestBody.String())), zap.Bool("requestBodyTruncated", requestBody.truncated))
+		}
+
+		if writer.body != nil {
+			fields = append(fields, zap.String("responseBody", redactBody(writer.body.String())), zap.Bool("responseBodyTruncated", writer.body.truncated))
+		}
+
+		log := logger.FromContext(c.Request.Context())
+
+		if status >= http.StatusInternalServerError {
+			log.Error("Request handled", fields...)
+			return
+		}
+
+		log.Info("Request handled", fields...)
+	}
+}
+
+// redactHeaders joins header values and hides credentials and cookie values.
+func redactHeaders(header http.Header) map[string]string {
+	result := make(map[string]string, len(header))
+
+	for key, values := range header {
+		switch {
+		case sensitiveHeaders[key]:
+			result[key] = redactedValue
+		case key == "Cookie":
+			result[key] = redactCookies(strings.Join(values, "; "))
+		case key == "Set-Cookie":
+			redacted := make([]string, 0, len(values))
+			for _, value := range values {
+				name, _, _ := strings.Cut(value, "=")
+				redacted = append(redacted, strings.TrimSpace(name)+"="+redactedValue)
+			}
+			result[key] = strings.Join(redacted, ", ")
+		default:
+			result[key] = strings.Join(values, ",")
+		}
+	}
+
+	return result
+}
+
+// redactCookies keeps names of cookies and hides their values.
+func redactCookies(cookies string) string {
+	parts := strings.Split(cookies, ";")
+
+	for i, part := range parts {
+		name, _, _ := strings.Cut(part, "=")
+		parts[i] = strings.TrimSpace(name) + "=" + redactedValue
 	}
+
+	return strings.Join(parts, "; ")
+}
+
+// redactBody hides values of sensitive JSON fields.
+func redactBody(body string) string {
+	return sensitiveBodyFields.ReplaceAllString(body, `$1"`+redactedValue+`"`)
+}
+
+// isLoggableContentType reports whether bodies of the content type are text.
+func isLoggableContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/x-www-form-urlencoded",
+		mediaType == "application/xml":
+		return true
+	}
+
+	return false
 }
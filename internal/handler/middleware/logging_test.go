@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestLogging_RedactsSecrets(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	defer func() { logger.Log = previous }()
+
+	router := gin.New()
+	router.Use(RequestLogging(1, 1024))
+	router.POST("/webhooks", func(c *gin.Context) {
+		_, _ = io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusCreated, gin.H{"id": "1", "secret": "whsec_response"})
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url": "https://example.com", "secret" : "whsec_request"}`))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+
+	assert.Equal(t, `{"url": "https://example.com", "secret" : "[REDACTED]"}`, fields["requestBody"])
+	assert.Equal(t, `{"id":"1","secret":"[REDACTED]"}`, fields["responseBody"])
+}
+
+func TestRedactBody(t *testing.T) {
+	assert.Equal(t, `{"secret":"[REDACTED]"}`, redactBody(`{"secret":"whsec_\"quoted\""}`))
+	// Values cut by the body size limit
+	assert.Equal(t, `{"id":"1","secret":"[REDACTED]"`, redactBody(`{"id":"1","secret":"whsec_ab`))
+}
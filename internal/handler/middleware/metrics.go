@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests which did not match any route, so random paths do not create new series.
+const unmatchedRoute = "unmatched"
+
+// RequestMetrics counts requests and observes their latency per route template.
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/ratelimit"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Rate limit headers (draft-ietf-httpapi-ratelimit-headers).
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// ErrRateLimited is passed to the rejection handler of limited requests.
+var ErrRateLimited = errors.New("too many requests")
+
+// RateLimitRule applies a budget to a route.
+type RateLimitRule struct {
+	// Budget names buckets of the rule, routes with the same budget share them.
+	Budget string
+	Limit  model.RateLimit
+}
+
+// RateLimitRules holds rules keyed by "METHOD route", they are replaced while requests are served.
+type RateLimitRules struct {
+	rules atomic.Pointer[map[string]RateLimitRule]
+}
+
+// Store replaces all rules.
+func (r *RateLimitRules) Store(rules map[string]RateLimitRule) {
+	r.rules.Store(&rules)
+}
+
+// Get returns the rule of the route.
+func (r *RateLimitRules) Get(route string) (RateLimitRule, bool) {
+	rule, ok := (*r.rules.Load())[route]
+	return rule, ok
+}
+
+// NewRateLimitRules returns RateLimitRules holding rules.
+func NewRateLimitRules(rules map[string]RateLimitRule) *RateLimitRules {
+	r := &RateLimitRules{}
+	r.Store(rules)
+	return r
+}
+
+// RateLimit limits requests with token buckets per client IP and per user. Requests of routes
+// without rules are not limited. userID returns the user of the request, empty for anonymous
+// requests. reject writes the 429 response of limited requests, tokens the rejected request took
+// from other buckets are refunded. Requests are let through if the store fails, so its outage
+// does not stop the service.
+func RateLimit(repository ratelimit.RateLimitRepository, rules *RateLimitRules, userID func(c *gin.Context) string, reject gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, ok := rules.Get(c.Request.Method + " " + c.FullPath())
+		if !ok || rule.Limit.Rate <= 0 {
+			return
+		}
+
+		keys := []string{rule.Budget + ":ip:" + c.ClientIP()}
+		if id := userID(c); id != "" {
+			keys = append(keys, rule.Budget+":user:"+id)
+		}
+
+		var strictest *model.RateLimitResult
+		var taken []string
+
+		for _, key := range keys {
+			result, err := repository.Take(c.Request.Context(), key, rule.Limit)
+			if err != nil {
+				logger.FromContext(c.Request.Context()).Warn("Rate limit check failed", zap.String("budget", rule.Budget), zap.Error(err))
+				continue
+			}
+
+			if result.Allowed {
+				taken = append(taken, key)
+			}
+
+			if strictest == nil || stricter(result, strictest) {
+				strictest = result
+			}
+		}
+
+		if strictest == nil {
+			return
+		}
+
+		c.Header(RateLimitLimitHeader, strconv.Itoa(strictest.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(strictest.Remaining))
+		c.Header(RateLimitResetHeader, strconv.Itoa(ceilSeconds(strictest.Reset)))
+
+		if strictest.Allowed {
+			return
+		}
+
+		metrics.RateLimitedRequests.WithLabelValues(rule.Budget).Inc()
+
+		for _, key := range taken {
+			if err := repository.Refund(c.Request.Context(), key, rule.Limit); err != nil {
+				logger.FromContext(c.Request.Context()).Warn("Rate limit refund failed", zap.String("budget", rule.Budget), zap.Error(err))
+			}
+		}
+
+		c.Header("Retry-After", strconv.Itoa(max(ceilSeconds(strictest.RetryAfter), 1)))
+		reject(c)
+		c.Abort()
+	}
+}
+
+// stricter reports whether a limits the client more than b.
+func stricter(a, b *model.RateLimitResult) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit_RefundsRejectedRequests(t *testing.T) {
+	router := gin.New()
+
+	rules := NewRateLimitRules(map[string]RateLimitRule{
+		"POST /": {Budget: "create", Limit: model.RateLimit{Rate: 1.0 / 60, Burst: 2}},
+	})
+
+	userID := func(c *gin.Context) string { return c.GetHeader("X-User") }
+
+	router.Use(RateLimit(ratelimit.NewInMemoryRateLimitRepository(), rules, userID, func(c *gin.Context) {
+		c.Status(http.StatusTooManyRequests)
+	}))
+	router.POST("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	post := func(ip string, user string) int {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.RemoteAddr = ip + ":1234"
+		request.Header.Set("X-User", user)
+
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response.Code
+	}
+
+	// The user spends the budget from the first address
+	assert.Equal(t, http.StatusOK, post("192.0.2.1", "user"))
+	assert.Equal(t, http.StatusOK, post("192.0.2.1", "user"))
+
+	// The user bucket rejects the request, the token of the second address is refunded
+	assert.Equal(t, http.StatusTooManyRequests, post("192.0.2.2", "user"))
+
+	assert.Equal(t, http.StatusOK, post("192.0.2.2", ""))
+	assert.Equal(t, http.StatusOK, post("192.0.2.2", ""))
+	assert.Equal(t, http.StatusTooManyRequests, post("192.0.2.2", ""))
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// maxRequestIDLength limits identifiers accepted from clients.
+const maxRequestIDLength = 128
+
+// RequestID honors X-Request-ID of the client or generates a new one and returns it in the response.
+// The request context gets the logger with request and trace IDs for downstream logs.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(audit.RequestIDHeader)
+
+		if !isValidRequestID(requestID) {
+			id, err := uuid.NewRandom()
+			if err != nil {
+				logger.Log.Error(fmt.Errorf("UUID generation error: %w", err).Error())
+			}
+			requestID = id.String()
+		}
+
+		// Handlers reading the header get the accepted identifier
+		c.Request.Header.Set(audit.RequestIDHeader, requestID)
+		c.Header(audit.RequestIDHeader, requestID)
+
+		ctx := c.Request.Context()
+		fields := []zap.Field{zap.String("requestID", requestID)}
+
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			fields = append(fields, zap.String("traceID", sc.TraceID().String()))
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request.header.x-request-id", requestID))
+
+		c.Request = c.Request.WithContext(logger.ContextWithLogger(ctx, logger.Log.With(fields...)))
+
+		c.Next()
+	}
+}
+
+// isValidRequestID accepts visible ASCII identifiers, so they can not break log lines or headers.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < '!' || requestID[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
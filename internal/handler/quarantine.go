@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var quarantineWarningPage = template.Must(template.New("quarantine").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex, nofollow">
+<title>Warning: suspicious link</title>
+</head>
+<body>
+<h1>This link may be unsafe</h1>
+<p>The destination of this short link was flagged as malicious. It may try to steal your data or install harmful software.</p>
+<p>Destination: <code>{{.}}</code></p>
+<p><a href="{{.}}" rel="noopener noreferrer nofollow">Continue anyway</a></p>
+</body>
+</html>
+`))
+
+// renderQuarantineWarning responds with an interstitial warning page instead of redirecting to fullURL.
+func renderQuarantineWarning(c *gin.Context, fullURL string) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+	c.Status(http.StatusOK)
+
+	utils.LogErrorWrapper(quarantineWarningPage.Execute(c.Writer, fullURL))
+}
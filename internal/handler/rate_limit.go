@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/handler/middleware"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/ratelimit"
+	"github.com/Alexey-zaliznuak/shortener/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit budgets, routes creating links one by one share the create budget.
+const (
+	rateLimitBudgetCreate   = "create"
+	rateLimitBudgetBatch    = "batch"
+	rateLimitBudgetRedirect = "redirect"
+)
+
+// RegisterRateLimits limits requests creating links and redirects per client IP and per user.
+// It must be called before the routes are registered. The returned rules are replaced on configuration reload.
+func RegisterRateLimits(router *gin.Engine, cfg *config.AppConfig, repository ratelimit.RateLimitRepository, authService *service.AuthService) *middleware.RateLimitRules {
+	rules := middleware.NewRateLimitRules(RateLimitRules(cfg))
+
+	router.Use(middleware.RateLimit(repository, rules, authService.PeekUserID, func(c *gin.Context) {
+		respondError(c, middleware.ErrRateLimited, strings.HasPrefix(c.FullPath(), "/api/"))
+	}))
+
+	return rules
+}
+
+// RateLimitRules returns the rules of the routes by the configured limits.
+func RateLimitRules(cfg *config.AppConfig) map[string]middleware.RateLimitRule {
+	create := middleware.RateLimitRule{Budget: rateLimitBudgetCreate, Limit: perMinute(cfg.RateLimit.CreatePerMinute, cfg.RateLimit.CreateBurst)}
+
+	return map[string]middleware.RateLimitRule{
+		http.MethodPost + " /":            create,
+		http.MethodPost + " /api/shorten": create,
+		http.MethodPost + " /api/shorten/batch": {
+			Budget: rateLimitBudgetBatch,
+			Limit:  perMinute(cfg.RateLimit.BatchPerMinute, cfg.RateLimit.BatchBurst),
+		},
+		http.MethodGet + " /:shortcut": {
+			Budget: rateLimitBudgetRedirect,
+			Limit:  perMinute(cfg.RateLimit.RedirectPerMinute, cfg.RateLimit.RedirectBurst),
+		},
+	}
+}
+
+func perMinute(requests int, burst int) model.RateLimit {
+	return model.RateLimit{Rate: float64(requests) / 60, Burst: burst}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// createWebhook registers a webhook notified about events of the user's links.
+// @Summary      Create webhook
+// @Description  Registers an endpoint receiving events of the user's links. Requests are signed with
+// @Description  X-Shortener-Signature: sha256=HMAC-SHA256(secret, timestamp + "." + body), the secret is returned only once.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        request  body  model.WebhookRequest  true  "Webhook endpoint and events, empty events subscribe to all"
+// @Success      201  {object}  model.Webhook  "Created webhook with secret"
+// @Failure      400  {object}  APIError  "Invalid request"
+// @Failure      401  {object}  APIError  "Authorization required"
+// @Failure      422  {object}  APIError  "URL rejected by policy"
+// @Failure      500  {object}  APIError  "Internal server error"
+// @Router       /api/user/webhooks [post]
+// @Security     CookieAuth
+func createWebhook(webhooksService *service.WebhooksService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request, err := readWebhookRequest(c)
+
+		if err != nil {
+			respondError(c, err, true)
+			return
+		}
+
+		webhook, err := webhooksService.Create(request, c)
+
+		if err != nil {
+			respondError(c, err, true)
+			return
+		}
+
+		c.JSON(http.StatusCreated, webhook)
+	}
+}
+
+// getWebhooks returns webhooks of the current user.
+// @Summary      Get user's webhooks
+// @Description  Returns webhooks of the authenticated user without secrets
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {array}  model.Webhook  "User's webhooks"
+// @Failure      401  {object}  APIError  "Authorization required"
+// @Failure      500  {object}  APIError  "Internal server error"
+// @Router       /api/user/webhooks [get]
+// @Security     CookieAuth
+func getWebhooks(webhooksService *service.WebhooksService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhooks, err := webhooksService.List(c)
+
+		if err != nil {
+			respondError(c, err, true)
+			return
+		}
+
+		c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+// getWebhook returns the webhook of the current user.
+// @Summary      Get webhook
+// @Description  Returns the webhook of the authenticated user without secret
+// @Tags         webhooks
+// @Produce      json
+// @Param        id  path  string  true  "Webhook identifier"
+// @Success      200  {object}  model.Webhook  "Webhook"
+// @Failure      401  {object}  APIError  "Authorization required"
+// @Failure      404  {object}  APIError  "Webhook not found"
+// @Failure      500  {object}  APIError  "Internal server error"
+// @Router       /api/user/webhooks/{id} [get]
+// @Security     CookieAuth
+func getWebhook(webhooksService *service.WebhooksService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhook, err := webhooksService.Get(c.Param("id"), c)
+
+		if err != nil {
+			respondError(c, err, true)
+			return
+		}
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// updateWebhook replaces endpoint and events of the webhook and optionally rotates its secret.
+// @Summary      Update webhook
+// @Description  Replaces endpoint and events of the webhook. With rotate_secret the new secret is returned.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string  true  "Webhook identifier"
+// @Param        request  body  model.WebhookRequest  true  "Webhook endpoint and events"
+// @Success      200  {object}  model.Webhook  "Updated webhook"
+// @Failure      400  {object}  APIError  "Invalid request"
+// @Failure      401  {object}  APIError  "Authorization required"
+// @Failure      404  {object}  APIError  "Webhook not found"
+// @Failure      422  {object}  APIError  "URL rejected by policy"
+// @Failure      500  {object}  APIError  "Internal server error"
+// @Router       /api/user/webhooks/{id} [put]
+// @Security     CookieAuth
+func updateWebhook(webhooksService *service.WebhooksService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request, err := readWebhookRequest(c)
+
+		if err != nil {
+			respondError(c, err, true)
+			return
+		}
+
+		webhook, err := webhooksService.Update(c.Param("id"), request, c)
+
+		if err != nil {
+			respondError(c, err, true)
+			return
+		}
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+// deleteWebhook removes the webhook with its delivery history.
+// @Summary      Delete webhook
+// @Tags         webhooks
+// @Param        id  path  string  true  "Webhook identifier"
+// @Success      204  "Webhook deleted"
+// @Failure      401  {object}  APIError  "Authorization required"
+// @Failure      404  {object}  APIError  "Webhook not found"
+// @Failure      500  {object}  APIError  "Internal server error"
+// @Router       /api/user/webhooks/{id} [delete]
+// @Security     CookieAuth
+func deleteWebhook(webhooksService *service.WebhooksService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := webhooksService.Delete(c.Param("id"), c); err != nil {
+			respondError(c, err, true)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// getWebhookDeliveries returns latest delivery attempts of the webhook.
+// @Summary      Get webhook deliveries
+// @Description  Returns latest delivery attempts of the webhook, newest first
+// @Tags         webhooks
+// @Produce      json
+// @Param        id     path   string  true   "Webhook identifier"
+// @Param        limit  query  int     false  "Maximum number of attempts, 50 by default, up to 100"
+// @Success      200  {array}  model.WebhookDelivery  "Delivery attempts"
+// @Failure      400  {object}  APIError  "Invalid request"
+// @Failure      401  {object}  APIError  "Authorization required"
+// @Failure      404  {object}  APIError  "Webhook not found"
+// @Failure      500  {object}  APIError  "Internal server error"
+// @Router       /api/user/webhooks/{id}/deliveries [get]
+// @Security     CookieAuth
+func getWebhookDeliveries(webhooksService *service.WebhooksService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := 0
+
+		if value := c.Query("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil {
+				respondError(c, invalidRequest(err), true)
+				return
+			}
+		}
+
+		deliveries, err := webhooksService.GetDeliveries(c.Param("id"), limit, c)
+
+		if err != nil {
+			respondError(c, err, true)
+			return
+		}
+
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+func readWebhookRequest(c *gin.Context) (*model.WebhookRequest, error) {
+	body, err := c.GetRawData()
+
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	request := &model.WebhookRequest{}
+
+	if err := json.Unmarshal(body, request); err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	return request, nil
+}
+
+// RegisterWebhooksRoutes registers routes managing webhooks of the current user:
+//   - POST /api/user/webhooks - create webhook
+//   - GET /api/user/webhooks - get all user's webhooks
+//   - GET /api/user/webhooks/:id - get webhook
+//   - PUT /api/user/webhooks/:id - update webhook
+//   - DELETE /api/user/webhooks/:id - delete webhook
+//   - GET /api/user/webhooks/:id/deliveries - get delivery attempts
+func RegisterWebhooksRoutes(router *gin.Engine, webhooksService *service.WebhooksService) {
+	router.POST("/api/user/webhooks", createWebhook(webhooksService))
+	router.GET("/api/user/webhooks", getWebhooks(webhooksService))
+	router.GET("/api/user/webhooks/:id", getWebhook(webhooksService))
+	router.PUT("/api/user/webhooks/:id", updateWebhook(webhooksService))
+	router.DELETE("/api/user/webhooks/:id", deleteWebhook(webhooksService))
+	router.GET("/api/user/webhooks/:id/deliveries", getWebhookDeliveries(webhooksService))
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/webhook"
+	"github.com/Alexey-zaliznuak/shortener/internal/service"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	events   []model.WebhookEvent
+	invalid  int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	signature := service.WebhookSignature(r.secret, req.Header.Get(service.WebhookTimestampHeader), body)
+	if !hmac.Equal([]byte(signature), []byte(req.Header.Get(service.WebhookSignatureHeader))) {
+		r.invalid++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	event := model.WebhookEvent{}
+	if err := json.Unmarshal(body, &event); err == nil && event.Event == req.Header.Get(service.WebhookEventHeader) {
+		r.events = append(r.events, event)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *webhookReceiver) received() []model.WebhookEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]model.WebhookEvent(nil), r.events...)
+}
+
+func Test_webhooks(t *testing.T) {
+	cfg, _ := config.GetConfig(&config.FlagsInitialConfig{})
+	cfg.DB.DatabaseDSN = ""
+	cfg.Server.Address = ""
+	cfg.Policy.BlockPrivateAddresses = false
+	cfg.Webhooks.RetryBaseDelay = 10 * time.Millisecond
+	cfg.Webhooks.AllowPrivateAddresses = true
+
+	linksRepository := link.NewInMemoryLinksRepository(cfg)
+	webhooksRepository := webhook.NewInMemoryWebhooksRepository()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dispatcher := service.NewWebhookDispatcher(webhooksRepository, linksRepository, cfg)
+	go dispatcher.Run(ctx)
+
+	auditor := audit.NewAuditorShortURLOperationManager()
+	auditor.UseAuditor(dispatcher)
+
+	linksService := service.NewLinksService(linksRepository, cfg)
+	linksService.UseAuditor(auditor)
+
+	router := NewRouter(cfg)
+	RegisterLinksRoutes(router, linksService, service.NewAuthService(cfg), auditor, nil)
+	RegisterWebhooksRoutes(router, service.NewWebhooksService(webhooksRepository, cfg))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	receiver := &webhookReceiver{failures: 1}
+	receiverServer := httptest.NewServer(receiver)
+	defer receiverServer.Close()
+
+	// The shortener is reached by 127.0.0.1, so the receiver is not rejected as self referenced
+	receiverURL := strings.Replace(receiverServer.URL, "127.0.0.1", "localhost", 1)
+
+	client := resty.New().SetRedirectPolicy(resty.RedirectPolicyFunc(
+		func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	))
+
+	t.Run("Authorization required", func(t *testing.T) {
+		response, err := client.R().SetBody(fmt.Sprintf(`{"url": "%s"}`, receiverURL)).Post(server.URL + "/api/user/webhooks")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode())
+	})
+
+	response, err := client.R().SetBody(generateRandomURL()).Post(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+	client.SetCookies(response.Cookies())
+
+	res := strings.Split(string(response.Body()), "/")
+	shortcut := res[len(res)-1]
+
+	t.Run("Unknown event", func(t *testing.T) {
+		response, err := client.R().SetBody(fmt.Sprintf(`{"url": "%s", "events": ["explode"]}`, receiverURL)).Post(server.URL + "/api/user/webhooks")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode())
+		assert.Contains(t, string(response.Body()), `"code":"invalid_webhook"`)
+	})
+
+	t.Run("Foreign webhook", func(t *testing.T) {
+		response, err := resty.New().R().Get(server.URL + "/api/user/webhooks/" + "00000000-0000-0000-0000-000000000000")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode())
+
+		response, err = client.R().Get(server.URL + "/api/user/webhooks/00000000-0000-0000-0000-000000000000")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode())
+	})
+
+	created := &model.Webhook{}
+	response, err = client.R().
+		SetBody(fmt.Sprintf(`{"url": "%s", "events": ["follow"]}`, receiverURL)).
+		SetResult(created).
+		Post(server.URL + "/api/user/webhooks")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+	require.True(t, strings.HasPrefix(created.Secret, "whsec_"))
+
+	receiver.mu.Lock()
+	receiver.secret = created.Secret
+	receiver.mu.Unlock()
+
+	t.Run("Secret is not returned", func(t *testing.T) {
+		var webhooks []*model.Webhook
+		response, err := client.R().SetResult(&webhooks).Get(server.URL + "/api/user/webhooks")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode())
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, created.ID, webhooks[0].ID)
+		assert.Empty(t, webhooks[0].Secret)
+	})
+
+	// Not subscribed event is not delivered
+	response, err = client.R().SetBody(generateRandomURL()).Post(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.StatusCode())
+
+	response, err = client.R().Get(server.URL + "/" + shortcut)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, response.StatusCode())
+
+	// The first attempt fails and is retried
+	require.Eventually(t, func() bool {
+		deliveries, err := webhooksRepository.GetDeliveries(created.ID, 10)
+		return err == nil && len(deliveries) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Len(t, receiver.received(), 1)
+
+	event := receiver.received()[0]
+	assert.Equal(t, audit.ShortURLActionGet, event.Event)
+	assert.Equal(t, shortcut, event.Shortcut)
+	assert.Equal(t, http.StatusTemporaryRedirect, event.Status)
+
+	var deliveries []*model.WebhookDelivery
+	response, err = client.R().SetResult(&deliveries).Get(server.URL + "/api/user/webhooks/" + created.ID + "/deliveries")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode())
+	require.Len(t, deliveries, 2)
+
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.True(t, deliveries[0].Success)
+	assert.Equal(t, 1, deliveries[1].Attempt)
+	assert.False(t, deliveries[1].Success)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[1].StatusCode)
+	assert.Equal(t, event.ID, deliveries[0].DeliveryID)
+	assert.Equal(t, deliveries[0].DeliveryID, deliveries[1].DeliveryID)
+
+	t.Run("Rotate secret", func(t *testing.T) {
+		updated := &model.Webhook{}
+		response, err := client.R().
+			SetBody(fmt.Sprintf(`{"url": "%s", "rotate_secret": true}`, receiverURL)).
+			SetResult(updated).
+			Put(server.URL + "/api/user/webhooks/" + created.ID)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode())
+		assert.NotEmpty(t, updated.Secret)
+		assert.NotEqual(t, created.Secret, updated.Secret)
+		assert.Empty(t, updated.Events)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		response, err := client.R().Delete(server.URL + "/api/user/webhooks/" + created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode())
+
+		response, err = client.R().Get(server.URL + "/api/user/webhooks/" + created.ID + "/deliveries")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode())
+	})
+
+	receiver.mu.Lock()
+	assert.Zero(t, receiver.invalid)
+	receiver.mu.Unlock()
+}
@@ -6,15 +6,20 @@ import (
 
 var Log *zap.Logger = zap.NewNop()
 
-func Initialize(level string) error {
-	lvl, err := zap.ParseAtomicLevel(level)
+// level is shared by Log, so SetLevel changes it without rebuilding the logger.
+var level = zap.NewAtomicLevel()
+
+func Initialize(levelName string) error {
+	lvl, err := zap.ParseAtomicLevel(levelName)
 	if err != nil {
 		return err
 	}
 
+	level.SetLevel(lvl.Level())
+
 	cfg := zap.NewProductionConfig()
 
-	cfg.Level = lvl
+	cfg.Level = level
 	cfg.EncoderConfig.TimeKey = "timestamp"
 	cfg.EncoderConfig.MessageKey = "message"
 
@@ -27,3 +32,14 @@ func Initialize(level string) error {
 	Log = configuredLogger
 	return nil
 }
+
+// SetLevel changes the level of the initialized logger.
+func SetLevel(levelName string) error {
+	lvl, err := zap.ParseAtomicLevel(levelName)
+	if err != nil {
+		return err
+	}
+
+	level.SetLevel(lvl.Level())
+	return nil
+}
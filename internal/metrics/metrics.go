@@ -0,0 +1,375 @@
+// Package metrics provides counters, gauges and histograms exposed
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets contains latency buckets in seconds suitable for HTTP requests.
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector writes a metric family in the text exposition format.
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry contains metrics exposed together.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+// register adds the collector or returns the already registered one with the same name,
+// so services created several times, e.g. in tests, share their metrics.
+func (r *Registry) register(c collector) collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.collectors[c.name()]; ok {
+		return existing
+	}
+
+	r.collectors[c.name()] = c
+
+	return c
+}
+
+// replace adds the collector replacing the registered one with the same name.
+func (r *Registry) replace(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors[c.name()] = c
+}
+
+// Write writes all metrics sorted by name.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(collectors, func(a, b collector) int { return strings.Compare(a.name(), b.name()) })
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler returns handler serving the metrics for scraping.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		r.Write(w)
+	})
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// Default is the registry metrics of the service are registered in.
+var Default = NewRegistry()
+
+// desc contains the common part of metric families.
+type desc struct {
+	metricName string
+	help       string
+	metricType string
+	labels     []string
+}
+
+func (d *desc) name() string {
+	return d.metricName
+}
+
+func (d *desc) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.metricName, escapeHelp(d.help), d.metricName, d.metricType)
+}
+
+// labelPairs formats labels as name="value" pairs separated by commas.
+func (d *desc) labelPairs(values []string) string {
+	var b strings.Builder
+
+	for i, label := range d.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(label)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(values[i]))
+		b.WriteByte('"')
+	}
+
+	return b.String()
+}
+
+// checkLabels returns values matching label names, missing values are empty and extra ones are dropped.
+func (d *desc) checkLabels(values []string) []string {
+	if len(values) == len(d.labels) {
+		return values
+	}
+
+	logger.Log.Error("Wrong number of metric label values",
+		zap.String("metric", d.metricName),
+		zap.Int("expected", len(d.labels)),
+		zap.Int("got", len(values)),
+	)
+
+	result := make([]string, len(d.labels))
+	copy(result, values)
+
+	return result
+}
+
+// registered registers c and returns the collector registered under its name if it has the same type.
+func registered[T collector](r *Registry, c T) T {
+	existing, ok := r.register(c).(T)
+	if !ok {
+		logger.Log.Error("Metric is already registered with another type", zap.String("metric", c.name()))
+		return c
+	}
+	return existing
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add increments the counter by n.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current value.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// CounterVec is a family of counters partitioned by label values.
+type CounterVec struct {
+	desc
+	children sync.Map
+}
+
+// WithLabelValues returns the counter for the label values in the order of label names.
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	values = v.checkLabels(values)
+
+	key := strings.Join(values, "\xff")
+
+	if c, ok := v.children.Load(key); ok {
+		return c.(*counterChild).counter
+	}
+
+	c, _ := v.children.LoadOrStore(key, &counterChild{values: values, counter: &Counter{}})
+	return c.(*counterChild).counter
+}
+
+type counterChild struct {
+	values  []string
+	counter *Counter
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.writeHeader(w)
+
+	children := []*counterChild{}
+	v.children.Range(func(_, value any) bool {
+		children = append(children, value.(*counterChild))
+		return true
+	})
+
+	slices.SortFunc(children, func(a, b *counterChild) int { return slices.Compare(a.values, b.values) })
+
+	for _, child := range children {
+		if len(v.labels) == 0 {
+			fmt.Fprintf(w, "%s %d\n", v.metricName, child.counter.Value())
+			continue
+		}
+		fmt.Fprintf(w, "%s{%s} %d\n", v.metricName, v.labelPairs(child.values), child.counter.Value())
+	}
+}
+
+// NewCounterVec registers a counter family in the registry.
+func (r *Registry) NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	return registered(r, &CounterVec{desc: desc{metricName: name, help: help, metricType: "counter", labels: labels}})
+}
+
+// NewCounter registers a counter without labels in the registry.
+func (r *Registry) NewCounter(name string, help string) *Counter {
+	return r.NewCounterVec(name, help).WithLabelValues()
+}
+
+// valueFunc reports value calculated on every scrape.
+type valueFunc struct {
+	desc
+	fn func() float64
+}
+
+func (f *valueFunc) write(w io.Writer) {
+	f.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", f.metricName, formatFloat(f.fn()))
+}
+
+// NewGaugeFunc registers a gauge calculated by fn on every scrape, it replaces the gauge with the same name.
+func (r *Registry) NewGaugeFunc(name string, help string, fn func() float64) {
+	r.replace(&valueFunc{desc: desc{metricName: name, help: help, metricType: "gauge"}, fn: fn})
+}
+
+// NewCounterFunc registers a counter calculated by fn on every scrape, fn must not decrease.
+// It replaces the counter with the same name.
+func (r *Registry) NewCounterFunc(name string, help string, fn func() float64) {
+	r.replace(&valueFunc{desc: desc{metricName: name, help: help, metricType: "counter"}, fn: fn})
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	upperBounds []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe adds the value to the histogram.
+func (h *Histogram) Observe(value float64) {
+	index, _ := slices.BinarySearch(h.upperBounds, value)
+
+	h.mu.Lock()
+	if index < len(h.counts) {
+		h.counts[index]++
+	}
+	h.count++
+	h.sum += value
+	h.mu.Unlock()
+}
+
+// HistogramVec is a family of histograms partitioned by label values.
+type HistogramVec struct {
+	desc
+	buckets  []float64
+	children sync.Map
+}
+
+type histogramChild struct {
+	values    []string
+	histogram *Histogram
+}
+
+// WithLabelValues returns the histogram for the label values in the order of label names.
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	values = v.checkLabels(values)
+
+	key := strings.Join(values, "\xff")
+
+	if h, ok := v.children.Load(key); ok {
+		return h.(*histogramChild).histogram
+	}
+
+	h, _ := v.children.LoadOrStore(key, &histogramChild{
+		values:    values,
+		histogram: &Histogram{upperBounds: v.buckets, counts: make([]uint64, len(v.buckets))},
+	})
+	return h.(*histogramChild).histogram
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.writeHeader(w)
+
+	children := []*histogramChild{}
+	v.children.Range(func(_, value any) bool {
+		children = append(children, value.(*histogramChild))
+		return true
+	})
+
+	slices.SortFunc(children, func(a, b *histogramChild) int { return slices.Compare(a.values, b.values) })
+
+	for _, child := range children {
+		h := child.histogram
+
+		h.mu.Lock()
+		counts := slices.Clone(h.counts)
+		count, sum := h.count, h.sum
+		h.mu.Unlock()
+
+		labels := v.labelPairs(child.values)
+		if labels != "" {
+			labels += ","
+		}
+
+		var cumulative uint64
+		for i, bound := range v.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", v.metricName, labels, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", v.metricName, labels, count)
+
+		suffix := ""
+		if labels != "" {
+			suffix = "{" + strings.TrimSuffix(labels, ",") + "}"
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.metricName, suffix, formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.metricName, suffix, count)
+	}
+}
+
+// NewHistogramVec registers a histogram family with upper bounds of buckets in the registry.
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	return registered(r, &HistogramVec{desc: desc{metricName: name, help: help, metricType: "histogram", labels: labels}, buckets: buckets})
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := registry.NewCounterVec("test_requests_total", "Number of requests.", "route", "status")
+	requests.WithLabelValues("/api/shorten", "201").Add(2)
+	requests.WithLabelValues(`/say "hi"`, "404").Inc()
+
+	// Registering the same name returns the existing metric
+	registry.NewCounterVec("test_requests_total", "Number of requests.", "route", "status").WithLabelValues("/api/shorten", "201").Inc()
+
+	latency := registry.NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.5, 0.1}, "route")
+	latency.WithLabelValues("/").Observe(0.05)
+	latency.WithLabelValues("/").Observe(0.3)
+	latency.WithLabelValues("/").Observe(2)
+
+	registry.NewGaugeFunc("test_connections", "Open connections.", func() float64 { return 3 })
+
+	var output strings.Builder
+	registry.Write(&output)
+
+	assert.Equal(t, strings.Join([]string{
+		"# HELP test_connections Open connections.",
+		"# TYPE test_connections gauge",
+		"test_connections 3",
+		"# HELP test_latency_seconds Latency.",
+		"# TYPE test_latency_seconds histogram",
+		`test_latency_seconds_bucket{route="/",le="0.1"} 1`,
+		`test_latency_seconds_bucket{route="/",le="0.5"} 2`,
+		`test_latency_seconds_bucket{route="/",le="+Inf"} 3`,
+		`test_latency_seconds_sum{route="/"} 2.35`,
+		`test_latency_seconds_count{route="/"} 3`,
+		"# HELP test_requests_total Number of requests.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{route="/api/shorten",status="201"} 3`,
+		`test_requests_total{route="/say \"hi\"",status="404"} 1`,
+		"",
+	}, "\n"), output.String())
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("test_total", "Test.").Inc()
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "test_total 1\n")
+}
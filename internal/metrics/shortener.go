@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"database/sql"
+)
+
+// Redirect results.
+const (
+	RedirectHit  = "hit"
+	RedirectMiss = "miss"
+	RedirectGone = "gone"
+)
+
+// Metrics of the service registered in Default.
+var (
+	HTTPRequests = Default.NewCounterVec(
+		"shortener_http_requests_total",
+		"Number of handled HTTP requests.",
+		"method", "route", "status",
+	)
+	HTTPRequestDuration = Default.NewHistogramVec(
+		"shortener_http_request_duration_seconds",
+		"Latency of handled HTTP requests.",
+		DefaultBuckets,
+		"method", "route",
+	)
+
+	LinksCreated = Default.NewCounter("shortener_links_created_total", "Number of created short links.")
+	LinksDeleted = Default.NewCounter("shortener_links_deleted_total", "Number of short links marked as deleted.")
+	Redirects    = Default.NewCounterVec(
+		"shortener_redirects_total",
+		"Number of redirect requests by result: hit, miss or gone.",
+		"result",
+	)
+	ShortcutCollisions = Default.NewCounter(
+		"shortener_shortcut_collisions_total",
+		"Number of generated shortcuts which were already taken.",
+	)
+
+	AuditFailures = Default.NewCounterVec(
+		"shortener_audit_failures_total",
+		"Number of events rejected by auditors.",
+		"auditor",
+	)
+	AuditDeliveryFailedAttempts = Default.NewCounter(
+		"shortener_audit_delivery_failed_attempts_total",
+		"Number of failed attempts to deliver a batch of audit events to the audit URL.",
+	)
+	AuditDeadLetterEvents = Default.NewCounter(
+		"shortener_audit_dead_letter_events_total",
+		"Number of audit events moved to the dead letter file.",
+	)
+	RateLimitedRequests = Default.NewCounterVec(
+		"shortener_rate_limited_requests_total",
+		"Number of requests rejected by rate limits by budget.",
+		"budget",
+	)
+	WebhookDeliveryFailedAttempts = Default.NewCounter(
+		"shortener_webhook_delivery_failed_attempts_total",
+		"Number of failed attempts to deliver an event to a webhook.",
+	)
+)
+
+// RegisterDBStats registers gauges of the connection pool, they are read on every scrape.
+func RegisterDBStats(db *sql.DB) {
+	Default.NewGaugeFunc("shortener_db_max_open_connections", "Maximum number of open connections to the database.",
+		func() float64 { return float64(db.Stats().MaxOpenConnections) })
+	Default.NewGaugeFunc("shortener_db_open_connections", "Number of established connections both in use and idle.",
+		func() float64 { return float64(db.Stats().OpenConnections) })
+	Default.NewGaugeFunc("shortener_db_in_use_connections", "Number of connections currently in use.",
+		func() float64 { return float64(db.Stats().InUse) })
+	Default.NewGaugeFunc("shortener_db_idle_connections", "Number of idle connections.",
+		func() float64 { return float64(db.Stats().Idle) })
+	Default.NewCounterFunc("shortener_db_wait_count_total", "Number of connections waited for.",
+		func() float64 { return float64(db.Stats().WaitCount) })
+	Default.NewCounterFunc("shortener_db_wait_duration_seconds_total", "Time blocked waiting for a new connection.",
+		func() float64 { return db.Stats().WaitDuration.Seconds() })
+	Default.NewCounterFunc("shortener_db_max_idle_closed_total", "Number of connections closed due to the idle limit.",
+		func() float64 { return float64(db.Stats().MaxIdleClosed) })
+	Default.NewCounterFunc("shortener_db_max_lifetime_closed_total", "Number of connections closed due to the lifetime limit.",
+		func() float64 { return float64(db.Stats().MaxLifetimeClosed) })
+}
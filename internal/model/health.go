@@ -0,0 +1,25 @@
+package model
+
+// Statuses of readiness and its components.
+const (
+	HealthStatusUp       = "up"
+	HealthStatusDown     = "down"
+	HealthStatusReady    = "ready"
+	HealthStatusNotReady = "not_ready"
+)
+
+// Readiness reports whether the service can serve requests.
+type Readiness struct {
+	// Status is "ready" when all components are up and "not_ready" otherwise.
+	Status string `json:"status"`
+	// Components contains statuses of checked components by name.
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// ComponentHealth contains the result of a component check.
+type ComponentHealth struct {
+	// Status is "up" or "down".
+	Status string `json:"status"`
+	// DurationMs contains the duration of the check in milliseconds.
+	DurationMs int64 `json:"duration_ms"`
+}
@@ -2,21 +2,28 @@ package model
 
 // Link represents a URL link model in the URL shortening system.
 type Link struct {
-	// FullURL contains the full unshortened URL of the link.
+	// FullURL contains the full unshortened URL of the link as it was sent by the user.
 	FullURL string `json:"url"`
+	// CanonicalURL contains the normalized form of FullURL used for deduplication.
+	CanonicalURL string `json:"canonicalURL,omitempty"`
 	// Shortcut contains the short representation of the link.
 	Shortcut string `json:"shortcut"`
 	// UserID contains the identifier of the user who created the link.
 	UserID string `json:"userID"`
 	// IsDeleted indicates whether the link is marked as deleted.
 	IsDeleted bool `json:"isDeleted"`
+	// IsQuarantined indicates whether the link is flagged as malicious
+	// and redirect shows a warning page instead.
+	IsQuarantined bool `json:"isQuarantined,omitempty"`
 }
 
 // ToCreateDto converts Link to CreateLinkDto.
 func (l *Link) ToCreateDto() *CreateLinkDto {
 	return &CreateLinkDto{
-		FullURL:  l.FullURL,
-		Shortcut: l.Shortcut,
+		FullURL:       l.FullURL,
+		CanonicalURL:  l.CanonicalURL,
+		Shortcut:      l.Shortcut,
+		IsQuarantined: l.IsQuarantined,
 	}
 }
 
@@ -32,19 +39,33 @@ type GetUserLinksRequestItem struct {
 type CreateLinkDto struct {
 	// FullURL contains the full URL to be shortened.
 	FullURL string `json:"url"`
+	// CanonicalURL contains the normalized form of FullURL.
+	CanonicalURL string `json:"canonicalURL,omitempty"`
 	// Shortcut contains the desired short representation of the link.
 	Shortcut string `json:"shortcut"`
+	// IsQuarantined indicates whether the link is flagged as malicious.
+	IsQuarantined bool `json:"isQuarantined,omitempty"`
 }
 
 // NewLink creates a new link based on the DTO data and user identifier.
 func (dto *CreateLinkDto) NewLink(userID string) *Link {
 	return &Link{
-		FullURL:  dto.FullURL,
-		Shortcut: dto.Shortcut,
-		UserID:   userID,
+		FullURL:       dto.FullURL,
+		CanonicalURL:  dto.CanonicalURL,
+		Shortcut:      dto.Shortcut,
+		UserID:        userID,
+		IsQuarantined: dto.IsQuarantined,
 	}
 }
 
+// DeduplicationURL returns CanonicalURL, or FullURL for links created before normalization.
+func (dto *CreateLinkDto) DeduplicationURL() string {
+	if dto.CanonicalURL != "" {
+		return dto.CanonicalURL
+	}
+	return dto.FullURL
+}
+
 // CreateLinkWithCorrelationIDRequestItem represents a request item
 // for creating a link with a correlation identifier.
 type CreateLinkWithCorrelationIDRequestItem struct {
@@ -61,6 +82,8 @@ type CreateLinkWithCorrelationIDResponseItem struct {
 	Shortcut string `json:"short_url"`
 	// CorrelationID contains the correlation identifier from the request.
 	CorrelationID string `json:"correlation_id"`
+	// Link contains the created link, it is not included in the response.
+	Link *Link `json:"-"`
 }
 
 // CreateShortURLRequest represents a request for creating a short URL.
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimit is a token bucket budget: Burst requests may be sent at once,
+// then tokens are refilled at Rate per second.
+type RateLimit struct {
+	// Rate contains the number of tokens added per second.
+	Rate float64
+	// Burst contains the capacity of the bucket.
+	Burst int
+}
+
+// TokenBucket is the stored state of a client's bucket.
+type TokenBucket struct {
+	// Tokens contains the number of tokens left at UpdatedAt.
+	Tokens float64
+	// UpdatedAt contains the time of the last refill.
+	UpdatedAt time.Time
+}
+
+// RateLimitResult describes the bucket after a request was counted.
+type RateLimitResult struct {
+	// Allowed is false if the bucket was empty.
+	Allowed bool
+	// Limit contains the capacity of the bucket.
+	Limit int
+	// Remaining contains the number of whole tokens left.
+	Remaining int
+	// RetryAfter contains the time until the next token, zero for allowed requests.
+	RetryAfter time.Duration
+	// Reset contains the time until the bucket is full.
+	Reset time.Duration
+}
+
+// NewTokenBucket returns a full bucket.
+func NewTokenBucket(limit RateLimit, now time.Time) *TokenBucket {
+	return &TokenBucket{Tokens: float64(limit.Burst), UpdatedAt: now}
+}
+
+// Take refills the bucket up to now and removes a token if there is one.
+func (b *TokenBucket) Take(limit RateLimit, now time.Time) RateLimitResult {
+	elapsed := now.Sub(b.UpdatedAt).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*limit.Rate)
+	b.UpdatedAt = now
+
+	allowed := b.Tokens >= 1
+	if allowed {
+		b.Tokens--
+	}
+
+	return NewRateLimitResult(limit, b.Tokens, allowed)
+}
+
+// Refund returns a token taken by a request which was rejected by another bucket.
+func (b *TokenBucket) Refund(limit RateLimit) {
+	b.Tokens = math.Min(float64(limit.Burst), b.Tokens+1)
+}
+
+// NewRateLimitResult describes the bucket with tokens left after the request was counted.
+func NewRateLimitResult(limit RateLimit, tokens float64, allowed bool) RateLimitResult {
+	result := RateLimitResult{Limit: limit.Burst, Allowed: allowed}
+
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate)
+
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
@@ -0,0 +1,89 @@
+package model
+
+import "time"
+
+// Webhook represents an endpoint notified about events of the user's links.
+type Webhook struct {
+	// ID contains the identifier of the webhook.
+	ID string `json:"id"`
+	// UserID contains the identifier of the webhook owner.
+	UserID string `json:"-"`
+	// URL contains the endpoint events are posted to.
+	URL string `json:"url"`
+	// Events contains the actions the webhook is subscribed to, empty list subscribes to all of them.
+	Events []string `json:"events"`
+	// Secret contains the key of the signature header, it is returned only on creation and rotation.
+	Secret string `json:"secret,omitempty"`
+	// CreatedAt contains the time of the webhook creation.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribed reports whether the webhook receives the event.
+func (w *Webhook) Subscribed(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithoutSecret returns a copy of the webhook safe to be returned to clients.
+func (w *Webhook) WithoutSecret() *Webhook {
+	result := *w
+	result.Secret = ""
+	return &result
+}
+
+// WebhookRequest represents a request for creating or updating a webhook.
+type WebhookRequest struct {
+	// URL contains the endpoint events are posted to.
+	URL string `json:"url"`
+	// Events contains the actions to subscribe to, empty list subscribes to all of them.
+	Events []string `json:"events"`
+	// RotateSecret replaces the secret on update, the new one is returned in the response.
+	RotateSecret bool `json:"rotate_secret,omitempty"`
+}
+
+// WebhookDelivery represents a single attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	// DeliveryID contains the identifier of the event delivery, it is the same for all attempts.
+	DeliveryID string `json:"delivery_id"`
+	// WebhookID contains the identifier of the webhook.
+	WebhookID string `json:"webhook_id"`
+	// Event contains the delivered action.
+	Event string `json:"event"`
+	// Attempt contains the 1-based number of the attempt.
+	Attempt int `json:"attempt"`
+	// StatusCode contains HTTP status returned by the endpoint, 0 if there was no response.
+	StatusCode int `json:"status_code,omitempty"`
+	// Error contains the reason of the failed attempt.
+	Error string `json:"error,omitempty"`
+	// Success indicates whether the endpoint accepted the event.
+	Success bool `json:"success"`
+	// CreatedAt contains the time of the attempt.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookEvent represents the body posted to webhooks.
+type WebhookEvent struct {
+	// ID contains the identifier of the delivery.
+	ID string `json:"id"`
+	// Event contains the action.
+	Event string `json:"event"`
+	// TS contains unix time of the action.
+	TS int64 `json:"ts"`
+	// Shortcut contains the short link the action was performed on.
+	Shortcut string `json:"shortcut"`
+	// URL contains the full URL of the link.
+	URL string `json:"url,omitempty"`
+	// Status contains HTTP status of the operation result.
+	Status int `json:"status,omitempty"`
+	// RequestID contains the identifier of the request which caused the action.
+	RequestID string `json:"request_id,omitempty"`
+}
@@ -32,7 +32,7 @@ func (repository *AuthRepository) BuildJWTString(UserID string) (string, error)
 	})
 
 	// создаём строку токена
-	tokenString, err := token.SignedString([]byte(repository.config.Auth.TokenSecretKey))
+	tokenString, err := token.SignedString([]byte(repository.config.Auth.TokenSecretKey.Value()))
 	if err != nil {
 		return "", err
 	}
@@ -48,7 +48,7 @@ func (repository *AuthRepository) ParsePayload(token string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrTokenValidation
 		}
-		return []byte(repository.config.Auth.TokenSecretKey), nil
+		return []byte(repository.config.Auth.TokenSecretKey.Value()), nil
 	})
 
 	if err != nil {
@@ -6,6 +6,9 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Alexey-zaliznuak/shortener/internal/config"
@@ -42,13 +45,16 @@ const (
 )
 
 var (
+	// ErrMigrationsPending is returned when the schema is older than the embedded migrations or dirty.
+	ErrMigrationsPending = errors.New("migrations are pending")
+
 	ErrNotFound                       = errors.New("not found")
 	ErrObjectDeleted                  = errors.New("deleted")
 	ErrExecuterNotSupportTransactions = errors.New("chosen repository does not support transactions")
 )
 
 func NewDatabaseConnectionPool(cfg *config.AppConfig) (*sql.DB, error) {
-	db, err := sql.Open("pgx", cfg.DB.DatabaseDSN)
+	db, err := sql.Open("pgx", cfg.DB.DatabaseDSN.Value())
 	if err != nil {
 		return nil, fmt.Errorf("connect database: %w", err)
 	}
@@ -94,6 +100,60 @@ func NewDatabaseConnectionPool(cfg *config.AppConfig) (*sql.DB, error) {
 	return db, nil
 }
 
+// CheckMigrations compares the schema version recorded by migrate with the latest embedded migration.
+func CheckMigrations(ctx context.Context, db *sql.DB) error {
+	latest, err := latestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	var version uint
+	var dirty bool
+
+	err = db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: no version applied, latest %d", ErrMigrationsPending, latest)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("%w: version %d is dirty", ErrMigrationsPending, version)
+	}
+
+	if version < latest {
+		return fmt.Errorf("%w: version %d, latest %d", ErrMigrationsPending, version, latest)
+	}
+
+	return nil
+}
+
+// latestMigrationVersion returns the version of the last embedded migration, e.g. 9 for 000009_create_webhooks.up.sql.
+func latestMigrationVersion() (uint, error) {
+	names, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration '%s' has no version: %w", name, err)
+		}
+
+		latest = max(latest, uint(version))
+	}
+
+	return latest, nil
+}
+
 func NewPostgresErrorClassifier() *PostgresErrorClassifier {
 	return &PostgresErrorClassifier{}
 }
@@ -0,0 +1,16 @@
+package link
+
+import "github.com/Alexey-zaliznuak/shortener/internal/config"
+
+// deduplicationKey returns the key used to find an already shortened copy of fullURL.
+// The second result is false when the scope disables deduplication.
+func deduplicationKey(scope string, fullURL string, userID string) (string, bool) {
+	switch scope {
+	case config.DeduplicationScopeNone:
+		return "", false
+	case config.DeduplicationScopeUser:
+		return userID + ":" + fullURL, true
+	default:
+		return fullURL, true
+	}
+}
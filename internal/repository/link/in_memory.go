@@ -27,7 +27,7 @@ type InMemoryLinkRepository struct {
 	config *config.AppConfig
 }
 
-func (r *InMemoryLinkRepository) GetByShortcut(shortcut string) (*model.Link, error) {
+func (r *InMemoryLinkRepository) GetByShortcut(_ context.Context, shortcut string) (*model.Link, error) {
 	r.shortMu.RLock()
 	l, ok := r.shortStorage[shortcut]
 	r.shortMu.RUnlock()
@@ -41,7 +41,7 @@ func (r *InMemoryLinkRepository) GetByShortcut(shortcut string) (*model.Link, er
 	return l, database.ErrNotFound
 }
 
-func (r *InMemoryLinkRepository) GetByUserID(userID string) ([]*model.GetUserLinksRequestItem, error) {
+func (r *InMemoryLinkRepository) GetByUserID(_ context.Context, userID string) ([]*model.GetUserLinksRequestItem, error) {
 	var result []*model.GetUserLinksRequestItem
 
 	r.shortMu.RLock()
@@ -55,9 +55,15 @@ func (r *InMemoryLinkRepository) GetByUserID(userID string) ([]*model.GetUserLin
 	return result, nil
 }
 
-func (r *InMemoryLinkRepository) GetByFullURL(url string) (*model.Link, error) {
+func (r *InMemoryLinkRepository) GetByFullURL(url string, userID string) (*model.Link, error) {
+	key, ok := deduplicationKey(r.config.DB.DeduplicationScope, url, userID)
+
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+
 	r.fullMu.RLock()
-	l, ok := r.fullStorage[url]
+	l, ok := r.fullStorage[key]
 	r.fullMu.RUnlock()
 
 	if ok {
@@ -66,8 +72,8 @@ func (r *InMemoryLinkRepository) GetByFullURL(url string) (*model.Link, error) {
 	return l, database.ErrNotFound
 }
 
-func (r *InMemoryLinkRepository) Create(link *model.CreateLinkDto, UserID string, executer database.Executer) (*model.Link, bool, error) {
-	l, err := r.GetByFullURL(link.FullURL)
+func (r *InMemoryLinkRepository) Create(_ context.Context, link *model.CreateLinkDto, UserID string, executer database.Executer) (*model.Link, bool, error) {
+	l, err := r.GetByFullURL(link.DeduplicationURL(), UserID)
 
 	if err != database.ErrNotFound {
 		if err != nil {
@@ -83,14 +89,18 @@ func (r *InMemoryLinkRepository) Create(link *model.CreateLinkDto, UserID string
 	r.shortStorage[link.Shortcut] = newLink
 	r.shortMu.Unlock()
 
-	r.fullMu.Lock()
-	r.fullStorage[link.FullURL] = newLink
-	r.fullMu.Unlock()
+	if key, ok := deduplicationKey(r.config.DB.DeduplicationScope, link.DeduplicationURL(), UserID); ok {
+		r.fullMu.Lock()
+		r.fullStorage[key] = newLink
+		r.fullMu.Unlock()
+	}
 
 	return newLink, true, nil
 }
 
-func (r *InMemoryLinkRepository) DeleteUserLinks(shortcuts []string, userID string) error {
+func (r *InMemoryLinkRepository) DeleteUserLinks(_ context.Context, shortcuts []string, userID string) ([]string, error) {
+	var deleted []string
+
 	for _, shortcut := range shortcuts {
 		r.shortMu.Lock() // Используем Lock вместо RLock, т.к. изменяем данные
 
@@ -98,16 +108,37 @@ func (r *InMemoryLinkRepository) DeleteUserLinks(shortcuts []string, userID stri
 
 		if !ok {
 			r.shortMu.Unlock()
-			return database.ErrNotFound
+			return deleted, database.ErrNotFound
 		}
 
-		if link.UserID == userID {
+		if link.UserID == userID && !link.IsDeleted {
 			link.IsDeleted = true
+			deleted = append(deleted, shortcut)
 		}
 
 		r.shortMu.Unlock()
 	}
 
+	return deleted, nil
+}
+
+func (r *InMemoryLinkRepository) Quarantine(_ context.Context, shortcut string, _ database.Executer) error {
+	r.shortMu.Lock()
+	defer r.shortMu.Unlock()
+
+	link, ok := r.shortStorage[shortcut]
+
+	if !ok {
+		return database.ErrNotFound
+	}
+
+	link.IsQuarantined = true
+
+	return nil
+}
+
+// Ping always succeeds, the storage is in the process memory.
+func (r *InMemoryLinkRepository) Ping(_ context.Context) error {
 	return nil
 }
 
@@ -133,7 +164,7 @@ func (r *InMemoryLinkRepository) LoadStoredData() error {
 	}
 
 	for _, link := range storedData {
-		r.Create(link.ToCreateDto(), "", nil)
+		r.Create(context.Background(), link.ToCreateDto(), link.UserID, nil)
 	}
 
 	logger.Log.Info(fmt.Sprintf("Restored urls: %d", len(storedData)))
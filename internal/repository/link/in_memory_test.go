@@ -1,6 +1,7 @@
 package link
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -18,7 +19,7 @@ func BenchmarkCreate(b *testing.B) {
 
 		require.NoError(b, err)
 
-		repo.Create(&model.CreateLinkDto{FullURL: fmt.Sprintf("http://example.com/%s", u.String()), Shortcut: u.String()}, u.String(), nil)
+		repo.Create(context.Background(), &model.CreateLinkDto{FullURL: fmt.Sprintf("http://example.com/%s", u.String()), Shortcut: u.String()}, u.String(), nil)
 	}
 }
 
@@ -31,7 +32,7 @@ func BenchmarkGetByShortcut(b *testing.B) {
 		u, _ := uuid.NewRandom()
 		shortcut := u.String()
 		shortcuts[i] = shortcut
-		repo.Create(&model.CreateLinkDto{
+		repo.Create(context.Background(), &model.CreateLinkDto{
 			FullURL:  fmt.Sprintf("http://example.com/%s", shortcut),
 			Shortcut: shortcut,
 		}, u.String(), nil)
@@ -39,7 +40,7 @@ func BenchmarkGetByShortcut(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		repo.GetByShortcut(shortcuts[i%1000])
+		repo.GetByShortcut(context.Background(), shortcuts[i%1000])
 	}
 }
 
@@ -48,11 +49,13 @@ func BenchmarkGetByFullURL(b *testing.B) {
 
 	// Подготовка данных
 	urls := make([]string, 1000)
+	userIDs := make([]string, 1000)
 	for i := range 1000 {
 		u, _ := uuid.NewRandom()
 		fullURL := fmt.Sprintf("http://example.com/%s", u.String())
 		urls[i] = fullURL
-		repo.Create(&model.CreateLinkDto{
+		userIDs[i] = u.String()
+		repo.Create(context.Background(), &model.CreateLinkDto{
 			FullURL:  fullURL,
 			Shortcut: u.String(),
 		}, u.String(), nil)
@@ -60,7 +63,7 @@ func BenchmarkGetByFullURL(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		repo.GetByFullURL(urls[i%1000])
+		repo.GetByFullURL(urls[i%1000], userIDs[i%1000])
 	}
 }
 
@@ -71,7 +74,7 @@ func BenchmarkGetByUserID(b *testing.B) {
 	userID := uuid.New().String()
 	for range 100 {
 		u, _ := uuid.NewRandom()
-		repo.Create(&model.CreateLinkDto{
+		repo.Create(context.Background(), &model.CreateLinkDto{
 			FullURL:  fmt.Sprintf("http://example.com/%s", u.String()),
 			Shortcut: u.String(),
 		}, userID, nil)
@@ -79,7 +82,7 @@ func BenchmarkGetByUserID(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		repo.GetByUserID(userID)
+		repo.GetByUserID(context.Background(), userID)
 	}
 }
 
@@ -91,7 +94,7 @@ func BenchmarkCreateConcurrent(b *testing.B) {
 			u, err := uuid.NewRandom()
 			require.NoError(b, err)
 
-			repo.Create(&model.CreateLinkDto{
+			repo.Create(context.Background(), &model.CreateLinkDto{
 				FullURL:  fmt.Sprintf("http://example.com/%s", u.String()),
 				Shortcut: u.String(),
 			}, u.String(), nil)
@@ -108,7 +111,7 @@ func BenchmarkGetByShortcutConcurrent(b *testing.B) {
 		u, _ := uuid.NewRandom()
 		shortcut := u.String()
 		shortcuts[i] = shortcut
-		repo.Create(&model.CreateLinkDto{
+		repo.Create(context.Background(), &model.CreateLinkDto{
 			FullURL:  fmt.Sprintf("http://example.com/%s", shortcut),
 			Shortcut: shortcut,
 		}, u.String(), nil)
@@ -118,8 +121,54 @@ func BenchmarkGetByShortcutConcurrent(b *testing.B) {
 	b.RunParallel(func(pb *testing.PB) {
 		i := 0
 		for pb.Next() {
-			repo.GetByShortcut(shortcuts[i%1000])
+			repo.GetByShortcut(context.Background(), shortcuts[i%1000])
 			i++
 		}
 	})
 }
+
+func TestInMemoryCreateDeduplicationScope(t *testing.T) {
+	tests := []struct {
+		name             string
+		scope            string
+		sameUserCreated  bool
+		otherUserCreated bool
+	}{
+		{name: "global", scope: config.DeduplicationScopeGlobal, sameUserCreated: false, otherUserCreated: false},
+		{name: "default is global", scope: "", sameUserCreated: false, otherUserCreated: false},
+		{name: "per user", scope: config.DeduplicationScopeUser, sameUserCreated: false, otherUserCreated: true},
+		{name: "none", scope: config.DeduplicationScopeNone, sameUserCreated: true, otherUserCreated: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.AppConfig{}
+			cfg.DB.DeduplicationScope = test.scope
+			repo := NewInMemoryLinksRepository(cfg)
+
+			fullURL := "http://example.com/dedup"
+
+			first, created, err := repo.Create(context.Background(), &model.CreateLinkDto{FullURL: fullURL, Shortcut: "first"}, "user-a", nil)
+			require.NoError(t, err)
+			require.True(t, created)
+
+			same, created, err := repo.Create(context.Background(), &model.CreateLinkDto{FullURL: fullURL, Shortcut: "same"}, "user-a", nil)
+			require.NoError(t, err)
+			require.Equal(t, test.sameUserCreated, created)
+			if !created {
+				require.Equal(t, first.Shortcut, same.Shortcut)
+			}
+
+			other, created, err := repo.Create(context.Background(), &model.CreateLinkDto{FullURL: fullURL, Shortcut: "other"}, "user-b", nil)
+			require.NoError(t, err)
+			require.Equal(t, test.otherUserCreated, created)
+			if created {
+				require.Equal(t, "other", other.Shortcut)
+
+				links, err := repo.GetByUserID(context.Background(), "user-b")
+				require.NoError(t, err)
+				require.Len(t, links, 1)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Alexey-zaliznuak/shortener/internal/config"
@@ -16,9 +17,15 @@ import (
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
 	"github.com/Alexey-zaliznuak/shortener/internal/utils"
 	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+const tracerName = "github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+
 type PostgreSQLLinksRepository struct {
 	db     *sql.DB
 	table  string
@@ -26,31 +33,33 @@ type PostgreSQLLinksRepository struct {
 	ctx    context.Context
 }
 
-func (r *PostgreSQLLinksRepository) GetByShortcut(shortcut string) (*model.Link, error) {
+func (r *PostgreSQLLinksRepository) GetByShortcut(ctx context.Context, shortcut string) (*model.Link, error) {
 	result := &model.Link{}
 
-	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
-	defer cancel()
-
-	row := r.db.QueryRowContext(
-		ctx,
-		fmt.Sprintf(
-			`
-			SELECT url, shortcut, is_deleted
+	query := fmt.Sprintf(
+		`
+			SELECT url, COALESCE(canonical_url, ''), shortcut, is_deleted, is_quarantined
 			FROM %s
 			WHERE shortcut = $1
 			`,
-			r.table,
-		),
-		shortcut,
+		r.table,
 	)
 
-	err := row.Scan(&result.FullURL, &result.Shortcut, &result.IsDeleted)
+	ctx, span := r.startQuery(ctx, "SELECT", query)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := r.db.QueryRowContext(ctx, query, shortcut)
+
+	err := row.Scan(&result.FullURL, &result.CanonicalURL, &result.Shortcut, &result.IsDeleted, &result.IsQuarantined)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, database.ErrNotFound
 		}
+		recordError(span, err)
 		return nil, err
 	}
 
@@ -61,26 +70,28 @@ func (r *PostgreSQLLinksRepository) GetByShortcut(shortcut string) (*model.Link,
 	return result, nil
 }
 
-func (r *PostgreSQLLinksRepository) GetByUserID(userID string) ([]*model.GetUserLinksRequestItem, error) {
+func (r *PostgreSQLLinksRepository) GetByUserID(ctx context.Context, userID string) ([]*model.GetUserLinksRequestItem, error) {
 	result := []*model.GetUserLinksRequestItem{}
 
-	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
-	defer cancel()
-
-	rows, err := r.db.QueryContext(
-		ctx,
-		fmt.Sprintf(
-			`
+	query := fmt.Sprintf(
+		`
 			SELECT url, shortcut
 			FROM %s
 			WHERE userID = $1
 			`,
-			r.table,
-		),
-		userID,
+		r.table,
 	)
 
+	ctx, span := r.startQuery(ctx, "SELECT", query)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+
 	if err != nil {
+		recordError(span, err)
 		return nil, err
 	}
 
@@ -98,6 +109,7 @@ func (r *PostgreSQLLinksRepository) GetByUserID(userID string) ([]*model.GetUser
 	}
 
 	if err := rows.Err(); err != nil {
+		recordError(span, err)
 		return nil, err
 	}
 
@@ -107,21 +119,24 @@ func (r *PostgreSQLLinksRepository) GetByUserID(userID string) ([]*model.GetUser
 func (r *PostgreSQLLinksRepository) getAll() ([]*model.Link, error) {
 	result := []*model.Link{}
 
-	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
-	defer cancel()
-
-	rows, err := r.db.QueryContext(
-		ctx,
-		fmt.Sprintf(
-			`
-			SELECT url, shortcut, userID
+	query := fmt.Sprintf(
+		`
+			SELECT url, COALESCE(canonical_url, ''), shortcut, userID, is_quarantined
 			FROM %s
 			`,
-			r.table,
-		),
+		r.table,
 	)
 
+	ctx, span := r.startQuery(r.ctx, "SELECT", query)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query)
+
 	if err != nil {
+		recordError(span, err)
 		return nil, err
 	}
 
@@ -129,7 +144,7 @@ func (r *PostgreSQLLinksRepository) getAll() ([]*model.Link, error) {
 
 	for rows.Next() {
 		l := &model.Link{}
-		err = rows.Scan(&l.FullURL, &l.Shortcut, &l.UserID)
+		err = rows.Scan(&l.FullURL, &l.CanonicalURL, &l.Shortcut, &l.UserID, &l.IsQuarantined)
 		if err != nil {
 			logger.Log.Error(fmt.Sprintf("row scanning failing: %s", err.Error()))
 			continue
@@ -146,7 +161,7 @@ func (r *PostgreSQLLinksRepository) getAll() ([]*model.Link, error) {
 }
 
 // Will modify shortcut if find link with same full url
-func (r *PostgreSQLLinksRepository) Create(link *model.CreateLinkDto, UserID string, executer database.Executer) (*model.Link, bool, error) {
+func (r *PostgreSQLLinksRepository) Create(ctx context.Context, link *model.CreateLinkDto, UserID string, executer database.Executer) (*model.Link, bool, error) {
 	var exec database.Executer = r.db
 	oldShortcut := link.Shortcut
 
@@ -154,49 +169,124 @@ func (r *PostgreSQLLinksRepository) Create(link *model.CreateLinkDto, UserID str
 		exec = executer
 	}
 
-	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
-	defer cancel()
-
 	// TODO: with precompiled queries
-	res, err := r.QueryRowContextWithRetry(ctx, fmt.Sprintf(
-		`INSERT INTO %s (url, shortcut, userID)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (url) DO UPDATE SET shortcut = links.shortcut
-			RETURNING %s.url, %s.shortcut, %s.userID;
+	query := fmt.Sprintf(
+		`INSERT INTO %s (url, canonical_url, shortcut, userID, dedup_key, is_quarantined)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (dedup_key) DO UPDATE SET shortcut = links.shortcut
+			RETURNING %s.url, COALESCE(%s.canonical_url, ''), %s.shortcut, %s.userID, %s.is_quarantined;
 			`,
-		r.table, r.table, r.table, r.table,
-	),
+		r.table, r.table, r.table, r.table, r.table, r.table,
+	)
+
+	ctx, span := r.startQuery(ctx, "INSERT", query)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.QueryRowContextWithRetry(ctx, query,
 		exec,
 		link.FullURL,
+		link.DeduplicationURL(),
 		link.Shortcut,
 		UserID,
+		r.deduplicationKey(link.DeduplicationURL(), UserID),
+		link.IsQuarantined,
 	)
 
 	if err != nil {
+		recordError(span, err)
 		return link.NewLink(UserID), false, err
 	}
 
 	newLink := &model.Link{}
-	res.Scan(&newLink.FullURL, &newLink.Shortcut, &newLink.UserID)
+
+	err = res.Scan(&newLink.FullURL, &newLink.CanonicalURL, &newLink.Shortcut, &newLink.UserID, &newLink.IsQuarantined)
+	if err != nil {
+		recordError(span, err)
+		return link.NewLink(UserID), false, err
+	}
 
 	return newLink, oldShortcut == newLink.Shortcut, nil
 }
 
-func (r *PostgreSQLLinksRepository) DeleteUserLinks(shortcuts []string, userID string) error {
+func (r *PostgreSQLLinksRepository) DeleteUserLinks(ctx context.Context, shortcuts []string, userID string) ([]string, error) {
 	if len(shortcuts) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
-	defer cancel()
-
 	query := fmt.Sprintf(
-		`UPDATE %s SET is_deleted = TRUE WHERE shortcut = ANY($1) AND userID = $2`,
+		`UPDATE %s SET is_deleted = TRUE WHERE shortcut = ANY($1) AND userID = $2 AND NOT is_deleted RETURNING shortcut`,
 		r.table,
 	)
 
-	_, err := r.db.ExecContext(ctx, query, pq.Array(shortcuts), userID)
-	return err
+	ctx, span := r.startQuery(ctx, "UPDATE", query)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(shortcuts), userID)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deleted []string
+
+	for rows.Next() {
+		var shortcut string
+
+		if err := rows.Scan(&shortcut); err != nil {
+			recordError(span, err)
+			return nil, err
+		}
+
+		deleted = append(deleted, shortcut)
+	}
+
+	if err := rows.Err(); err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+func (r *PostgreSQLLinksRepository) Quarantine(ctx context.Context, shortcut string, executer database.Executer) error {
+	var exec database.Executer = r.db
+
+	if executer != nil {
+		exec = executer
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET is_quarantined = TRUE WHERE shortcut = $1`, r.table)
+
+	ctx, span := r.startQuery(ctx, "UPDATE", query)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := exec.ExecContext(ctx, query, shortcut)
+
+	if err != nil {
+		recordError(span, err)
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return database.ErrNotFound
+	}
+
+	return nil
+}
+
+// Ping checks the connection to the database.
+func (r *PostgreSQLLinksRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
 }
 
 func (r *PostgreSQLLinksRepository) LoadStoredData() error {
@@ -228,21 +318,24 @@ func (r *PostgreSQLLinksRepository) LoadStoredData() error {
 	}
 
 	for _, link := range storedData {
-		_, err := r.GetByShortcut(link.Shortcut)
+		_, err := r.GetByShortcut(r.ctx, link.Shortcut)
 		if err != nil {
 			if errors.Is(err, database.ErrNotFound) {
 				res, err := r.QueryRowContextWithRetry(
 					context.Background(),
 					fmt.Sprintf(
-						`INSERT INTO %s (url, shortcut, userID)
-						VALUES ($1, $2, $3)
-						ON CONFLICT (url) DO NOTHING
+						`INSERT INTO %s (url, canonical_url, shortcut, userID, dedup_key, is_quarantined)
+						VALUES ($1, $2, $3, $4, $5, $6)
+						ON CONFLICT (dedup_key) DO NOTHING
 						RETURNING %s.url, %s.shortcut;
 					`, r.table, r.table, r.table),
 					tx,
 					link.FullURL,
+					link.ToCreateDto().DeduplicationURL(),
 					link.Shortcut,
 					link.UserID,
+					r.deduplicationKey(link.ToCreateDto().DeduplicationURL(), link.UserID),
+					link.IsQuarantined,
 				)
 
 				if err != nil {
@@ -312,10 +405,11 @@ func (r *PostgreSQLLinksRepository) QueryRowContextWithRetry(ctx context.Context
 			return row, nil
 		}
 
+		lastErr = err
 		classification := classifier.Classify(err)
 
 		if classification == database.NonRetriable {
-			fmt.Printf("Непредвиденная ошибка: %v\n", err)
+			logger.Log.Error(fmt.Sprintf("Non-retriable query error: %s", err.Error()))
 			return nil, err
 		}
 	}
@@ -323,6 +417,31 @@ func (r *PostgreSQLLinksRepository) QueryRowContextWithRetry(ctx context.Context
 	return nil, fmt.Errorf("операция прервана после %d попыток: %w", maxRetries, lastErr)
 }
 
+// startQuery starts the client span of the query, it is named by the operation and the table.
+func (r *PostgreSQLLinksRepository) startQuery(ctx context.Context, operation string, query string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, operation+" "+r.table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.collection.name", r.table),
+			attribute.String("db.query.text", strings.Join(strings.Fields(query), " ")),
+		),
+	)
+}
+
+// recordError adds the exception event to the span and marks it failed.
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// deduplicationKey returns dedup_key column value, NULL never conflicts with other rows.
+func (r *PostgreSQLLinksRepository) deduplicationKey(fullURL string, userID string) sql.NullString {
+	key, ok := deduplicationKey(r.config.DB.DeduplicationScope, fullURL, userID)
+	return sql.NullString{String: key, Valid: ok}
+}
+
 func (r *PostgreSQLLinksRepository) GetTransactionExecuter(ctx context.Context, opts *sql.TxOptions) (database.TransactionExecuter, error) {
 	return r.db.BeginTx(ctx, opts)
 }
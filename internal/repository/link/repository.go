@@ -9,11 +9,18 @@ import (
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
 )
 
+// LinkRepository stores links. Context of lookups and changes carries
+// deadline and trace of the request they are made for.
 type LinkRepository interface {
-	GetByShortcut(shortcut string) (*model.Link, error)
-	GetByUserID(userID string) ([]*model.GetUserLinksRequestItem, error)
-	Create(link *model.CreateLinkDto, userID string, executer database.Executer) (*model.Link, bool, error)
-	DeleteUserLinks(shortcuts []string, userID string) error
+	GetByShortcut(ctx context.Context, shortcut string) (*model.Link, error)
+	GetByUserID(ctx context.Context, userID string) ([]*model.GetUserLinksRequestItem, error)
+	Create(ctx context.Context, link *model.CreateLinkDto, userID string, executer database.Executer) (*model.Link, bool, error)
+	// DeleteUserLinks marks links of the user as deleted and returns shortcuts of the marked links.
+	DeleteUserLinks(ctx context.Context, shortcuts []string, userID string) ([]string, error)
+	// Quarantine flags the link, executer allows to see links created in the same transaction.
+	Quarantine(ctx context.Context, shortcut string, executer database.Executer) error
+	// Ping checks that the storage is available.
+	Ping(ctx context.Context) error
 	LoadStoredData() error
 	SaveInStorage() error
 	GetTransactionExecuter(ctx context.Context, opts *sql.TxOptions) (database.TransactionExecuter, error)
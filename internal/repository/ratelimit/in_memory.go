@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+)
+
+type inMemoryBucket struct {
+	bucket *model.TokenBucket
+	// expiresAt contains the time the bucket becomes full, so it may be removed.
+	expiresAt time.Time
+}
+
+type InMemoryRateLimitRepository struct {
+	buckets map[string]*inMemoryBucket
+	now     func() time.Time
+
+	mu sync.Mutex
+}
+
+func (r *InMemoryRateLimitRepository) Take(_ context.Context, key string, limit model.RateLimit) (*model.RateLimitResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+
+	stored, ok := r.buckets[key]
+	if !ok {
+		stored = &inMemoryBucket{bucket: model.NewTokenBucket(limit, now)}
+		r.buckets[key] = stored
+	}
+
+	result := stored.bucket.Take(limit, now)
+	stored.expiresAt = now.Add(result.Reset)
+
+	return &result, nil
+}
+
+func (r *InMemoryRateLimitRepository) Refund(_ context.Context, key string, limit model.RateLimit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.buckets[key]; ok {
+		stored.bucket.Refund(limit)
+	}
+
+	return nil
+}
+
+func (r *InMemoryRateLimitRepository) Run(ctx context.Context) {
+	sweepEvery(ctx, r.sweep)
+}
+
+// sweep removes full buckets, they are recreated full on the next request.
+func (r *InMemoryRateLimitRepository) sweep() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+
+	for key, stored := range r.buckets {
+		if !stored.expiresAt.After(now) {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+func NewInMemoryRateLimitRepository() *InMemoryRateLimitRepository {
+	return &InMemoryRateLimitRepository{
+		buckets: make(map[string]*inMemoryBucket),
+		now:     time.Now,
+	}
+}
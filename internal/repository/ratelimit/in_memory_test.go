@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryRateLimitRepository_Take(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := NewInMemoryRateLimitRepository()
+	repo.now = func() time.Time { return now }
+
+	limit := model.RateLimit{Rate: 1, Burst: 2}
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		result, err := repo.Take(context.Background(), "create:ip:127.0.0.1", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := repo.Take(context.Background(), "create:ip:127.0.0.1", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 2*time.Second, result.Reset)
+
+	// Other keys have their own buckets
+	result, err = repo.Take(context.Background(), "create:ip:127.0.0.2", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	now = now.Add(1500 * time.Millisecond)
+
+	result, err = repo.Take(context.Background(), "create:ip:127.0.0.1", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// Refunded token is taken again
+	require.NoError(t, repo.Refund(context.Background(), "create:ip:127.0.0.1", limit))
+
+	result, err = repo.Take(context.Background(), "create:ip:127.0.0.1", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Buckets become full and are removed
+	now = now.Add(sweepInterval)
+
+	_, err = repo.Take(context.Background(), "create:ip:127.0.0.3", limit)
+	require.NoError(t, err)
+
+	repo.sweep()
+	assert.Len(t, repo.buckets, 1)
+}
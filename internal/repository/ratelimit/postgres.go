@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"go.uber.org/zap"
+)
+
+// PostgreSQLRateLimitRepository keeps buckets in the rate_limits table. A bucket is refilled and taken
+// by a single statement with the database clock, so instances share budgets without explicit transactions.
+type PostgreSQLRateLimitRepository struct {
+	db *sql.DB
+}
+
+// takeQuery creates the bucket without the taken token or refills the existing one and takes a token
+// when there is one. Denied requests leave the row unchanged, the refill is computed from updated_at
+// again on the next request, and the refilled tokens are read by the second part of the query.
+// The bucket is full and may be removed expires_at after the last taken token.
+const takeQuery = `
+WITH taken AS (
+	INSERT INTO rate_limits AS b (key, tokens, updated_at, expires_at)
+	VALUES ($1, $2::float8 - 1, NOW(), NOW() + $2::float8 / $3::float8 * INTERVAL '1 second')
+	ON CONFLICT (key) DO UPDATE SET
+		tokens = LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM NOW() - b.updated_at)::float8, 0) * $3::float8) - 1,
+		updated_at = NOW(),
+		expires_at = NOW() + $2::float8 / $3::float8 * INTERVAL '1 second'
+	WHERE LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM NOW() - b.updated_at)::float8, 0) * $3::float8) >= 1
+	RETURNING b.tokens
+)
+SELECT tokens, TRUE FROM taken
+UNION ALL
+SELECT LEAST($2::float8, tokens + GREATEST(EXTRACT(EPOCH FROM NOW() - updated_at)::float8, 0) * $3::float8), FALSE
+FROM rate_limits
+WHERE key = $1 AND NOT EXISTS (SELECT 1 FROM taken)`
+
+func (r *PostgreSQLRateLimitRepository) Take(ctx context.Context, key string, limit model.RateLimit) (*model.RateLimitResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var tokens float64
+	var allowed bool
+
+	err := r.db.QueryRowContext(ctx, takeQuery, key, limit.Burst, limit.Rate).Scan(&tokens, &allowed)
+
+	// The bucket created by a concurrent request after the statement started is locked but not visible,
+	// the request is denied as if the bucket was empty
+	if errors.Is(err, sql.ErrNoRows) {
+		tokens, allowed, err = 0, false, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := model.NewRateLimitResult(limit, tokens, allowed)
+	return &result, nil
+}
+
+func (r *PostgreSQLRateLimitRepository) Refund(ctx context.Context, key string, limit model.RateLimit) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE rate_limits SET tokens = LEAST($2::float8, tokens + 1) WHERE key = $1`, key, limit.Burst)
+	return err
+}
+
+func (r *PostgreSQLRateLimitRepository) Run(ctx context.Context) {
+	sweepEvery(ctx, func() { r.sweep(ctx) })
+}
+
+// sweep removes full buckets, they are recreated full on the next request.
+func (r *PostgreSQLRateLimitRepository) sweep(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE expires_at < NOW()`); err != nil {
+		logger.Log.Warn("Expired rate limit buckets were not removed", zap.Error(err))
+	}
+}
+
+func NewInPostgresSQLRateLimitRepository(db *sql.DB) *PostgreSQLRateLimitRepository {
+	return &PostgreSQLRateLimitRepository{db: db}
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDatabaseEnv contains the DSN of a disposable database, tests of the postgres store are skipped without it.
+const testDatabaseEnv = "TEST_DATABASE_CONN_STRING"
+
+// newTestPostgresRepository returns the store on the migrated test database and a key prefix
+// of the test, buckets with the prefix are removed after the test.
+func newTestPostgresRepository(t *testing.T) (*PostgreSQLRateLimitRepository, *sql.DB, string) {
+	t.Helper()
+
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	cfg := &config.AppConfig{}
+	cfg.DB.DatabaseDSN = config.Secret(dsn)
+
+	db, err := database.NewDatabaseConnectionPool(cfg)
+	require.NoError(t, err)
+
+	prefix := "test:" + uuid.NewString() + ":"
+
+	t.Cleanup(func() {
+		_, err := db.Exec(`DELETE FROM rate_limits WHERE key LIKE $1`, prefix+"%")
+		assert.NoError(t, err)
+		assert.NoError(t, db.Close())
+	})
+
+	return NewInPostgresSQLRateLimitRepository(db), db, prefix
+}
+
+func TestPostgreSQLRateLimitRepository_Take(t *testing.T) {
+	repo, db, prefix := newTestPostgresRepository(t)
+	ctx := context.Background()
+
+	key := prefix + "create:ip:127.0.0.1"
+	limit := model.RateLimit{Rate: 1.0 / 60, Burst: 2}
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		result, err := repo.Take(ctx, key, limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	// The empty bucket denies the request and reads the refilled tokens
+	result, err := repo.Take(ctx, key, limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.InDelta(t, time.Minute, result.RetryAfter, float64(5*time.Second))
+
+	// Refunded token is taken again
+	require.NoError(t, repo.Refund(ctx, key, limit))
+
+	result, err = repo.Take(ctx, key, limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Full buckets are removed
+	_, err = db.Exec(`UPDATE rate_limits SET expires_at = NOW() - INTERVAL '1 second' WHERE key = $1`, key)
+	require.NoError(t, err)
+
+	repo.sweep(ctx)
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM rate_limits WHERE key = $1`, key).Scan(&count))
+	assert.Zero(t, count)
+}
+
+func TestPostgreSQLRateLimitRepository_Take_ConcurrentlyCreatedBucket(t *testing.T) {
+	repo, db, prefix := newTestPostgresRepository(t)
+
+	key := prefix + "create:ip:127.0.0.1"
+	limit := model.RateLimit{Rate: 1, Burst: 2}
+
+	// The empty bucket is created by a transaction which is not committed yet
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	_, err = tx.Exec(
+		`INSERT INTO rate_limits (key, tokens, updated_at, expires_at) VALUES ($1, 0, NOW() + INTERVAL '1 hour', NOW() + INTERVAL '1 hour')`,
+		key,
+	)
+	require.NoError(t, err)
+
+	type taken struct {
+		result *model.RateLimitResult
+		err    error
+	}
+	done := make(chan taken, 1)
+
+	go func() {
+		result, err := repo.Take(context.Background(), key, limit)
+		done <- taken{result, err}
+	}()
+
+	// Take waits for the lock of the bucket which is not visible to its snapshot
+	require.Eventually(t, func() bool {
+		var waiting int
+		err := db.QueryRow(
+			`SELECT COUNT(*) FROM pg_stat_activity WHERE datname = current_database() AND wait_event_type = 'Lock'`,
+		).Scan(&waiting)
+		return err == nil && waiting > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, tx.Commit())
+
+	// The statement returns no rows, the request is denied as if the bucket was empty
+	result := <-done
+	require.NoError(t, result.err)
+	assert.False(t, result.result.Allowed)
+	assert.Equal(t, 0, result.result.Remaining)
+	assert.Equal(t, time.Second, result.result.RetryAfter)
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+)
+
+// sweepInterval is the interval between removals of expired buckets.
+const sweepInterval = time.Minute
+
+// ErrDatabaseRequired is returned when the postgres store is selected without a database.
+var ErrDatabaseRequired = errors.New("rate limit store postgres requires a database connection")
+
+// RateLimitRepository stores token buckets of clients.
+type RateLimitRepository interface {
+	// Take counts a request in the bucket of key, a missing bucket is created full.
+	Take(ctx context.Context, key string, limit model.RateLimit) (*model.RateLimitResult, error)
+	// Refund returns the token taken from the bucket of key by a request which was rejected by another bucket.
+	Refund(ctx context.Context, key string, limit model.RateLimit) error
+	// Run removes full buckets every sweepInterval until ctx is done, they are recreated full on the next request.
+	Run(ctx context.Context)
+}
+
+// sweepEvery calls sweep every sweepInterval until ctx is done.
+func sweepEvery(ctx context.Context, sweep func()) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweep()
+		}
+	}
+}
+
+// NewRateLimitRepository returns the store selected by the configuration,
+// postgres shares budgets between instances of the service.
+func NewRateLimitRepository(cfg *config.AppConfig, db *sql.DB) (RateLimitRepository, error) {
+	if cfg.RateLimit.Store != config.RateLimitStorePostgres {
+		return NewInMemoryRateLimitRepository(), nil
+	}
+
+	if db == nil {
+		return nil, ErrDatabaseRequired
+	}
+
+	return NewInPostgresSQLRateLimitRepository(db), nil
+}
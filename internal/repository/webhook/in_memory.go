@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
+)
+
+// maxStoredDeliveries limits attempts kept in memory for every webhook.
+const maxStoredDeliveries = 100
+
+type InMemoryWebhookRepository struct {
+	webhooks   map[string]*model.Webhook
+	deliveries map[string][]*model.WebhookDelivery
+
+	mu sync.RWMutex
+}
+
+func (r *InMemoryWebhookRepository) Create(webhook *model.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *webhook
+	r.webhooks[webhook.ID] = &stored
+
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) Get(id string, userID string) (*model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok || webhook.UserID != userID {
+		return nil, database.ErrNotFound
+	}
+
+	result := *webhook
+	return &result, nil
+}
+
+func (r *InMemoryWebhookRepository) GetByUserID(userID string) ([]*model.Webhook, error) {
+	result := []*model.Webhook{}
+
+	r.mu.RLock()
+	for _, webhook := range r.webhooks {
+		if webhook.UserID == userID {
+			item := *webhook
+			result = append(result, &item)
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(result, func(a, b *model.Webhook) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return result, nil
+}
+
+func (r *InMemoryWebhookRepository) HasSubscribers(event string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, webhook := range r.webhooks {
+		if webhook.Subscribed(event) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryWebhookRepository) Update(webhook *model.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.webhooks[webhook.ID]
+	if !ok || stored.UserID != webhook.UserID {
+		return database.ErrNotFound
+	}
+
+	stored.URL = webhook.URL
+	stored.Events = webhook.Events
+	stored.Secret = webhook.Secret
+
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) Delete(id string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok || webhook.UserID != userID {
+		return database.ErrNotFound
+	}
+
+	delete(r.webhooks, id)
+	delete(r.deliveries, id)
+
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) AddDelivery(delivery *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[delivery.WebhookID]; !ok {
+		return database.ErrNotFound
+	}
+
+	deliveries := append(r.deliveries[delivery.WebhookID], delivery)
+	if len(deliveries) > maxStoredDeliveries {
+		deliveries = deliveries[len(deliveries)-maxStoredDeliveries:]
+	}
+
+	r.deliveries[delivery.WebhookID] = deliveries
+
+	return nil
+}
+
+func (r *InMemoryWebhookRepository) GetDeliveries(webhookID string, limit int) ([]*model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := r.deliveries[webhookID]
+	result := make([]*model.WebhookDelivery, 0, min(limit, len(deliveries)))
+
+	for i := len(deliveries) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, deliveries[i])
+	}
+
+	return result, nil
+}
+
+func NewInMemoryWebhooksRepository() *InMemoryWebhookRepository {
+	return &InMemoryWebhookRepository{
+		webhooks:   make(map[string]*model.Webhook),
+		deliveries: make(map[string][]*model.WebhookDelivery),
+	}
+}
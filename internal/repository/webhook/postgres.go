@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"github.com/lib/pq"
+)
+
+type PostgreSQLWebhookRepository struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+func (r *PostgreSQLWebhookRepository) Create(webhook *model.Webhook) error {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO webhooks (id, userID, url, events, secret, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		webhook.ID, webhook.UserID, webhook.URL, pq.Array(webhook.Events), webhook.Secret, webhook.CreatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgreSQLWebhookRepository) Get(id string, userID string) (*model.Webhook, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT id, userID, url, events, secret, created_at FROM webhooks WHERE id = $1 AND userID = $2`,
+		id, userID,
+	)
+
+	webhook := &model.Webhook{}
+
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, pq.Array(&webhook.Events), &webhook.Secret, &webhook.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (r *PostgreSQLWebhookRepository) GetByUserID(userID string) ([]*model.Webhook, error) {
+	result := []*model.Webhook{}
+
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, userID, url, events, secret, created_at FROM webhooks WHERE userID = $1 ORDER BY created_at`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { utils.LogErrorWrapper(rows.Close()) }()
+
+	for rows.Next() {
+		webhook := &model.Webhook{}
+
+		err = rows.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, pq.Array(&webhook.Events), &webhook.Secret, &webhook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, webhook)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *PostgreSQLWebhookRepository) HasSubscribers(event string) (bool, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	var exists bool
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM webhooks WHERE cardinality(events) = 0 OR $1 = ANY(events))`,
+		event,
+	).Scan(&exists)
+
+	return exists, err
+}
+
+func (r *PostgreSQLWebhookRepository) Update(webhook *model.Webhook) error {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE webhooks SET url = $3, events = $4, secret = $5 WHERE id = $1 AND userID = $2`,
+		webhook.ID, webhook.UserID, webhook.URL, pq.Array(webhook.Events), webhook.Secret,
+	)
+
+	return affectedOrNotFound(result, err)
+}
+
+func (r *PostgreSQLWebhookRepository) Delete(id string, userID string) error {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND userID = $2`, id, userID)
+
+	return affectedOrNotFound(result, err)
+}
+
+func (r *PostgreSQLWebhookRepository) AddDelivery(delivery *model.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, delivery_id, event, attempt, status_code, error, success, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		delivery.WebhookID,
+		delivery.DeliveryID,
+		delivery.Event,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Error,
+		delivery.Success,
+		delivery.CreatedAt,
+	)
+
+	return err
+}
+
+func (r *PostgreSQLWebhookRepository) GetDeliveries(webhookID string, limit int) ([]*model.WebhookDelivery, error) {
+	result := []*model.WebhookDelivery{}
+
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT webhook_id, delivery_id, event, attempt, status_code, error, success, created_at
+			FROM webhook_deliveries
+			WHERE webhook_id = $1
+			ORDER BY id DESC
+			LIMIT $2`,
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { utils.LogErrorWrapper(rows.Close()) }()
+
+	for rows.Next() {
+		d := &model.WebhookDelivery{}
+
+		err = rows.Scan(&d.WebhookID, &d.DeliveryID, &d.Event, &d.Attempt, &d.StatusCode, &d.Error, &d.Success, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, d)
+	}
+
+	return result, rows.Err()
+}
+
+func affectedOrNotFound(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return database.ErrNotFound
+	}
+
+	return nil
+}
+
+func NewInPostgresSQLWebhooksRepository(ctx context.Context, db *sql.DB) *PostgreSQLWebhookRepository {
+	return &PostgreSQLWebhookRepository{db: db, ctx: ctx}
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+)
+
+type WebhookRepository interface {
+	Create(webhook *model.Webhook) error
+	Get(id string, userID string) (*model.Webhook, error)
+	GetByUserID(userID string) ([]*model.Webhook, error)
+	// HasSubscribers reports whether any webhook receives the event.
+	HasSubscribers(event string) (bool, error)
+	Update(webhook *model.Webhook) error
+	Delete(id string, userID string) error
+	AddDelivery(delivery *model.WebhookDelivery) error
+	// GetDeliveries returns up to limit latest attempts, newest first.
+	GetDeliveries(webhookID string, limit int) ([]*model.WebhookDelivery, error)
+}
+
+func NewWebhooksRepository(ctx context.Context, cfg *config.AppConfig, db *sql.DB) (WebhookRepository, error) {
+	if cfg.DB.DatabaseDSN == "" {
+		return NewInMemoryWebhooksRepository(), nil
+	}
+
+	return NewInPostgresSQLWebhooksRepository(ctx, db), nil
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"go.uber.org/zap"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// CertificateReloader serves the certificate loaded from PEM files and replaces it when the files change,
+// so certificates renewed by ACME clients are used without restarting the server.
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	states      map[string]fileState
+}
+
+// GetCertificate returns the current certificate, it is used as tls.Config.GetCertificate.
+func (r *CertificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.certificate, nil
+}
+
+// Reload reads the certificate and the key again. The current certificate is kept if they fail to load.
+func (r *CertificateReloader) Reload() error {
+	states := make(map[string]fileState, 2)
+
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("load certificate: %w", err)
+		}
+		states[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate '%s': %w", r.certFile, err)
+	}
+
+	r.mu.Lock()
+	r.certificate = &certificate
+	r.states = states
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the certificate every interval when any file was changed until ctx is done.
+func (r *CertificateReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+
+			if err := r.Reload(); err != nil {
+				logger.Log.Error("Certificate reload failed", zap.Error(err))
+				continue
+			}
+
+			logger.Log.Info("Certificate reloaded", zap.String("file", r.certFile))
+		}
+	}
+}
+
+func (r *CertificateReloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for path, state := range r.states {
+		info, err := os.Stat(path)
+		if err != nil {
+			return true
+		}
+
+		if !info.ModTime().Equal(state.modTime) || info.Size() != state.size {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewCertificateReloader loads the certificate chain and the private key from PEM files.
+func NewCertificateReloader(certFile string, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+)
+
+var tlsVersions = map[string]uint16{
+	config.TLSVersion12: tls.VersionTLS12,
+	config.TLSVersion13: tls.VersionTLS13,
+}
+
+// NewServer returns the server of the handler with timeouts from the configuration.
+// HTTP/2 is served over TLS, plain connections use HTTP/1.1.
+func NewServer(cfg *config.AppConfig, handler http.Handler) *http.Server {
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+
+	return &http.Server{
+		Addr:              cfg.Server.Address,
+		Handler:           handler,
+		Protocols:         protocols,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+}
+
+// NewTLSConfig returns the TLS configuration with the minimum version and cipher suites from
+// the configuration, certificates are taken from the reloader on every handshake.
+func NewTLSConfig(cfg *config.AppConfig, reloader *CertificateReloader) (*tls.Config, error) {
+	minVersion, ok := tlsVersions[cfg.TLS.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unknown TLS version '%s'", cfg.TLS.MinVersion)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.TLS.CipherSuites != "" {
+		suites := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			suites[suite.Name] = suite.ID
+		}
+
+		for _, name := range strings.Split(cfg.TLS.CipherSuites, ",") {
+			id, ok := suites[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown or insecure cipher suite '%s'", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// NewRedirectServer returns the plain HTTP server on the redirect address which permanently
+// redirects all requests to the same path on the host of BASE_URL. The Host header of the request
+// is not used, so the server can not redirect to other sites.
+func NewRedirectServer(cfg *config.AppConfig) *http.Server {
+	host := ""
+	if baseURL, err := url.Parse(cfg.Server.BaseURL); err == nil {
+		host = baseURL.Host
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+
+		// 308 keeps the method and the body of API requests
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+
+	return &http.Server{
+		Addr:              cfg.TLS.RedirectAddress,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate for localhost with the serial number.
+func writeCertificate(t *testing.T, certFile string, keyFile string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func serialNumber(t *testing.T, reloader *CertificateReloader) int64 {
+	certificate, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.SerialNumber.Int64()
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	_, err := NewCertificateReloader(certFile, keyFile)
+	require.Error(t, err)
+
+	writeCertificate(t, certFile, keyFile, 1)
+
+	reloader, err := NewCertificateReloader(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), serialNumber(t, reloader))
+	assert.False(t, reloader.changed())
+
+	writeCertificate(t, certFile, keyFile, 2)
+	require.NoError(t, os.Chtimes(certFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	assert.True(t, reloader.changed())
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, int64(2), serialNumber(t, reloader))
+
+	// A broken file keeps the current certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	require.Error(t, reloader.Reload())
+	assert.Equal(t, int64(2), serialNumber(t, reloader))
+}
+
+func TestNewServer_HTTP2(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, 1)
+
+	reloader, err := NewCertificateReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	cfg := &config.AppConfig{}
+	cfg.Server.ReadHeaderTimeout = time.Second
+	cfg.TLS.MinVersion = config.TLSVersion12
+
+	srv := NewServer(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+
+	srv.TLSConfig, err = NewTLSConfig(cfg, reloader)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() { _ = srv.ServeTLS(listener, "", "") }()
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+
+	response, err := client.Get("https://" + listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+
+	assert.Equal(t, "HTTP/2.0", response.Proto)
+	assert.Equal(t, uint16(tls.VersionTLS13), response.TLS.Version)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	cfg := &config.AppConfig{}
+	cfg.TLS.MinVersion = config.TLSVersion13
+	cfg.TLS.CipherSuites = "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"
+
+	tlsConfig, err := NewTLSConfig(cfg, &CertificateReloader{})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, tlsConfig.CipherSuites)
+
+	cfg.TLS.CipherSuites = "TLS_RSA_WITH_RC4_128_SHA"
+	_, err = NewTLSConfig(cfg, &CertificateReloader{})
+	assert.Error(t, err)
+}
+
+func TestNewRedirectServer(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseURL   string
+		target    string
+		wantedURL string
+	}{
+		{"default port", "https://example.com", "http://example.com/abc?x=1", "https://example.com/abc?x=1"},
+		{"custom port", "https://example.com:8443/", "http://example.com:8080/api/shorten", "https://example.com:8443/api/shorten"},
+		{"foreign host", "https://example.com", "http://evil.example/abc", "https://example.com/abc"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.AppConfig{}
+			cfg.Server.BaseURL = test.baseURL
+
+			recorder := httptest.NewRecorder()
+			NewRedirectServer(cfg).Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, test.target, nil))
+
+			assert.Equal(t, http.StatusPermanentRedirect, recorder.Code)
+			assert.Equal(t, test.wantedURL, recorder.Header().Get("Location"))
+		})
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"net/http"
 
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
 	"github.com/Alexey-zaliznuak/shortener/internal/config"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository"
 	"github.com/gin-gonic/gin"
@@ -11,6 +12,7 @@ import (
 
 type AuthService struct {
 	Repository *repository.AuthRepository
+	auditor    *audit.AuditorShortURLOperationManager
 }
 
 func (service *AuthService) GetAuthorization(c *gin.Context) (*repository.Claims, error) {
@@ -27,7 +29,33 @@ func (service *AuthService) GetAuthorization(c *gin.Context) (*repository.Claims
 		}
 	}
 
-	return service.Repository.ParsePayload(auth)
+	claims, err := service.Repository.ParsePayload(auth)
+
+	if err != nil {
+		service.audit(c, audit.AuthActionTokenRejected, "")
+	}
+
+	return claims, err
+}
+
+// PeekUserID returns the user of a valid token without issuing a new one, empty if there is none.
+// Rejected tokens are not audited, the request is expected to be authorized later by a handler.
+func (service *AuthService) PeekUserID(c *gin.Context) string {
+	auth, err := c.Cookie("Authorization")
+	if err != nil {
+		auth = c.GetHeader("Authorization")
+	}
+
+	if auth == "" {
+		return ""
+	}
+
+	claims, err := service.Repository.ParsePayload(auth)
+	if err != nil {
+		return ""
+	}
+
+	return claims.UserID
 }
 
 func (service *AuthService) SaveAuthorization(UserID string, c *gin.Context) (string, error) {
@@ -52,7 +80,23 @@ func (service *AuthService) GetOrCreateAndSaveAuthorization(c *gin.Context) (*re
 	return service.CreateAndSaveAuthorization(c)
 }
 
+// GetOrCreateAndSaveVisitorAuthorization is GetOrCreateAndSaveAuthorization for anonymous visitors
+// of short links, tokens issued to them are not audited.
+func (service *AuthService) GetOrCreateAndSaveVisitorAuthorization(c *gin.Context) (*repository.Claims, error) {
+	auth, err := service.GetAuthorization(c)
+
+	if err == nil {
+		return auth, err
+	}
+
+	return service.createAndSaveAuthorization(c, false)
+}
+
 func (service *AuthService) CreateAndSaveAuthorization(c *gin.Context) (*repository.Claims, error) {
+	return service.createAndSaveAuthorization(c, true)
+}
+
+func (service *AuthService) createAndSaveAuthorization(c *gin.Context, audited bool) (*repository.Claims, error) {
 	UserID, err := uuid.NewRandom()
 
 	if err != nil {
@@ -64,9 +108,24 @@ func (service *AuthService) CreateAndSaveAuthorization(c *gin.Context) (*reposit
 		return nil, err
 	}
 
+	if audited {
+		service.audit(c, audit.AuthActionTokenIssued, UserID.String())
+	}
+
 	return service.Repository.ParsePayload(jwt)
 }
 
+// UseAuditor sets the auditor notified about issued and rejected tokens.
+func (service *AuthService) UseAuditor(auditor *audit.AuditorShortURLOperationManager) {
+	service.auditor = auditor
+}
+
+func (service *AuthService) audit(c *gin.Context, action audit.ShortURLAction, userID string) {
+	if service.auditor != nil {
+		service.auditor.AuditNotify(audit.NewRequestPayload(c, action, userID))
+	}
+}
+
 func NewAuthService(config *config.AppConfig) *AuthService {
 	return &AuthService{Repository: repository.NewAuthRepository(config)}
 }
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+	"go.uber.org/zap"
+)
+
+// defaultHealthCheckTimeout limits every component check.
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// ErrShuttingDown is reported by readiness after the shutdown has started.
+var ErrShuttingDown = errors.New("shutdown in progress")
+
+// HealthCheck checks a component required to serve requests.
+type HealthCheck func(ctx context.Context) error
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthService reports readiness of the service. The storage and the shutdown
+// flag are always checked, other components are added with AddCheck.
+type HealthService struct {
+	repository link.LinkRepository
+	checks     []namedHealthCheck
+	timeout    time.Duration
+
+	shuttingDown atomic.Bool
+}
+
+// AddCheck adds the component checked by readiness.
+func (s *HealthService) AddCheck(name string, check HealthCheck) {
+	s.checks = append(s.checks, namedHealthCheck{name: name, check: check})
+}
+
+// StartShutdown makes the service not ready, so load balancers stop sending new requests.
+func (s *HealthService) StartShutdown() {
+	s.shuttingDown.Store(true)
+}
+
+// PingStorage checks the links storage.
+func (s *HealthService) PingStorage(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.repository.Ping(ctx)
+}
+
+// Readiness checks all components concurrently. Errors are logged and not returned,
+// so details of the infrastructure are not exposed.
+func (s *HealthService) Readiness(ctx context.Context) *model.Readiness {
+	checks := append([]namedHealthCheck{
+		{name: "storage", check: s.repository.Ping},
+		{name: "shutdown", check: s.checkShutdown},
+	}, s.checks...)
+
+	result := &model.Readiness{Status: model.HealthStatusReady, Components: make(map[string]model.ComponentHealth, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.check(checkCtx)
+
+			component := model.ComponentHealth{Status: model.HealthStatusUp, DurationMs: time.Since(start).Milliseconds()}
+
+			if err != nil {
+				component.Status = model.HealthStatusDown
+				logger.FromContext(ctx).Warn("Readiness check failed", zap.String("component", c.name), zap.Error(err))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			result.Components[c.name] = component
+			if err != nil {
+				result.Status = model.HealthStatusNotReady
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return result
+}
+
+func (s *HealthService) checkShutdown(_ context.Context) error {
+	if s.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+func NewHealthService(repository link.LinkRepository) *HealthService {
+	return &HealthService{
+		repository: repository,
+		timeout:    defaultHealthCheckTimeout,
+	}
+}
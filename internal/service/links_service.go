@@ -6,37 +6,96 @@ import (
 	"fmt"
 	"math/rand"
 	"net/url"
+	"sync/atomic"
 
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
 	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
 	"github.com/Alexey-zaliznuak/shortener/internal/model"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
 	"github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+	"github.com/Alexey-zaliznuak/shortener/internal/service/urlcheck"
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
+const tracerName = "github.com/Alexey-zaliznuak/shortener/internal/service"
+
+// ErrInvalidURL is returned when the URL to shorten is not a valid absolute URL.
+var ErrInvalidURL = errors.New("create link error: invalid URL")
+
+// ErrBatchTooLarge is returned for batches exceeding the configured number of links.
+var ErrBatchTooLarge = errors.New("create links error: batch is too large")
+
+func invalidURLError(rawURL string, cause error) error {
+	if cause != nil {
+		return fmt.Errorf("%w: '%s': %w", ErrInvalidURL, rawURL, cause)
+	}
+	return fmt.Errorf("%w: '%s'", ErrInvalidURL, rawURL)
+}
+
 type LinksService struct {
 	repository link.LinkRepository
 	auth       *AuthService
+	normalizer *URLNormalizer
+	policy     *URLPolicy
+	checker    urlcheck.URLChecker
+	auditor    *audit.AuditorShortURLOperationManager
 	*config.AppConfig
+
+	// reloaded contains the configuration applied after the start, reloadable settings are read from it.
+	reloaded atomic.Pointer[config.AppConfig]
 }
 
 func (s *LinksService) GetFullURLFromShort(shortcut string) (string, error) {
-	link, err := s.repository.GetByShortcut(shortcut)
+	link, err := s.repository.GetByShortcut(context.Background(), shortcut)
 	if err != nil {
 		return "", err
 	}
 	return link.FullURL, nil
 }
 
-func (s *LinksService) GetUserLinks(c *gin.Context) ([]*model.GetUserLinksRequestItem, error) {
+// GetLink returns the link by shortcut. When checks on redirect are enabled
+// the destination is checked again and flagged links are quarantined.
+func (s *LinksService) GetLink(ctx context.Context, shortcut string) (link *model.Link, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "LinksService.GetLink",
+		trace.WithAttributes(attribute.String("shortcut", shortcut)))
+	defer func() { endSpan(span, err) }()
+
+	link, err = s.repository.GetByShortcut(ctx, shortcut)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.IsQuarantined || !s.current().URLCheck.CheckOnRedirect {
+		return link, nil
+	}
+
+	if s.isMalicious(ctx, link.ToCreateDto().DeduplicationURL()) {
+		utils.LogErrorWrapper(s.quarantine(ctx, link, audit.AuditPayload{}, nil))
+	}
+
+	return link, nil
+}
+
+func (s *LinksService) GetUserLinks(c *gin.Context) (links []*model.GetUserLinksRequestItem, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(c.Request.Context(), "LinksService.GetUserLinks")
+	defer func() { endSpan(span, err) }()
+
 	claims, err := s.auth.GetAuthorization(c)
 
 	if err != nil {
 		return nil, err
 	}
 
-	links, err := s.repository.GetByUserID(claims.UserID)
+	links, err = s.repository.GetByUserID(ctx, claims.UserID)
 
 	if err != nil {
 		return nil, err
@@ -51,7 +110,10 @@ func (s *LinksService) GetUserLinks(c *gin.Context) ([]*model.GetUserLinksReques
 	return links, err
 }
 
-func (s *LinksService) CreateLink(link *model.CreateLinkDto, c *gin.Context) (*model.Link, *repository.Claims, bool, error) {
+func (s *LinksService) CreateLink(link *model.CreateLinkDto, c *gin.Context) (_ *model.Link, _ *repository.Claims, _ bool, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(c.Request.Context(), "LinksService.CreateLink")
+	defer func() { endSpan(span, err) }()
+
 	auth, err := s.auth.GetOrCreateAndSaveAuthorization(c)
 
 	if err != nil {
@@ -59,43 +121,76 @@ func (s *LinksService) CreateLink(link *model.CreateLinkDto, c *gin.Context) (*m
 	}
 
 	if !s.isValidURL(link.FullURL) {
-		return link.NewLink(auth.UserID), nil, false, fmt.Errorf("create link error: invalid URL: '%s'", link.FullURL)
+		return link.NewLink(auth.UserID), nil, false, invalidURLError(link.FullURL, nil)
 	}
 
+	link.CanonicalURL, err = s.normalizer.Normalize(link.FullURL)
+
+	if err != nil {
+		return link.NewLink(auth.UserID), nil, false, invalidURLError(link.FullURL, err)
+	}
+
+	if err := s.checkPolicy(link, c); err != nil {
+		return link.NewLink(auth.UserID), nil, false, err
+	}
+
+	link.IsQuarantined = s.isMalicious(ctx, link.CanonicalURL)
+
 	if link.Shortcut == "" {
 		var err error
 
-		link.Shortcut, err = s.createUniqueShortcut()
+		link.Shortcut, err = s.createUniqueShortcut(ctx)
 
 		if err != nil {
 			return link.NewLink(auth.UserID), auth, false, err
 		}
 	}
-	l, created, err := s.repository.Create(link, auth.UserID, nil)
+	l, created, err := s.repository.Create(ctx, link, auth.UserID, nil)
+
+	if err == nil && created {
+		metrics.LinksCreated.Inc()
+	}
+
+	if err == nil && link.IsQuarantined && !l.IsQuarantined {
+		err = s.quarantine(ctx, l, audit.NewRequestPayload(c, audit.ShortURLActionUpdate, auth.UserID), nil)
+	}
 
 	return l, auth, created, err
 }
 
-func (s *LinksService) DeleteUserLinks(shortcuts []string, c *gin.Context) error {
+// DeleteUserLinks marks links of the current user as deleted and returns shortcuts of the marked links.
+func (s *LinksService) DeleteUserLinks(shortcuts []string, c *gin.Context) (_ []string, _ *repository.Claims, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(c.Request.Context(), "LinksService.DeleteUserLinks",
+		trace.WithAttributes(attribute.Int("links.count", len(shortcuts))))
+	defer func() { endSpan(span, err) }()
+
 	auth, err := s.auth.GetOrCreateAndSaveAuthorization(c)
 
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	fmt.Println(auth)
-	fmt.Println(shortcuts)
+	deleted, err := s.repository.DeleteUserLinks(ctx, shortcuts, auth.UserID)
+	metrics.LinksDeleted.Add(uint64(len(deleted)))
 
-	return s.repository.DeleteUserLinks(shortcuts, auth.UserID)
+	return deleted, auth, err
 }
 
-func (s *LinksService) BulkCreateWithCorrelationID(links []*model.CreateLinkWithCorrelationIDRequestItem, c *gin.Context) ([]*model.CreateLinkWithCorrelationIDResponseItem, error) {
+func (s *LinksService) BulkCreateWithCorrelationID(links []*model.CreateLinkWithCorrelationIDRequestItem, c *gin.Context) (_ []*model.CreateLinkWithCorrelationIDResponseItem, _ *repository.Claims, err error) {
 	var result []*model.CreateLinkWithCorrelationIDResponseItem
 
+	ctx, span := otel.Tracer(tracerName).Start(c.Request.Context(), "LinksService.BulkCreateWithCorrelationID",
+		trace.WithAttributes(attribute.Int("links.count", len(links))))
+	defer func() { endSpan(span, err) }()
+
+	if maxBatchSize := s.current().RateLimit.MaxBatchSize; maxBatchSize > 0 && len(links) > maxBatchSize {
+		return nil, nil, fmt.Errorf("%w: at most %d links are allowed", ErrBatchTooLarge, maxBatchSize)
+	}
+
 	auth, err := s.auth.GetOrCreateAndSaveAuthorization(c)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	transactionExecuter, err := s.repository.GetTransactionExecuter(context.Background(), nil)
@@ -105,83 +200,199 @@ func (s *LinksService) BulkCreateWithCorrelationID(links []*model.CreateLinkWith
 		if errors.Is(err, database.ErrExecuterNotSupportTransactions) {
 			supportTransaction = false
 		} else {
-			return nil, err
+			return nil, auth, err
 		}
 	}
 
+	// Links of the failed part of the batch are not stored
+	defer func() {
+		if err != nil && supportTransaction && transactionExecuter != nil {
+			utils.LogErrorWrapper(transactionExecuter.Rollback())
+		}
+	}()
+
 	for index, link := range links {
 		if !s.isValidURL(link.FullURL) {
-			if supportTransaction {
-				transactionExecuter.Commit()
-			}
-			return nil, fmt.Errorf("create link error: invalid URL: '%s'", link.FullURL)
+			return nil, auth, invalidURLError(link.FullURL, nil)
 		}
 
-		shortcut, err := s.createUniqueShortcut()
+		canonicalURL, err := s.normalizer.Normalize(link.FullURL)
 		if err != nil {
-			if supportTransaction {
-				transactionExecuter.Commit()
-			}
-			return nil, err
+			return nil, auth, invalidURLError(link.FullURL, err)
 		}
 
-		l := &model.CreateLinkDto{FullURL: link.FullURL, Shortcut: shortcut}
+		l := &model.CreateLinkDto{FullURL: link.FullURL, CanonicalURL: canonicalURL}
+
+		if err := s.checkPolicy(l, c); err != nil {
+			return nil, auth, err
+		}
 
-		newLink, _, err := s.repository.Create(l, auth.UserID, transactionExecuter)
+		l.IsQuarantined = s.isMalicious(ctx, l.CanonicalURL)
 
+		l.Shortcut, err = s.createUniqueShortcut(ctx)
 		if err != nil {
-			if supportTransaction {
-				transactionExecuter.Commit()
-			}
-			return nil, err
+			return nil, auth, err
 		}
 
-		shortcut, err = s.BuildShortURL(newLink.Shortcut, c)
+		newLink, created, err := s.repository.Create(ctx, l, auth.UserID, transactionExecuter)
+
+		if err == nil && created {
+			metrics.LinksCreated.Inc()
+		}
+
+		if err == nil && l.IsQuarantined && !newLink.IsQuarantined {
+			err = s.quarantine(ctx, newLink, audit.NewRequestPayload(c, audit.ShortURLActionUpdate, auth.UserID), transactionExecuter)
+		}
 
 		if err != nil {
-			if supportTransaction {
-				transactionExecuter.Commit()
-			}
-			return nil, err
+			return nil, auth, err
 		}
 
-		result = append(result, &model.CreateLinkWithCorrelationIDResponseItem{CorrelationID: link.CorrelationID, Shortcut: shortcut})
+		shortcut, err := s.BuildShortURL(newLink.Shortcut, c)
+
+		if err != nil {
+			return nil, auth, err
+		}
+
+		result = append(result, &model.CreateLinkWithCorrelationIDResponseItem{CorrelationID: link.CorrelationID, Shortcut: shortcut, Link: newLink})
+
+		if supportTransaction && ((index+1)%1000 == 0 || index == len(links)-1) {
+			committed := transactionExecuter
+			transactionExecuter = nil
+
+			if err := committed.Commit(); err != nil {
+				return nil, auth, err
+			}
+
+			if index == len(links)-1 {
+				break
+			}
 
-		if supportTransaction && (index+1%1000 == 0 || index == len(links)-1) {
-			transactionExecuter.Commit()
 			transactionExecuter, err = s.repository.GetTransactionExecuter(context.Background(), nil)
 			if err != nil {
-				return nil, err
+				return nil, auth, err
 			}
 		}
 	}
 
-	if supportTransaction {
-		transactionExecuter.Commit()
+	// An empty batch leaves the opened transaction
+	if supportTransaction && transactionExecuter != nil {
+		if err := transactionExecuter.Commit(); err != nil {
+			return nil, auth, err
+		}
 	}
 
-	return result, nil
+	return result, auth, nil
 }
 
-func (s *LinksService) createUniqueShortcut() (string, error) {
-	maxAttempts := 5
+// UseURLPolicy replaces the policy destination URLs are checked against.
+func (s *LinksService) UseURLPolicy(policy *URLPolicy) {
+	s.policy = policy
+}
 
-	newShortcut := s.generateShortcut(s.AppConfig.Server.ShortLinksLength)
+// UseURLChecker sets the checker consulted on links creation and, if configured, on redirects.
+func (s *LinksService) UseURLChecker(checker urlcheck.URLChecker) {
+	s.checker = checker
+}
 
-	for range maxAttempts {
-		_, err := s.repository.GetByShortcut(newShortcut)
-		if err != nil {
-			newShortcut = s.generateShortcut(s.AppConfig.Server.ShortLinksLength)
-			continue
+// ApplyConfig replaces the configuration of reloadable settings: shortcuts length, batch size and checks on redirect.
+func (s *LinksService) ApplyConfig(cfg *config.AppConfig) {
+	s.reloaded.Store(cfg)
+}
+
+// current returns the configuration applied last.
+func (s *LinksService) current() *config.AppConfig {
+	if cfg := s.reloaded.Load(); cfg != nil {
+		return cfg
+	}
+	return s.AppConfig
+}
+
+// UseAuditor sets the auditor notified about links and authorization changes.
+func (s *LinksService) UseAuditor(auditor *audit.AuditorShortURLOperationManager) {
+	s.auditor = auditor
+	s.auth.UseAuditor(auditor)
+}
+
+// quarantine marks the existing link as quarantined and audits the update.
+// executer is the transaction the link was created in, nil outside of batches.
+func (s *LinksService) quarantine(ctx context.Context, link *model.Link, event audit.AuditPayload, executer database.Executer) error {
+	if err := s.repository.Quarantine(ctx, link.Shortcut, executer); err != nil {
+		return err
+	}
+
+	link.IsQuarantined = true
+
+	if s.auditor != nil {
+		event.Action = audit.ShortURLActionUpdate
+		event.Shortcut = link.Shortcut
+		event.URL = link.FullURL
+		if event.UserID == "" {
+			event.UserID = link.UserID
 		}
-		break
+		s.auditor.AuditNotify(event)
 	}
 
-	if _, err := s.repository.GetByShortcut(newShortcut); err != database.ErrNotFound {
-		return "", fmt.Errorf("create link error: could not generate unique shortcut after %d attempts", maxAttempts)
+	return nil
+}
+
+// isMalicious consults the URL checker. Check errors are logged and the URL
+// is considered safe so an unavailable checker does not block links creation.
+func (s *LinksService) isMalicious(ctx context.Context, rawURL string) bool {
+	if s.checker == nil {
+		return false
+	}
+
+	verdict, err := s.checker.Check(ctx, rawURL)
+
+	if err != nil {
+		logger.FromContext(ctx).Error("URL check failed", zap.String("url", rawURL), zap.Error(err))
+		return false
+	}
+
+	if verdict.Malicious {
+		logger.FromContext(ctx).Warn("Malicious URL quarantined", zap.String("url", rawURL), zap.String("threatType", verdict.ThreatType))
+	}
+
+	return verdict.Malicious
+}
+
+// checkPolicy checks the length and the scheme of the submitted URL and host rules
+// against the canonical one, so lists match punycode hosts.
+func (s *LinksService) checkPolicy(link *model.CreateLinkDto, c *gin.Context) error {
+	return s.policy.CheckCanonical(link.FullURL, link.DeduplicationURL(), c.Request.Host)
+}
+
+func (s *LinksService) createUniqueShortcut(ctx context.Context) (string, error) {
+	maxAttempts := 5
+
+	for range maxAttempts {
+		newShortcut := s.generateShortcut(s.current().Server.ShortLinksLength)
+
+		_, err := s.repository.GetByShortcut(ctx, newShortcut)
+
+		if errors.Is(err, database.ErrNotFound) {
+			return newShortcut, nil
+		}
+
+		// Deleted links keep their shortcuts
+		if err != nil && !errors.Is(err, database.ErrObjectDeleted) {
+			return "", err
+		}
+
+		metrics.ShortcutCollisions.Inc()
 	}
 
-	return newShortcut, nil
+	return "", fmt.Errorf("create link error: could not generate unique shortcut after %d attempts", maxAttempts)
+}
+
+// endSpan ends the span of a method, errors other than missing links are recorded.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, database.ErrNotFound) && !errors.Is(err, database.ErrObjectDeleted) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 }
 
 func (s *LinksService) generateShortcut(length int) string {
@@ -220,6 +431,8 @@ func NewLinksService(repository link.LinkRepository, config *config.AppConfig) *
 	return &LinksService{
 		repository: repository,
 		auth:       NewAuthService(config),
+		normalizer: NewURLNormalizer(config),
+		policy:     NewURLPolicy(config),
 		AppConfig:  config,
 	}
 }
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTx records how the batch transaction was finished.
+type recordingTx struct {
+	database.Executer
+	committed  int
+	rolledBack int
+}
+
+func (tx *recordingTx) Commit() error {
+	tx.committed++
+	return nil
+}
+
+func (tx *recordingTx) Rollback() error {
+	tx.rolledBack++
+	return nil
+}
+
+// transactionalRepository is the in-memory repository handing out recordingTx transactions.
+type transactionalRepository struct {
+	*link.InMemoryLinkRepository
+	transactions []*recordingTx
+}
+
+func (r *transactionalRepository) GetTransactionExecuter(context.Context, *sql.TxOptions) (database.TransactionExecuter, error) {
+	tx := &recordingTx{}
+	r.transactions = append(r.transactions, tx)
+	return tx, nil
+}
+
+func TestLinksService_BulkCreateWithCorrelationID_Transaction(t *testing.T) {
+	t.Setenv("BASE_URL", "http://localhost:8080")
+	cfg, err := config.GetConfig(&config.FlagsInitialConfig{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		urls         []string
+		wantErr      bool
+		wantCommit   int
+		wantRollback int
+	}{
+		{name: "valid batch is committed", urls: []string{"https://a.example/", "https://b.example/"}, wantCommit: 1},
+		{name: "invalid link rolls back the batch", urls: []string{"https://a.example/", "not a url"}, wantErr: true, wantRollback: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &transactionalRepository{InMemoryLinkRepository: link.NewInMemoryLinksRepository(cfg)}
+			service := NewLinksService(repository, cfg)
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("POST", "/api/shorten/batch", nil)
+
+			items := make([]*model.CreateLinkWithCorrelationIDRequestItem, len(tt.urls))
+			for i, url := range tt.urls {
+				items[i] = &model.CreateLinkWithCorrelationIDRequestItem{CorrelationID: url, FullURL: url}
+			}
+
+			_, _, err := service.BulkCreateWithCorrelationID(items, c)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, repository.transactions, 1)
+			assert.Equal(t, tt.wantCommit, repository.transactions[0].committed)
+			assert.Equal(t, tt.wantRollback, repository.transactions[0].rolledBack)
+		})
+	}
+}
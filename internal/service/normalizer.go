@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"golang.org/x/net/idna"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
+// hostProfile maps hostnames like idna.Lookup but keeps STD3 rules off,
+// so hostnames with underscores stay valid.
+var hostProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false))
+
+var trackingParams = map[string]bool{
+	"gclid":  true,
+	"fbclid": true,
+	"yclid":  true,
+}
+
+// URLNormalizer builds the canonical form of a URL which is used to detect duplicates.
+type URLNormalizer struct {
+	// SortQuery sorts query parameters by name.
+	SortQuery bool
+	// StripTrackingParams removes utm_* and click identifier parameters.
+	StripTrackingParams bool
+	// StripTrailingSlash removes the trailing slash from non-root paths.
+	StripTrailingSlash bool
+}
+
+// Normalize returns the canonical form of rawURL: lowercase scheme and host,
+// punycode host, no default port and optionally filtered and sorted query.
+func (n *URLNormalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, err := n.normalizeHost(u.Scheme, u.Hostname(), u.Port())
+	if err != nil {
+		return "", fmt.Errorf("normalize host '%s': %w", u.Host, err)
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	if n.StripTrailingSlash && len(u.Path) > 1 && strings.HasSuffix(u.Path, "/") {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+
+	u.RawQuery = n.normalizeQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+func (n *URLNormalizer) normalizeHost(scheme string, hostname string, port string) (string, error) {
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		if ip.To4() == nil {
+			hostname = "[" + ip.String() + "]"
+		} else {
+			hostname = ip.String()
+		}
+	} else {
+		ascii, err := hostProfile.ToASCII(hostname)
+		if err != nil {
+			return "", err
+		}
+		hostname = ascii
+	}
+
+	if port == "" || defaultPorts[scheme] == port {
+		return hostname, nil
+	}
+
+	return hostname + ":" + port, nil
+}
+
+// normalizeQuery works with raw "key=value" pairs to keep the original
+// parameters order and encoding when sorting is disabled.
+func (n *URLNormalizer) normalizeQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	params := make([]string, 0)
+
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+
+		if n.StripTrackingParams && isTrackingParam(queryParamName(param)) {
+			continue
+		}
+
+		params = append(params, param)
+	}
+
+	if n.SortQuery {
+		slices.SortStableFunc(params, func(a, b string) int {
+			return strings.Compare(queryParamName(a), queryParamName(b))
+		})
+	}
+
+	return strings.Join(params, "&")
+}
+
+func queryParamName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "utm_") || trackingParams[name]
+}
+
+// NewURLNormalizer creates URLNormalizer with options from the application config.
+func NewURLNormalizer(cfg *config.AppConfig) *URLNormalizer {
+	return &URLNormalizer{
+		SortQuery:           cfg.Links.NormalizeSortQuery,
+		StripTrackingParams: cfg.Links.NormalizeStripTrackingParams,
+		StripTrailingSlash:  cfg.Links.NormalizeStripTrailingSlash,
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLNormalizer_Normalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer URLNormalizer
+		url        string
+		want       string
+	}{
+		{
+			name: "lowercase scheme and host",
+			url:  "HTTP://Example.COM/Path",
+			want: "http://example.com/Path",
+		},
+		{
+			name:       "same link with trailing slash",
+			normalizer: URLNormalizer{StripTrailingSlash: true},
+			url:        "http://example.com/a/",
+			want:       "http://example.com/a",
+		},
+		{
+			name:       "root path kept",
+			normalizer: URLNormalizer{StripTrailingSlash: true},
+			url:        "http://example.com",
+			want:       "http://example.com/",
+		},
+		{
+			name: "default ports stripped",
+			url:  "https://example.com:443/a?b=1",
+			want: "https://example.com/a?b=1",
+		},
+		{
+			name: "custom port kept",
+			url:  "http://example.com:8080/",
+			want: "http://example.com:8080/",
+		},
+		{
+			name: "idn to punycode",
+			url:  "https://Пример.рф/путь",
+			want: "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C",
+		},
+		{
+			name: "underscore in host",
+			url:  "https://My_Host.example.com/a",
+			want: "https://my_host.example.com/a",
+		},
+		{
+			name: "ipv6 host",
+			url:  "http://[::1]:80/a",
+			want: "http://[::1]/a",
+		},
+		{
+			name:       "tracking params removed",
+			normalizer: URLNormalizer{StripTrackingParams: true},
+			url:        "https://example.com/a?utm_source=x&id=1&UTM_Medium=y&fbclid=z",
+			want:       "https://example.com/a?id=1",
+		},
+		{
+			name: "query order kept without sorting",
+			url:  "https://example.com/a?b=2&a=1",
+			want: "https://example.com/a?b=2&a=1",
+		},
+		{
+			name:       "query sorted",
+			normalizer: URLNormalizer{SortQuery: true},
+			url:        "https://example.com/a?b=2&a=1&a=0",
+			want:       "https://example.com/a?a=1&a=0&b=2",
+		},
+		{
+			name:       "only tracking params",
+			normalizer: URLNormalizer{StripTrackingParams: true},
+			url:        "https://example.com/a?utm_source=x",
+			want:       "https://example.com/a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.normalizer.Normalize(test.url)
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
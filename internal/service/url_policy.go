@@ -0,0 +1,313 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+)
+
+// Codes of URL policy violations returned to clients.
+const (
+	PolicyViolationURLTooLong        = "url_too_long"
+	PolicyViolationSchemeNotAllowed  = "scheme_not_allowed"
+	PolicyViolationDomainDenied      = "domain_denied"
+	PolicyViolationDomainNotAllowed  = "domain_not_allowed"
+	PolicyViolationPrivateAddress    = "private_address"
+	PolicyViolationSelfReferencedURL = "self_referenced_url"
+)
+
+// PolicyViolationError is returned when a destination URL is rejected by URLPolicy.
+type PolicyViolationError struct {
+	// Code contains the machine readable violation code.
+	Code string `json:"code"`
+	// Message contains the human readable violation description.
+	Message string `json:"message"`
+	// URL contains the rejected URL.
+	URL string `json:"url"`
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("create link error: %s: '%s'", e.Message, e.URL)
+}
+
+// URLPolicy decides whether a destination URL may be shortened.
+type URLPolicy struct {
+	allowedSchemes        map[string]bool
+	blockPrivateAddresses bool
+	maxURLLength          int
+	serviceHosts          map[string]bool
+
+	mu             sync.RWMutex
+	allowedDomains map[string]bool
+	deniedDomains  map[string]bool
+}
+
+// Check validates rawURL against the policy. requestHost is the host the service
+// was reached by and is treated as a short domain together with the configured ones.
+func (p *URLPolicy) Check(rawURL string, requestHost string) error {
+	return p.CheckCanonical(rawURL, rawURL, requestHost)
+}
+
+// CheckCanonical validates the length and the scheme of rawURL as submitted and host rules
+// against canonicalURL, so normalization can neither shorten the URL nor hide its host.
+func (p *URLPolicy) CheckCanonical(rawURL string, canonicalURL string, requestHost string) error {
+	if p.maxURLLength > 0 && len(rawURL) > p.maxURLLength {
+		return &PolicyViolationError{
+			Code:    PolicyViolationURLTooLong,
+			Message: fmt.Sprintf("URL is longer than %d characters", p.maxURLLength),
+			URL:     rawURL,
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return invalidURLError(rawURL, err)
+	}
+
+	if !p.allowedSchemes[strings.ToLower(u.Scheme)] {
+		return &PolicyViolationError{
+			Code:    PolicyViolationSchemeNotAllowed,
+			Message: fmt.Sprintf("scheme '%s' is not allowed", u.Scheme),
+			URL:     rawURL,
+		}
+	}
+
+	if canonicalURL != rawURL {
+		if u, err = url.Parse(canonicalURL); err != nil {
+			return invalidURLError(rawURL, err)
+		}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	if p.serviceHosts[host] || host == hostWithoutPort(requestHost) {
+		return &PolicyViolationError{
+			Code:    PolicyViolationSelfReferencedURL,
+			Message: "URL points to the shortener itself",
+			URL:     rawURL,
+		}
+	}
+
+	if p.blockPrivateAddresses && isPrivateHost(host) {
+		return &PolicyViolationError{
+			Code:    PolicyViolationPrivateAddress,
+			Message: "URL points to a private or loopback address",
+			URL:     rawURL,
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if matchDomain(p.deniedDomains, host) {
+		return &PolicyViolationError{
+			Code:    PolicyViolationDomainDenied,
+			Message: fmt.Sprintf("domain '%s' is denied", host),
+			URL:     rawURL,
+		}
+	}
+
+	if len(p.allowedDomains) > 0 && !matchDomain(p.allowedDomains, host) {
+		return &PolicyViolationError{
+			Code:    PolicyViolationDomainNotAllowed,
+			Message: fmt.Sprintf("domain '%s' is not in allow list", host),
+			URL:     rawURL,
+		}
+	}
+
+	return nil
+}
+
+// LoadDomainLists replaces allow and deny lists with domains from the files.
+// Empty path clears the corresponding list.
+func (p *URLPolicy) LoadDomainLists(allowListFile string, denyListFile string) error {
+	allowed, err := loadDomainList(allowListFile)
+	if err != nil {
+		return fmt.Errorf("load domain allow list: %w", err)
+	}
+
+	denied, err := loadDomainList(denyListFile)
+	if err != nil {
+		return fmt.Errorf("load domain deny list: %w", err)
+	}
+
+	p.mu.Lock()
+	p.allowedDomains = allowed
+	p.deniedDomains = denied
+	p.mu.Unlock()
+
+	return nil
+}
+
+// loadDomainList reads one domain per line, empty lines and lines starting with # are skipped.
+func loadDomainList(path string) (map[string]bool, error) {
+	domains := make(map[string]bool)
+
+	if path == "" {
+		return domains, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { utils.LogErrorWrapper(file.Close()) }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		domains[strings.TrimSuffix(strings.ToLower(line), ".")] = true
+	}
+
+	return domains, scanner.Err()
+}
+
+// matchDomain reports whether host or any of its parent domains is in domains.
+func matchDomain(domains map[string]bool, host string) bool {
+	for host != "" {
+		if domains[host] {
+			return true
+		}
+
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return false
+		}
+		host = parent
+	}
+
+	return false
+}
+
+func isPrivateHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		var isNumeric bool
+		if ip, isNumeric = parseNumericIPv4(host); !isNumeric {
+			return false
+		}
+		// Numeric hosts that are not valid addresses are rejected as well
+		if ip == nil {
+			return true
+		}
+	}
+
+	return isPrivateIP(ip)
+}
+
+// isPrivateIP reports whether ip is loopback, private, unspecified or link-local.
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast()
+}
+
+// parseNumericIPv4 parses hosts like inet_aton does: one to four decimal, octal (leading 0)
+// or hex (leading 0x) parts, the last part fills the remaining bytes, so 2130706433,
+// 0x7f.1 and 0177.0.0.1 are all 127.0.0.1. isNumeric reports whether the last label is
+// numeric, browsers resolve such hosts as IPv4 addresses, ip is nil when it is invalid.
+func parseNumericIPv4(host string) (ip net.IP, isNumeric bool) {
+	parts := strings.Split(host, ".")
+	if _, err := parseNumericIPv4Part(parts[len(parts)-1]); err != nil {
+		return nil, false
+	}
+	if len(parts) > 4 {
+		return nil, true
+	}
+
+	var address uint64
+	for i, part := range parts {
+		value, err := parseNumericIPv4Part(part)
+		if err != nil {
+			return nil, true
+		}
+
+		if i < len(parts)-1 {
+			if value > 0xff {
+				return nil, true
+			}
+			address |= value << (8 * (3 - i))
+			continue
+		}
+
+		if value >= 1<<(8*(4-i)) {
+			return nil, true
+		}
+		address |= value
+	}
+
+	return net.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address)), true
+}
+
+func parseNumericIPv4Part(part string) (uint64, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x") || strings.HasPrefix(part, "0X"):
+		part, base = part[2:], 16
+		if part == "" {
+			return 0, nil
+		}
+	case len(part) > 1 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+
+	return strconv.ParseUint(part, base, 32)
+}
+
+func hostWithoutPort(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		hostport = host
+	}
+	return strings.Trim(strings.ToLower(hostport), "[]")
+}
+
+// NewURLPolicy creates URLPolicy with static rules from the config.
+// Domain lists are loaded separately with LoadDomainLists.
+func NewURLPolicy(cfg *config.AppConfig) *URLPolicy {
+	policy := &URLPolicy{
+		allowedSchemes:        make(map[string]bool),
+		blockPrivateAddresses: cfg.Policy.BlockPrivateAddresses,
+		maxURLLength:          cfg.Policy.MaxURLLength,
+		serviceHosts:          make(map[string]bool),
+		allowedDomains:        make(map[string]bool),
+		deniedDomains:         make(map[string]bool),
+	}
+
+	for _, scheme := range strings.Split(cfg.Policy.AllowedSchemes, ",") {
+		if scheme = strings.TrimSpace(scheme); scheme != "" {
+			policy.allowedSchemes[strings.ToLower(scheme)] = true
+		}
+	}
+
+	if len(policy.allowedSchemes) == 0 {
+		policy.allowedSchemes["http"] = true
+		policy.allowedSchemes["https"] = true
+	}
+
+	// The listen address is not a public host of the service, only BASE_URL is.
+	if baseURL, err := url.Parse(cfg.Server.BaseURL); err == nil && baseURL.Hostname() != "" {
+		policy.serviceHosts[strings.ToLower(baseURL.Hostname())] = true
+	}
+
+	return policy
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLPolicy_Check(t *testing.T) {
+	dir := t.TempDir()
+
+	allowList := filepath.Join(dir, "allow.txt")
+	require.NoError(t, os.WriteFile(allowList, []byte("# partners\nexample.com\nallowed.org\n"), 0644))
+
+	denyList := filepath.Join(dir, "deny.txt")
+	require.NoError(t, os.WriteFile(denyList, []byte("bad.example.com\n"), 0644))
+
+	cfg := &config.AppConfig{}
+	cfg.Server.BaseURL = "https://sho.rt/"
+	cfg.Server.Address = "localhost:8080"
+	cfg.Policy.AllowedSchemes = "http,https"
+	cfg.Policy.BlockPrivateAddresses = true
+	cfg.Policy.MaxURLLength = 64
+
+	policy := NewURLPolicy(cfg)
+	require.NoError(t, policy.LoadDomainLists(allowList, denyList))
+
+	tests := []struct {
+		name string
+		url  string
+		code string
+	}{
+		{name: "allowed", url: "https://example.com/a"},
+		{name: "allowed subdomain", url: "https://www.example.com/a"},
+		{name: "too long", url: "https://example.com/" + string(make([]byte, 64)), code: PolicyViolationURLTooLong},
+		{name: "scheme in other casing", url: "JavaScript://example.com/a", code: PolicyViolationSchemeNotAllowed},
+		{name: "ftp", url: "ftp://example.com/a", code: PolicyViolationSchemeNotAllowed},
+		{name: "short domain", url: "https://SHO.RT/abc", code: PolicyViolationSelfReferencedURL},
+		{name: "request host", url: "http://shortener.internal/abc", code: PolicyViolationSelfReferencedURL},
+		{name: "loopback", url: "http://127.0.0.1/admin", code: PolicyViolationPrivateAddress},
+		{name: "private", url: "http://10.1.2.3/", code: PolicyViolationPrivateAddress},
+		{name: "decimal loopback", url: "http://2130706433/", code: PolicyViolationPrivateAddress},
+		{name: "hex loopback", url: "http://0x7f.1/", code: PolicyViolationPrivateAddress},
+		{name: "octal loopback", url: "http://0177.0.0.1/", code: PolicyViolationPrivateAddress},
+		{name: "invalid numeric host", url: "http://1.2.3.4.5/", code: PolicyViolationPrivateAddress},
+		{name: "ipv6 loopback", url: "http://[::1]:8081/", code: PolicyViolationPrivateAddress},
+		{name: "denied", url: "https://bad.example.com/", code: PolicyViolationDomainDenied},
+		{name: "not in allow list", url: "https://other.net/", code: PolicyViolationDomainNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := policy.Check(test.url, "shortener.internal:8080")
+
+			if test.code == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var violation *PolicyViolationError
+			require.ErrorAs(t, err, &violation)
+			assert.Equal(t, test.code, violation.Code)
+		})
+	}
+}
+
+func TestURLPolicy_ListenAddressIsNotServiceHost(t *testing.T) {
+	cfg := &config.AppConfig{}
+	cfg.Server.BaseURL = "https://sho.rt/"
+	cfg.Server.Address = "localhost:8080"
+
+	policy := NewURLPolicy(cfg)
+
+	assert.NoError(t, policy.Check("http://localhost:3000/app", "sho.rt"))
+}
+
+func TestURLPolicy_CheckCanonical(t *testing.T) {
+	cfg := &config.AppConfig{}
+	cfg.Policy.AllowedSchemes = "https"
+	cfg.Policy.BlockPrivateAddresses = true
+	cfg.Policy.MaxURLLength = 32
+
+	policy := NewURLPolicy(cfg)
+
+	tests := []struct {
+		name      string
+		url       string
+		canonical string
+		code      string
+	}{
+		{name: "allowed", url: "https://example.com/?utm_id=a", canonical: "https://example.com/"},
+		{name: "length of submitted URL", url: "https://example.com/?utm_source=aaaaaaaaaa", canonical: "https://example.com/", code: PolicyViolationURLTooLong},
+		{name: "scheme of submitted URL", url: "http://example.com/", canonical: "https://example.com/", code: PolicyViolationSchemeNotAllowed},
+		{name: "host of canonical URL", url: "https://example.com/", canonical: "https://127.0.0.1/", code: PolicyViolationPrivateAddress},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := policy.CheckCanonical(test.url, test.canonical, "")
+
+			if test.code == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var violation *PolicyViolationError
+			require.ErrorAs(t, err, &violation)
+			assert.Equal(t, test.code, violation.Code)
+			assert.Equal(t, test.url, violation.URL)
+		})
+	}
+}
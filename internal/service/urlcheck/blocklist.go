@@ -0,0 +1,217 @@
+package urlcheck
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	minPrefixLength = 4
+	maxPrefixLength = sha256.Size
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// prefixes holds hash prefixes grouped by their length to check every hash
+// only against the lengths present in the lists.
+type prefixes map[int]map[string]string
+
+// HashPrefixBlocklist checks URLs against local lists of SHA-256 hash prefixes
+// of URL expressions. Every file contains one hex encoded prefix (4-32 bytes) per line,
+// lines starting with # are comments. The file name without extension is used as the threat type.
+type HashPrefixBlocklist struct {
+	files []string
+
+	mu       sync.RWMutex
+	prefixes prefixes
+	states   map[string]fileState
+}
+
+// Check reports whether any expression of rawURL has a hash prefix from the lists.
+func (b *HashPrefixBlocklist) Check(ctx context.Context, rawURL string) (Verdict, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// Lists may be configured later by SetFiles
+	if len(b.files) == 0 {
+		return Verdict{}, nil
+	}
+
+	expressions, err := urlExpressions(rawURL)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	for _, expression := range expressions {
+		if err := ctx.Err(); err != nil {
+			return Verdict{}, err
+		}
+
+		hash := sha256.Sum256([]byte(expression))
+
+		for length, list := range b.prefixes {
+			if threatType, ok := list[string(hash[:length])]; ok {
+				return Verdict{Malicious: true, ThreatType: threatType}, nil
+			}
+		}
+	}
+
+	return Verdict{}, nil
+}
+
+// Reload reads all list files again. Current lists are kept if any file fails to load.
+func (b *HashPrefixBlocklist) Reload() error {
+	return b.load(b.Files())
+}
+
+// SetFiles replaces list files and loads them. Current files and lists are kept if any file fails to load.
+func (b *HashPrefixBlocklist) SetFiles(files []string) error {
+	return b.load(files)
+}
+
+// Files returns list files.
+func (b *HashPrefixBlocklist) Files() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.files
+}
+
+func (b *HashPrefixBlocklist) load(files []string) error {
+	loaded := make(prefixes)
+	states := make(map[string]fileState, len(files))
+
+	for _, path := range files {
+		state, err := loadPrefixes(path, loaded)
+		if err != nil {
+			return fmt.Errorf("load blocklist '%s': %w", path, err)
+		}
+		states[path] = state
+	}
+
+	b.mu.Lock()
+	b.files = files
+	b.prefixes = loaded
+	b.states = states
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads lists every interval when any file was changed until ctx is done.
+// A non-positive interval disables watching.
+func (b *HashPrefixBlocklist) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !b.changed() {
+				continue
+			}
+
+			if err := b.Reload(); err != nil {
+				logger.Log.Error("Blocklist reload failed", zap.Error(err))
+				continue
+			}
+
+			logger.Log.Info("Blocklist reloaded", zap.Strings("files", b.Files()))
+		}
+	}
+}
+
+func (b *HashPrefixBlocklist) changed() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, path := range b.files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return true
+		}
+
+		state := b.states[path]
+		if !info.ModTime().Equal(state.modTime) || info.Size() != state.size {
+			return true
+		}
+	}
+
+	return false
+}
+
+func loadPrefixes(path string, loaded prefixes) (fileState, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	defer func() { utils.LogErrorWrapper(file.Close()) }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fileState{}, err
+	}
+
+	threatType := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		prefix, err := hex.DecodeString(text)
+		if err != nil {
+			return fileState{}, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if len(prefix) < minPrefixLength || len(prefix) > maxPrefixLength {
+			return fileState{}, fmt.Errorf("line %d: prefix length must be from %d to %d bytes", line, minPrefixLength, maxPrefixLength)
+		}
+
+		if loaded[len(prefix)] == nil {
+			loaded[len(prefix)] = make(map[string]string)
+		}
+		loaded[len(prefix)][string(prefix)] = threatType
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fileState{}, err
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// NewHashPrefixBlocklist creates HashPrefixBlocklist and loads the files.
+func NewHashPrefixBlocklist(files []string) (*HashPrefixBlocklist, error) {
+	blocklist := &HashPrefixBlocklist{files: files}
+
+	if err := blocklist.Reload(); err != nil {
+		return nil, err
+	}
+
+	return blocklist, nil
+}
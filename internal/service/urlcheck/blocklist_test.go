@@ -0,0 +1,131 @@
+package urlcheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hashPrefix(expression string, length int) string {
+	hash := sha256.Sum256([]byte(expression))
+	return hex.EncodeToString(hash[:length])
+}
+
+func Test_urlExpressions(t *testing.T) {
+	expressions, err := urlExpressions("http://a.b.c/1/2.html?param=1")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"a.b.c/1/2.html?param=1", "a.b.c/1/2.html", "a.b.c/", "a.b.c/1/",
+		"b.c/1/2.html?param=1", "b.c/1/2.html", "b.c/", "b.c/1/",
+	}, expressions)
+
+	expressions, err = urlExpressions("http://1.2.3.4/1/")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1.2.3.4/1/", "1.2.3.4/"}, expressions)
+}
+
+func TestHashPrefixBlocklist_Check(t *testing.T) {
+	dir := t.TempDir()
+	malware := filepath.Join(dir, "malware.txt")
+	phishing := filepath.Join(dir, "phishing.txt")
+
+	require.NoError(t, os.WriteFile(malware, []byte("# malware hosts\n"+hashPrefix("evil.example/", 4)+"\n"), 0644))
+	require.NoError(t, os.WriteFile(phishing, []byte(hashPrefix("bank.example/login", 32)+"\n"), 0644))
+
+	blocklist, err := NewHashPrefixBlocklist([]string{malware, phishing})
+	require.NoError(t, err)
+
+	tests := []struct {
+		url        string
+		threatType string
+	}{
+		{url: "https://evil.example/any/path?x=1", threatType: "malware"},
+		{url: "https://www.EVIL.example/", threatType: "malware"},
+		{url: "https://bank.example/login", threatType: "phishing"},
+		{url: "https://bank.example/login?next=1", threatType: "phishing"},
+		{url: "https://bank.example/"},
+		{url: "https://example.com/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			verdict, err := blocklist.Check(context.Background(), test.url)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.threatType != "", verdict.Malicious)
+			assert.Equal(t, test.threatType, verdict.ThreatType)
+		})
+	}
+}
+
+func TestHashPrefixBlocklist_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "malware.txt")
+	require.NoError(t, os.WriteFile(path, []byte(""), 0644))
+
+	blocklist, err := NewHashPrefixBlocklist([]string{path})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go blocklist.Watch(ctx, 10*time.Millisecond)
+
+	verdict, err := blocklist.Check(ctx, "https://late.example/")
+	require.NoError(t, err)
+	require.False(t, verdict.Malicious)
+
+	require.NoError(t, os.WriteFile(path, []byte(hashPrefix("late.example/", 8)+"\n"), 0644))
+
+	assert.Eventually(t, func() bool {
+		verdict, err := blocklist.Check(ctx, "https://late.example/")
+		return err == nil && verdict.Malicious
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHashPrefixBlocklist_WatchDisabled(t *testing.T) {
+	blocklist, err := NewHashPrefixBlocklist(nil)
+	require.NoError(t, err)
+
+	// A zero interval returns instead of panicking in time.NewTicker
+	blocklist.Watch(context.Background(), 0)
+}
+
+func TestNewHashPrefixBlocklist_InvalidPrefix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.txt")
+	require.NoError(t, os.WriteFile(path, []byte("abc\n"), 0644))
+
+	_, err := NewHashPrefixBlocklist([]string{path})
+	assert.Error(t, err)
+}
+
+func TestHashPrefixBlocklist_SetFiles(t *testing.T) {
+	dir := t.TempDir()
+	malware := filepath.Join(dir, "malware.txt")
+	require.NoError(t, os.WriteFile(malware, []byte(hashPrefix("evil.example/", 4)+"\n"), 0644))
+
+	blocklist, err := NewHashPrefixBlocklist(nil)
+	require.NoError(t, err)
+
+	verdict, err := blocklist.Check(context.Background(), "https://evil.example/")
+	require.NoError(t, err)
+	assert.False(t, verdict.Malicious)
+
+	require.NoError(t, blocklist.SetFiles([]string{malware}))
+
+	verdict, err = blocklist.Check(context.Background(), "https://evil.example/")
+	require.NoError(t, err)
+	assert.True(t, verdict.Malicious)
+
+	// Files failing to load keep the current lists
+	require.Error(t, blocklist.SetFiles([]string{filepath.Join(dir, "missing.txt")}))
+	assert.Equal(t, []string{malware}, blocklist.Files())
+}
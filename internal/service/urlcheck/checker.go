@@ -0,0 +1,17 @@
+// Package urlcheck provides checks of destination URLs against malicious URL sources.
+package urlcheck
+
+import "context"
+
+// Verdict is the result of a URL check.
+type Verdict struct {
+	// Malicious reports whether the URL is flagged by the checker.
+	Malicious bool
+	// ThreatType contains the name of the list the URL was found in.
+	ThreatType string
+}
+
+// URLChecker checks whether a URL is known to be malicious.
+type URLChecker interface {
+	Check(ctx context.Context, rawURL string) (Verdict, error)
+}
@@ -0,0 +1,93 @@
+package urlcheck
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const (
+	maxHostSuffixes = 5
+	maxPathPrefixes = 6
+)
+
+// urlExpressions returns host suffix / path prefix combinations of rawURL
+// the same way Safe Browsing lookups build them, e.g. for "http://a.b.c/1/2.html?param=1":
+//
+//	a.b.c/1/2.html?param=1, a.b.c/1/2.html, a.b.c/, a.b.c/1/,
+//	b.c/1/2.html?param=1, b.c/1/2.html, b.c/, b.c/1/
+func urlExpressions(rawURL string) ([]string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := strings.Trim(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return nil, fmt.Errorf("url '%s' has no host", rawURL)
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	expressions := make([]string, 0, maxHostSuffixes*maxPathPrefixes)
+
+	for _, h := range hostSuffixes(host) {
+		for _, p := range pathPrefixes(path, u.RawQuery) {
+			expressions = append(expressions, h+p)
+		}
+	}
+
+	return expressions, nil
+}
+
+// hostSuffixes returns the exact host and up to four hosts formed by
+// the last five components with leading components successively removed.
+func hostSuffixes(host string) []string {
+	if net.ParseIP(host) != nil {
+		return []string{host}
+	}
+
+	result := []string{host}
+
+	components := strings.Split(host, ".")
+	start := max(1, len(components)-maxHostSuffixes)
+
+	for i := start; i <= len(components)-2; i++ {
+		result = append(result, strings.Join(components[i:], "."))
+	}
+
+	return result
+}
+
+// pathPrefixes returns the exact path with and without query,
+// the root path and up to three successive path directories.
+func pathPrefixes(path string, query string) []string {
+	result := make([]string, 0, maxPathPrefixes)
+	seen := make(map[string]bool)
+
+	add := func(p string) {
+		if !seen[p] && len(result) < maxPathPrefixes {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+
+	if query != "" {
+		add(path + "?" + query)
+	}
+	add(path)
+	add("/")
+
+	components := strings.Split(strings.Trim(path, "/"), "/")
+	prefix := "/"
+	for i := 0; i < len(components)-1 && i < 3; i++ {
+		prefix += components[i] + "/"
+		add(prefix)
+	}
+
+	return result
+}
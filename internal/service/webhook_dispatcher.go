@@ -0,0 +1,452 @@
+package service
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/logger"
+	"github.com/Alexey-zaliznuak/shortener/internal/metrics"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/webhook"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Headers of webhook requests.
+const (
+	WebhookEventHeader     = "X-Shortener-Event"
+	WebhookDeliveryHeader  = "X-Shortener-Delivery"
+	WebhookTimestampHeader = "X-Shortener-Timestamp"
+	// WebhookSignatureHeader contains "sha256=" and hex HMAC-SHA256 of "<timestamp>.<body>"
+	// calculated with the webhook secret.
+	WebhookSignatureHeader = "X-Shortener-Signature"
+)
+
+// ErrWebhookQueueFull is returned when the event is dropped because deliveries fall behind.
+var ErrWebhookQueueFull = errors.New("webhook queue is full")
+
+// ErrWebhookPrivateAddress is returned when a webhook host resolves to a private address.
+var ErrWebhookPrivateAddress = errors.New("webhook address is private or loopback")
+
+// webhookSubscribersTTL is how long the dispatcher trusts the answer whether anyone
+// receives follow events, it saves a link lookup on every redirect.
+const webhookSubscribersTTL = 5 * time.Second
+
+// WebhookDispatcher delivers audit events of links to webhooks of their owners.
+// Events are queued by Audit and delivered by workers started with Run.
+type WebhookDispatcher struct {
+	webhooks webhook.WebhookRepository
+	links    link.LinkRepository
+	client   *http.Client
+	queue    chan audit.AuditPayload
+
+	workers        int
+	maxAttempts    int
+	maxInFlight    int
+	retryBaseDelay time.Duration
+
+	// Failed attempts wait in retries until they are due and are passed to workers by ready
+	retriesMu  sync.Mutex
+	retries    webhookAttemptQueue
+	retryAdded chan struct{}
+	ready      chan *webhookAttempt
+
+	inFlightMu sync.Mutex
+	inFlight   map[string]int
+
+	subscribersMu      sync.Mutex
+	followSubscribers  bool
+	subscribersChecked time.Time
+}
+
+// webhookAttempt is a delivery of one event to one webhook.
+type webhookAttempt struct {
+	webhook    *model.Webhook
+	event      string
+	deliveryID string
+	body       []byte
+	attempt    int
+	delay      time.Duration
+	due        time.Time
+}
+
+// webhookAttemptQueue is a heap of attempts ordered by due time.
+type webhookAttemptQueue []*webhookAttempt
+
+func (q webhookAttemptQueue) Len() int           { return len(q) }
+func (q webhookAttemptQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+func (q webhookAttemptQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *webhookAttemptQueue) Push(x any)        { *q = append(*q, x.(*webhookAttempt)) }
+
+func (q *webhookAttemptQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Audit queues the event, it never blocks the request.
+func (d *WebhookDispatcher) Audit(payload audit.AuditPayload) error {
+	select {
+	case d.queue <- payload:
+		return nil
+	default:
+		return ErrWebhookQueueFull
+	}
+}
+
+// Run delivers queued events and due retries until ctx is done.
+// Events left in the queue and pending retries are dropped.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for range d.workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case payload := <-d.queue:
+					d.dispatch(ctx, payload)
+				case attempt := <-d.ready:
+					d.attempt(ctx, attempt)
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.runRetries(ctx)
+	}()
+
+	wg.Wait()
+}
+
+// runRetries passes attempts to workers when they are due.
+func (d *WebhookDispatcher) runRetries(ctx context.Context) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		attempt, wait := d.nextRetry()
+
+		if attempt != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case d.ready <- attempt:
+			}
+			continue
+		}
+
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.retryAdded:
+		case <-timer.C:
+		}
+	}
+}
+
+// nextRetry pops the due attempt or returns how long to wait for the earliest one.
+func (d *WebhookDispatcher) nextRetry() (*webhookAttempt, time.Duration) {
+	d.retriesMu.Lock()
+	defer d.retriesMu.Unlock()
+
+	if len(d.retries) == 0 {
+		return nil, time.Hour
+	}
+
+	if wait := time.Until(d.retries[0].due); wait > 0 {
+		return nil, wait
+	}
+
+	return heap.Pop(&d.retries).(*webhookAttempt), 0
+}
+
+// schedule queues the attempt to be made after delay.
+func (d *WebhookDispatcher) schedule(attempt *webhookAttempt, delay time.Duration) {
+	attempt.due = time.Now().Add(delay)
+
+	d.retriesMu.Lock()
+	heap.Push(&d.retries, attempt)
+	d.retriesMu.Unlock()
+
+	select {
+	case d.retryAdded <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch sends the event to every webhook of the link owner subscribed to it.
+func (d *WebhookDispatcher) dispatch(ctx context.Context, payload audit.AuditPayload) {
+	if payload.Shortcut == "" || payload.Status >= http.StatusBadRequest {
+		return
+	}
+
+	userID, ok := d.owner(ctx, payload)
+	if !ok {
+		return
+	}
+
+	webhooks, err := d.webhooks.GetByUserID(userID)
+	if err != nil {
+		logger.Log.Error("Webhooks lookup failed", zap.String("userID", userID), zap.Error(err))
+		return
+	}
+
+	for _, w := range webhooks {
+		if !w.Subscribed(payload.Action) {
+			continue
+		}
+
+		deliveryID := uuid.NewString()
+
+		body, err := json.Marshal(model.WebhookEvent{
+			ID:        deliveryID,
+			Event:     payload.Action,
+			TS:        payload.TS,
+			Shortcut:  payload.Shortcut,
+			URL:       payload.URL,
+			Status:    payload.Status,
+			RequestID: payload.RequestID,
+		})
+		if err != nil {
+			logger.Log.Error("Webhook event marshaling failed", zap.Error(err))
+			return
+		}
+
+		d.attempt(ctx, &webhookAttempt{
+			webhook:    w,
+			event:      payload.Action,
+			deliveryID: deliveryID,
+			body:       body,
+			attempt:    1,
+			delay:      d.retryBaseDelay,
+		})
+	}
+}
+
+// owner returns the user to notify. Follow events carry the visitor, so the owner
+// is taken from the link, other events carry the owner.
+func (d *WebhookDispatcher) owner(ctx context.Context, payload audit.AuditPayload) (string, bool) {
+	switch payload.Action {
+	case audit.ShortURLActionDelete, audit.ShortURLActionCreate, audit.ShortURLActionBatchCreate, audit.ShortURLActionUpdate:
+		return payload.UserID, payload.UserID != ""
+	case audit.ShortURLActionGet:
+		if !d.hasFollowSubscribers() {
+			return "", false
+		}
+
+		l, err := d.links.GetByShortcut(ctx, payload.Shortcut)
+		if err != nil {
+			return "", false
+		}
+		return l.UserID, l.UserID != ""
+	default:
+		return "", false
+	}
+}
+
+// hasFollowSubscribers reports whether any webhook receives follow events,
+// the answer is cached for webhookSubscribersTTL.
+func (d *WebhookDispatcher) hasFollowSubscribers() bool {
+	d.subscribersMu.Lock()
+	defer d.subscribersMu.Unlock()
+
+	if time.Since(d.subscribersChecked) < webhookSubscribersTTL {
+		return d.followSubscribers
+	}
+
+	exists, err := d.webhooks.HasSubscribers(audit.ShortURLActionGet)
+	if err != nil {
+		logger.Log.Error("Webhook subscribers lookup failed", zap.Error(err))
+		return true
+	}
+
+	d.followSubscribers = exists
+	d.subscribersChecked = time.Now()
+
+	return exists
+}
+
+// attempt posts the event once and records the attempt. Failed attempts are retried
+// with doubled delay, attempts to a webhook with too many deliveries in flight are postponed.
+func (d *WebhookDispatcher) attempt(ctx context.Context, a *webhookAttempt) {
+	if !d.acquire(a.webhook.ID) {
+		d.schedule(a, d.retryBaseDelay)
+		return
+	}
+
+	statusCode, err := d.post(ctx, a.webhook, a.event, a.deliveryID, a.body)
+	d.release(a.webhook.ID)
+
+	record := &model.WebhookDelivery{
+		DeliveryID: a.deliveryID,
+		WebhookID:  a.webhook.ID,
+		Event:      a.event,
+		Attempt:    a.attempt,
+		StatusCode: statusCode,
+		Success:    err == nil,
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if recordErr := d.webhooks.AddDelivery(record); recordErr != nil {
+		logger.Log.Error("Webhook delivery recording failed", zap.String("webhookID", a.webhook.ID), zap.Error(recordErr))
+	}
+
+	if err == nil {
+		return
+	}
+
+	metrics.WebhookDeliveryFailedAttempts.Inc()
+
+	if a.attempt == d.maxAttempts {
+		logger.Log.Warn("Webhook delivery failed", zap.String("webhookID", a.webhook.ID), zap.String("deliveryID", a.deliveryID), zap.Error(err))
+		return
+	}
+
+	delay := a.delay
+	a.attempt++
+	a.delay *= 2
+
+	d.schedule(a, delay)
+}
+
+func (d *WebhookDispatcher) acquire(webhookID string) bool {
+	d.inFlightMu.Lock()
+	defer d.inFlightMu.Unlock()
+
+	if d.inFlight[webhookID] >= d.maxInFlight {
+		return false
+	}
+
+	d.inFlight[webhookID]++
+	return true
+}
+
+func (d *WebhookDispatcher) release(webhookID string) {
+	d.inFlightMu.Lock()
+	defer d.inFlightMu.Unlock()
+
+	if d.inFlight[webhookID]--; d.inFlight[webhookID] <= 0 {
+		delete(d.inFlight, webhookID)
+	}
+}
+
+func (d *WebhookDispatcher) post(ctx context.Context, w *model.Webhook, event string, deliveryID string, body []byte) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "shortener-webhook")
+	request.Header.Set(WebhookEventHeader, event)
+	request.Header.Set(WebhookDeliveryHeader, deliveryID)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, WebhookSignature(w.Secret, timestamp, body))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+// WebhookSignature returns value of WebhookSignatureHeader, receivers compare it
+// with hmac.Equal after calculating it from the raw body.
+func WebhookSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// rejectPrivateAddress is the dialer control rejecting connections to private addresses.
+// It runs after DNS resolution, so hosts rebound to internal addresses are rejected as well.
+func rejectPrivateAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookPrivateAddress, host)
+	}
+
+	return nil
+}
+
+func NewWebhookDispatcher(webhooks webhook.WebhookRepository, links link.LinkRepository, config *config.AppConfig) *WebhookDispatcher {
+	dialer := &net.Dialer{Timeout: config.Webhooks.RequestTimeout}
+	if !config.Webhooks.AllowPrivateAddresses {
+		dialer.Control = rejectPrivateAddress
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect to webhooks bypassing the dialer control
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookDispatcher{
+		webhooks: webhooks,
+		links:    links,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Webhooks.RequestTimeout,
+			// Redirects could lead webhooks to addresses rejected by the URL policy
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		queue:          make(chan audit.AuditPayload, config.Webhooks.QueueSize),
+		workers:        config.Webhooks.Workers,
+		maxAttempts:    config.Webhooks.MaxAttempts,
+		maxInFlight:    config.Webhooks.MaxInFlight,
+		retryBaseDelay: config.Webhooks.RetryBaseDelay,
+		retryAdded:     make(chan struct{}, 1),
+		ready:          make(chan *webhookAttempt),
+		inFlight:       make(map[string]int),
+	}
+}
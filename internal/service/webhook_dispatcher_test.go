@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/link"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDispatcher_PrivateAddress(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	cfg := &config.AppConfig{}
+	cfg.Webhooks.RequestTimeout = time.Second
+
+	tests := []struct {
+		name    string
+		allow   bool
+		wantErr bool
+	}{
+		{name: "rejected", wantErr: true},
+		{name: "allowed for development", allow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Webhooks.AllowPrivateAddresses = tt.allow
+			dispatcher := NewWebhookDispatcher(webhook.NewInMemoryWebhooksRepository(), link.NewInMemoryLinksRepository(cfg), cfg)
+
+			// localhost is resolved by the dialer, so the check does not depend on the URL policy
+			w := &model.Webhook{ID: "1", URL: strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)}
+
+			_, err := dispatcher.post(context.Background(), w, "follow", "1", []byte("{}"))
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrWebhookPrivateAddress)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWebhookDispatcher_MaxInFlight(t *testing.T) {
+	cfg := &config.AppConfig{}
+	cfg.Webhooks.RequestTimeout = time.Second
+	cfg.Webhooks.MaxInFlight = 1
+	cfg.Webhooks.MaxAttempts = 3
+	cfg.Webhooks.RetryBaseDelay = time.Minute
+
+	webhooks := webhook.NewInMemoryWebhooksRepository()
+	dispatcher := NewWebhookDispatcher(webhooks, link.NewInMemoryLinksRepository(cfg), cfg)
+
+	w := &model.Webhook{ID: "1", URL: "http://127.0.0.1:1/"}
+	require.True(t, dispatcher.acquire(w.ID))
+
+	// The webhook already has a delivery in flight, so the attempt is postponed without a request
+	dispatcher.attempt(context.Background(), &webhookAttempt{webhook: w, event: "follow", deliveryID: "1", attempt: 1})
+
+	deliveries, err := webhooks.GetDeliveries(w.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	require.Len(t, dispatcher.retries, 1)
+	assert.Equal(t, 1, dispatcher.retries[0].attempt)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), dispatcher.retries[0].due, time.Second)
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/Alexey-zaliznuak/shortener/internal/audit"
+	"github.com/Alexey-zaliznuak/shortener/internal/config"
+	"github.com/Alexey-zaliznuak/shortener/internal/model"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/database"
+	"github.com/Alexey-zaliznuak/shortener/internal/repository/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxWebhooksPerUser limits endpoints notified about a single event.
+	maxWebhooksPerUser = 10
+
+	webhookSecretPrefix = "whsec_"
+
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 100
+)
+
+// WebhookEvents contains actions webhooks can subscribe to.
+var WebhookEvents = []audit.ShortURLAction{
+	audit.ShortURLActionGet,
+	audit.ShortURLActionCreate,
+	audit.ShortURLActionBatchCreate,
+	audit.ShortURLActionDelete,
+	audit.ShortURLActionUpdate,
+}
+
+var (
+	// ErrInvalidWebhook is returned when the webhook request is rejected.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrWebhookNotFound is returned when the webhook does not exist or belongs to another user.
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// WebhooksService manages webhooks of the authorized user.
+type WebhooksService struct {
+	repository webhook.WebhookRepository
+	auth       *AuthService
+	policy     *URLPolicy
+}
+
+// Create registers the webhook, the response contains the generated secret.
+func (s *WebhooksService) Create(request *model.WebhookRequest, c *gin.Context) (*model.Webhook, error) {
+	claims, err := s.auth.GetAuthorization(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(request, c); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repository.GetByUserID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(existing) >= maxWebhooksPerUser {
+		return nil, fmt.Errorf("%w: limit of %d webhooks reached", ErrInvalidWebhook, maxWebhooksPerUser)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.Webhook{
+		ID:        id.String(),
+		UserID:    claims.UserID,
+		URL:       request.URL,
+		Events:    request.Events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	if err := s.repository.Create(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// List returns webhooks of the user without secrets.
+func (s *WebhooksService) List(c *gin.Context) ([]*model.Webhook, error) {
+	claims, err := s.auth.GetAuthorization(c)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.repository.GetByUserID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, w.WithoutSecret())
+	}
+
+	return result, nil
+}
+
+// Get returns the webhook of the user without secret.
+func (s *WebhooksService) Get(id string, c *gin.Context) (*model.Webhook, error) {
+	result, err := s.get(id, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.WithoutSecret(), nil
+}
+
+// Update replaces URL and events of the webhook. The secret is returned only when it is rotated.
+func (s *WebhooksService) Update(id string, request *model.WebhookRequest, c *gin.Context) (*model.Webhook, error) {
+	result, err := s.get(id, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(request, c); err != nil {
+		return nil, err
+	}
+
+	result.URL = request.URL
+	result.Events = request.Events
+
+	if request.RotateSecret {
+		if result.Secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repository.Update(result); err != nil {
+		return nil, notFoundWebhook(err)
+	}
+
+	if !request.RotateSecret {
+		return result.WithoutSecret(), nil
+	}
+
+	return result, nil
+}
+
+// Delete removes the webhook with its delivery history.
+func (s *WebhooksService) Delete(id string, c *gin.Context) error {
+	claims, err := s.auth.GetAuthorization(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrWebhookNotFound
+	}
+
+	return notFoundWebhook(s.repository.Delete(id, claims.UserID))
+}
+
+// GetDeliveries returns latest delivery attempts of the webhook, limit 0 selects the default.
+func (s *WebhooksService) GetDeliveries(id string, limit int, c *gin.Context) ([]*model.WebhookDelivery, error) {
+	if limit < 0 || limit > maxDeliveriesLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidWebhook, maxDeliveriesLimit)
+	}
+
+	if limit == 0 {
+		limit = defaultDeliveriesLimit
+	}
+
+	if _, err := s.get(id, c); err != nil {
+		return nil, err
+	}
+
+	return s.repository.GetDeliveries(id, limit)
+}
+
+// UseURLPolicy replaces the policy webhook URLs are checked against.
+func (s *WebhooksService) UseURLPolicy(policy *URLPolicy) {
+	s.policy = policy
+}
+
+func (s *WebhooksService) get(id string, c *gin.Context) (*model.Webhook, error) {
+	claims, err := s.auth.GetAuthorization(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	result, err := s.repository.Get(id, claims.UserID)
+	if err != nil {
+		return nil, notFoundWebhook(err)
+	}
+
+	return result, nil
+}
+
+// validate checks the endpoint against the URL policy, so webhooks can not reach
+// internal addresses, and normalizes the events list.
+func (s *WebhooksService) validate(request *model.WebhookRequest, c *gin.Context) error {
+	u, err := url.ParseRequestURI(request.URL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: '%s' is not an absolute URL", ErrInvalidWebhook, request.URL)
+	}
+
+	if err := s.policy.Check(request.URL, c.Request.Host); err != nil {
+		return err
+	}
+
+	events := make([]string, 0, len(request.Events))
+
+	for _, event := range request.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Errorf("%w: unknown event '%s', expected one of %v", ErrInvalidWebhook, event, WebhookEvents)
+		}
+
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	request.Events = events
+
+	return nil
+}
+
+func notFoundWebhook(err error) error {
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return webhookSecretPrefix + hex.EncodeToString(secret), nil
+}
+
+func NewWebhooksService(repository webhook.WebhookRepository, config *config.AppConfig) *WebhooksService {
+	return &WebhooksService{
+		repository: repository,
+		auth:       NewAuthService(config),
+		policy:     NewURLPolicy(config),
+	}
+}
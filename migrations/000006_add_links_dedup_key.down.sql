@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS idx_links_url;
+
+ALTER TABLE links
+DROP CONSTRAINT IF EXISTS links_dedup_key_key;
+
+ALTER TABLE links
+DROP COLUMN IF EXISTS dedup_key;
+
+-- При областях дедупликации user и none один URL может храниться несколько раз. Перед возвратом
+-- ограничения UNIQUE (url) для каждого URL остается одна ссылка: неудаленная, если такая есть.
+DELETE FROM links
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY url ORDER BY is_deleted, shortcut) AS duplicate_number
+        FROM links
+    ) numbered
+    WHERE duplicate_number > 1
+);
+
+ALTER TABLE links
+ADD CONSTRAINT links_url_key UNIQUE (url);
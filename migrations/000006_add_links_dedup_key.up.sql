@@ -0,0 +1,15 @@
+ALTER TABLE links
+ADD COLUMN dedup_key TEXT;
+
+-- Область дедупликации задается конфигурацией сервиса и миграции неизвестна, поэтому существующие ссылки
+-- получают ключи области global. При области user или none новые ключи с ними не совпадают: повторное
+-- сокращение URL, сохраненного до миграции, создает новую ссылку, а сохраненные ссылки продолжают работать.
+UPDATE links SET dedup_key = url;
+
+ALTER TABLE links
+ADD CONSTRAINT links_dedup_key_key UNIQUE (dedup_key);
+
+ALTER TABLE links
+DROP CONSTRAINT IF EXISTS links_url_key;
+
+CREATE INDEX IF NOT EXISTS idx_links_url ON links(url);
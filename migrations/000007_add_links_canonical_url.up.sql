@@ -0,0 +1,4 @@
+ALTER TABLE links
+ADD COLUMN canonical_url TEXT;
+
+UPDATE links SET canonical_url = url;
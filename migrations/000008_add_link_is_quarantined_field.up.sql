@@ -0,0 +1,2 @@
+ALTER TABLE links
+ADD COLUMN "is_quarantined" BOOLEAN NOT NULL DEFAULT FALSE;